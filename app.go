@@ -1,7 +1,10 @@
 package gw
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/conf"
+	"strings"
 )
 
 // App represents a application.
@@ -41,15 +44,73 @@ type App interface {
 
 	// OnShutDown define a API that notify your Application when server shutdown before.
 	OnShutDown(state *ServerState)
+}
+
+// IAppDependency represents a App that Depend On other apps.
+//
+// The gw framework calls Use/Register/Migrate/OnStart APIs of apps by dependency order,
+// and calls OnShutDown APIs by reverse order.
+type IAppDependency interface {
+	// DependOn define a API that tells gw framework, Your application Depend On apps(the App.Name() of apps).
+	DependOn() []string
+}
 
-	// Fixme(OceanHo): need?
-	// Use define a API that tells gw framework, Your application Depend On apps.
-	//DependOn(registeredApps map[string]App) []string
+// IAppConfigChanged represents a App that want to be notified after the ApplicationConfig has been reloaded.
+type IAppConfigChanged interface {
+	// OnConfigChanged define a API that notify your Application when the ApplicationConfig has been changed.
+	OnConfigChanged(old, new *conf.ApplicationConfig)
 }
 
 type internalApp struct {
 	instance    App
 	isPatchOnly bool
+	dependOn    []string
+}
+
+// sortApps returns apps that sorted by dependency order(topological order).
+// apps that has no dependency relationship are keep their registration order.
+// returns a error if there are has missing dependency or dependency cycle.
+func sortApps(apps map[string]internalApp, names []string) ([]internalApp, error) {
+	// the unvisited apps has no state(the zero value).
+	const (
+		visiting = iota + 1
+		visited
+	)
+	var sorted = make([]internalApp, 0, len(names))
+	var states = make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch states[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("app dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+		app := apps[name]
+		states[name] = visiting
+		path = append(path, name)
+		var deps = append([]string{}, app.dependOn...)
+		if d, ok := app.instance.(IAppDependency); ok {
+			deps = append(deps, d.DependOn()...)
+		}
+		for _, dep := range deps {
+			if _, ok := apps[dep]; !ok {
+				return fmt.Errorf("app: %s depend on app: %s, but it's not registered", name, dep)
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		states[name] = visited
+		sorted = append(sorted, app)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
 }
 
 // MigrationContext represents a Migration Context Object.
@@ -0,0 +1,83 @@
+package gw
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testerDependApp struct {
+	name     string
+	dependOn []string
+}
+
+func (t testerDependApp) Name() string {
+	return t.name
+}
+
+func (t testerDependApp) Router() string {
+	return t.name
+}
+
+func (t testerDependApp) Register(router *RouterGroup) {
+}
+
+func (t testerDependApp) Use(option *ServerOption) {
+}
+
+func (t testerDependApp) Migrate(state *ServerState) {
+}
+
+func (t testerDependApp) OnStart(state *ServerState) {
+}
+
+func (t testerDependApp) OnShutDown(state *ServerState) {
+}
+
+func (t testerDependApp) DependOn() []string {
+	return t.dependOn
+}
+
+func newTesterDependApps(apps ...testerDependApp) (map[string]internalApp, []string) {
+	var maps = make(map[string]internalApp)
+	var names []string
+	for _, app := range apps {
+		maps[app.name] = internalApp{instance: app}
+		names = append(names, app.name)
+	}
+	return maps, names
+}
+
+func TestSortApps(t *testing.T) {
+	apps, names := newTesterDependApps(
+		testerDependApp{name: "devops", dependOn: []string{"uap", "stor"}},
+		testerDependApp{name: "stor", dependOn: []string{"uap"}},
+		testerDependApp{name: "admin"},
+		testerDependApp{name: "uap"},
+	)
+	sorted, err := sortApps(apps, names)
+	assert.Nil(t, err)
+	var sortedNames []string
+	for _, app := range sorted {
+		sortedNames = append(sortedNames, app.instance.Name())
+	}
+	assert.Equal(t, []string{"uap", "stor", "devops", "admin"}, sortedNames)
+}
+
+func TestSortApps_Cycle(t *testing.T) {
+	apps, names := newTesterDependApps(
+		testerDependApp{name: "a", dependOn: []string{"b"}},
+		testerDependApp{name: "b", dependOn: []string{"c"}},
+		testerDependApp{name: "c", dependOn: []string{"a"}},
+	)
+	_, err := sortApps(apps, names)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "a -> b -> c -> a")
+}
+
+func TestSortApps_MissingDependency(t *testing.T) {
+	apps, names := newTesterDependApps(
+		testerDependApp{name: "devops", dependOn: []string{"uap"}},
+	)
+	_, err := sortApps(apps, names)
+	assert.NotNil(t, err)
+}
@@ -317,6 +317,7 @@ type User struct {
 	Roles       []string              `gorm:"-"`
 	Permissions []Permission          `gorm:"-"`
 	PermMaps    map[string]Permission `gorm:"-"`
+	Locale      string                `gorm:"-"` // the preferred locale of user profile, such as zh-CN.
 }
 
 func (user User) MarshalBinary() (data []byte, err error) {
@@ -6,6 +6,7 @@ import (
 	"github.com/oceanho/gw/conf"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,7 +28,7 @@ func gwLogin(c *gin.Context) {
 	}
 
 	if !hasCheckPass {
-		c.JSON(http.StatusBadRequest, s.RespBodyBuildFunc(http.StatusBadRequest, reqId, err, nil))
+		renderResp(c, http.StatusBadRequest, s.RespBodyBuildFunc(http.StatusBadRequest, reqId, err, nil))
 		c.Abort()
 		return
 	}
@@ -35,18 +36,18 @@ func gwLogin(c *gin.Context) {
 	// Login
 	user, err := s.AuthManager.Login(authParam)
 	if err != nil || user.IsEmpty() {
-		c.JSON(http.StatusNotFound, s.RespBodyBuildFunc(http.StatusNotFound, reqId, err.Error(), nil))
+		renderResp(c, http.StatusNotFound, s.RespBodyBuildFunc(http.StatusNotFound, reqId, err.Error(), nil))
 		c.Abort()
 		return
 	}
 	sid, credential, ok := encryptSid(s, authParam)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, s.RespBodyBuildFunc(http.StatusInternalServerError, reqId, "Create session ID fail.", nil))
+		renderResp(c, http.StatusInternalServerError, s.RespBodyBuildFunc(http.StatusInternalServerError, reqId, s.Translator.T(resolveLocale(s, c), "Create session ID fail."), nil))
 		c.Abort()
 		return
 	}
 	if err := s.SessionStateManager.Save(sid, user); err != nil {
-		c.JSON(http.StatusInternalServerError, s.RespBodyBuildFunc(http.StatusInternalServerError, reqId, "Save session fail.", err.Error()))
+		renderResp(c, http.StatusInternalServerError, s.RespBodyBuildFunc(http.StatusInternalServerError, reqId, s.Translator.T(resolveLocale(s, c), "Save session fail."), err.Error()))
 		c.Abort()
 		return
 	}
@@ -58,7 +59,7 @@ func gwLogin(c *gin.Context) {
 			"Desc": p.Descriptor,
 		})
 	}
-	cks := s.conf.Security.Auth.Cookie
+	cks := s.Config().Security.Auth.Cookie
 	expiredAt := time.Duration(cks.MaxAge) * time.Second
 	var userRoles = gin.H{
 		"Id":   0,
@@ -75,7 +76,7 @@ func gwLogin(c *gin.Context) {
 	}
 	body := s.RespBodyBuildFunc(0, reqId, nil, payload)
 	c.SetCookie(cks.Key, credential, cks.MaxAge, cks.Path, cks.Domain, cks.Secure, cks.HttpOnly)
-	c.JSON(http.StatusOK, body)
+	renderResp(c, http.StatusOK, body)
 }
 
 // GW framework logout API.
@@ -83,7 +84,7 @@ func gwLogout(c *gin.Context) {
 	s := getHostServer(c)
 	reqId := getRequestId(s, c)
 	user := getUser(c)
-	cks := s.conf.Security.Auth.Cookie
+	cks := s.Config().Security.Auth.Cookie
 	ok := s.AuthManager.Logout(user)
 	if !ok {
 		s.RespBodyBuildFunc(http.StatusInternalServerError, reqId, "auth logout fail", nil)
@@ -94,21 +95,42 @@ func gwLogout(c *gin.Context) {
 		s.RespBodyBuildFunc(http.StatusInternalServerError, reqId, "session store logout fail", nil)
 		return
 	}
-	_ = s.SessionStateManager.Remove(sid)
+	_ = s.RemoveSession(sid)
 	c.SetCookie(cks.Key, "", -1, cks.Path, cks.Domain, cks.Secure, cks.HttpOnly)
 }
 
-// GW framework auth Check Middleware
-func gwAuthChecker(urls []conf.AllowUrl) gin.HandlerFunc {
+type allowUrlsState struct {
+	cnf       *conf.ApplicationConfig
+	allowUrls map[string]bool
+}
+
+func newAllowUrlsState(cnf *conf.ApplicationConfig) *allowUrlsState {
 	var allowUrls = make(map[string]bool)
-	for _, url := range urls {
+	for _, url := range cnf.Security.Auth.AllowUrls {
 		for _, p := range url.Urls {
 			s := p
 			allowUrls[s] = true
 		}
 	}
+	return &allowUrlsState{
+		cnf:       cnf,
+		allowUrls: allowUrls,
+	}
+}
+
+// GW framework auth Check Middleware
+// The allow urls will be re-compiled after the ApplicationConfig has been reloaded.
+func gwAuthChecker() gin.HandlerFunc {
+	var state atomic.Value
 	return func(c *gin.Context) {
 		s := getHostServer(c)
+		cnf := s.Config()
+		current, ok := state.Load().(*allowUrlsState)
+		if !ok || current.cnf != cnf {
+			current = newAllowUrlsState(cnf)
+			state.Store(current)
+		}
+		allowUrls := current.allowUrls
 		user := getUser(c)
 		path := fmt.Sprintf("%s:%s", c.Request.Method, c.Request.URL.Path)
 		requestId := getRequestId(s, c)
@@ -117,7 +139,7 @@ func gwAuthChecker(urls []conf.AllowUrl) gin.HandlerFunc {
 		// UnAuthorized
 		//
 		if (user.IsEmpty() || !user.IsAuth()) && !allowUrls[path] {
-			auth := s.conf.Security.AuthServer
+			auth := cnf.Security.AuthServer
 			// Check url are allow dict.
 			payload := gin.H{
 				"Auth": gin.H{
@@ -134,9 +156,8 @@ func gwAuthChecker(urls []conf.AllowUrl) gin.HandlerFunc {
 					},
 				},
 			}
-			body := s.RespBodyBuildFunc(http.StatusUnauthorized, requestId, errDefault401Msg, payload)
-			c.JSON(http.StatusUnauthorized, body)
 			c.Abort()
+			respErr(c, requestId, http.StatusUnauthorized, ErrUnauthorized.WithMessage(errDefault401Msg), payload)
 			return
 		}
 		c.Next()
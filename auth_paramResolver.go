@@ -12,7 +12,7 @@ func (u UserPasswordAuthParamResolver) Resolve(c *gin.Context) AuthParameter {
 	s := getHostServer(c)
 	var tenantIdStr = ""
 	var param AuthParameter
-	paramKey := s.conf.Security.Auth.ParamKey
+	paramKey := s.Config().Security.Auth.ParamKey
 	// 1. User/Password
 	param.CredType = UserPasswordAuth
 	param.Passport, _ = c.GetPostForm(paramKey.Passport)
@@ -55,7 +55,7 @@ type BasicAuthParamResolver struct {
 func (u BasicAuthParamResolver) Resolve(c *gin.Context) AuthParameter {
 	s := getHostServer(c)
 	var tenantIdStr = ""
-	paramKey := s.conf.Security.Auth.ParamKey
+	paramKey := s.Config().Security.Auth.ParamKey
 	var param AuthParameter
 	// 2. Basic auth
 	var ok = false
@@ -77,7 +77,7 @@ type AksAuthParamResolver struct {
 func (u AksAuthParamResolver) Resolve(c *gin.Context) AuthParameter {
 	s := getHostServer(c)
 	var param AuthParameter
-	paramKey := s.conf.Security.Auth.ParamKey
+	paramKey := s.Config().Security.Auth.ParamKey
 	param.CredType = AksAuth
 	var tenantIdStr = c.GetHeader(paramKey.TenantId)
 	param.Passport = c.GetHeader(paramKey.Passport)
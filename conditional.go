@@ -0,0 +1,190 @@
+package gw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/backend/gwdb"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPreconditionFailed = NewError(http.StatusPreconditionFailed, ErrCodePreconditionFailed, "precondition failed")
+	modificationStateType = reflect.TypeOf(gwdb.HasModificationState{})
+)
+
+// Validator represents the validators of a resource representation for the conditional requests(RFC 7232).
+type Validator struct {
+	ETag         string
+	LastModified *time.Time
+}
+
+// Weak returns true if the ETag is a weak entity tag(W/"...").
+func (v Validator) Weak() bool {
+	return strings.HasPrefix(v.ETag, "W/")
+}
+
+// ValidatorOf returns the Validator of the entity,
+// It's a strong ETag(of the type, ID and ModifiedAt) and Last-Modified if the entity embeds a gwdb.HasModificationState that has ModifiedAt,
+// otherwise it's a strong ETag of the entity's JSON body hash, the body are byte-for-byte identical if the hash matches,
+// so the ETag can be used by If-Match.
+func ValidatorOf(entity interface{}) Validator {
+	if modifiedAt := modifiedAtOf(entity); modifiedAt != nil {
+		value := reflect.Indirect(reflect.ValueOf(entity))
+		h := sha256.Sum256([]byte(fmt.Sprintf("%s:%v:%d", value.Type().String(), idOf(value), modifiedAt.UnixNano())))
+		lastModified := modifiedAt.UTC().Truncate(time.Second)
+		return Validator{
+			ETag:         fmt.Sprintf(`"%s"`, hex.EncodeToString(h[:16])),
+			LastModified: &lastModified,
+		}
+	}
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return Validator{}
+	}
+	h := sha256.Sum256(b)
+	return Validator{ETag: fmt.Sprintf(`"%s"`, hex.EncodeToString(h[:16]))}
+}
+
+func modifiedAtOf(entity interface{}) *time.Time {
+	value := reflect.ValueOf(entity)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous && field.Type == modificationStateType {
+			return value.Field(i).Interface().(gwdb.HasModificationState).ModifiedAt
+		}
+	}
+	return nil
+}
+
+func idOf(value reflect.Value) interface{} {
+	if f := value.FieldByName("ID"); f.IsValid() {
+		return f.Interface()
+	}
+	return ""
+}
+
+// matchETag returns true if the If-Match/If-None-Match header value matches the etag,
+// weak is true for the weak comparison(If-None-Match).
+func matchETag(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(tag, "W/") && !strings.HasPrefix(etag, "W/") && tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func parseHttpTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
+
+// evaluatePreconditions evaluates the conditional request headers against the Validator by RFC 7232 section 6,
+// returns http.StatusPreconditionFailed, http.StatusNotModified or 0 if the request should be processed.
+func evaluatePreconditions(c *gin.Context, v Validator) int {
+	safe := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+	if header := c.GetHeader("If-Match"); header != "" {
+		if !matchETag(header, v.ETag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if t, ok := parseHttpTime(c.GetHeader("If-Unmodified-Since")); ok && v.LastModified != nil {
+		if v.LastModified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		if matchETag(header, v.ETag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if t, ok := parseHttpTime(c.GetHeader("If-Modified-Since")); ok && safe && v.LastModified != nil {
+		if !v.LastModified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+func setValidatorHeaders(c *gin.Context, v Validator) {
+	if v.ETag != "" {
+		c.Header("ETag", v.ETag)
+	}
+	if v.LastModified != nil {
+		c.Header("Last-Modified", v.LastModified.Format(http.TimeFormat))
+	}
+}
+
+// conditionalJSON200 response the payload with the ETag/Last-Modified headers,
+// it's response 304 if the If-None-Match/If-Modified-Since matched, or 412 if the If-Match/If-Unmodified-Since not matched.
+func (c *Context) conditionalJSON200(payload interface{}) {
+	v := ValidatorOf(payload)
+	switch evaluatePreconditions(c.Context, v) {
+	case http.StatusNotModified:
+		setValidatorHeaders(c.Context, v)
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	case http.StatusPreconditionFailed:
+		respErr(c.Context, c.RequestId(), 0, ErrPreconditionFailed, nil)
+		return
+	}
+	setValidatorHeaders(c.Context, v)
+	c.JSON200(payload)
+}
+
+// CheckPreconditions checks the If-Match/If-Unmodified-Since(and If-None-Match) headers against the current entity,
+// returns ErrPreconditionFailed if the entity has been modified by others, it's used to prevent lost updates.
+//
+// The framework can not load the current entity of a write, so the write handlers(such as dynamic REST Put/Patch/Delete)
+// honour the preconditions by calling it after the entity has been loaded. The ETag that responded by Get/Detail
+// matches if the entity(or it's ModifiedAt) has not been changed, the If-Unmodified-Since requires a gwdb.HasModificationState.
+//
+// Usage:
+//
+//	func (u *User) Put(c *gw.Context, in UserInput) (*User, error) {
+//		var user User
+//		// ... load the current user.
+//		if err := c.CheckPreconditions(&user); err != nil {
+//			return nil, err
+//		}
+//		// ... update the user.
+//	}
+func (c *Context) CheckPreconditions(entity interface{}) error {
+	if evaluatePreconditions(c.Context, ValidatorOf(entity)) != 0 {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
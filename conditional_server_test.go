@@ -0,0 +1,178 @@
+package gw_test
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/backend/gwdb"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type conditionalUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+	gwdb.HasModificationState
+}
+
+type conditionalUserQuery struct {
+	ID uint64 `uri:"id" binding:"required"`
+}
+
+type conditionalUpdateUser struct {
+	ID   uint64 `json:"id" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+type conditionalUserAPI struct {
+}
+
+func (u *conditionalUserAPI) Name() string {
+	return "users"
+}
+
+func (u *conditionalUserAPI) Detail(user gw.User, store gw.IStore, in *conditionalUserQuery) (*conditionalUser, error) {
+	var out conditionalUser
+	err := store.GetDbStore().Where("id = ? and tenant_id = ?", in.ID, user.TenantId).First(&out).Error
+	return &out, err
+}
+
+func (u *conditionalUserAPI) Put(c *gw.Context, in conditionalUpdateUser) (*conditionalUser, error) {
+	var user conditionalUser
+	db := c.Store().GetDbStore()
+	if err := db.Where("id = ? and tenant_id = ?", in.ID, c.User().TenantId).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if err := c.CheckPreconditions(&user); err != nil {
+		return nil, err
+	}
+	modifiedAt := time.Now()
+	user.Name, user.ModifiedAt = in.Name, &modifiedAt
+	return &user, db.Save(&user).Error
+}
+
+func TestServer_ConditionalRequests(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.RegisterRestAPIs(&conditionalUserAPI{})
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			db := state.Store().GetDbStore()
+			_ = db.AutoMigrate(&conditionalUser{})
+			modifiedAt := time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC)
+			db.Create(&conditionalUser{ID: 1, TenantId: 10, Name: "gw", HasModificationState: gwdb.HasModificationState{ModifiedAt: &modifiedAt}})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	detail := gwtest.Params{"id": "1"}
+
+	resp := client.Call("(*conditionalUserAPI).Detail", detail, nil).AssertOK()
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	assert.NotEmpty(t, etag)
+	assert.False(t, strings.HasPrefix(etag, "W/"))
+	assert.Equal(t, "Mon, 01 Jun 2020 08:00:00 GMT", lastModified)
+
+	resp = client.WithHeader("If-None-Match", etag).Call("(*conditionalUserAPI).Detail", detail, nil)
+	resp.AssertStatus(http.StatusNotModified)
+	assert.Empty(t, resp.Body)
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+	client.WithHeader("If-Modified-Since", lastModified).
+		Call("(*conditionalUserAPI).Detail", detail, nil).AssertStatus(http.StatusNotModified)
+	client.WithHeader("If-None-Match", `"stale"`).Call("(*conditionalUserAPI).Detail", detail, nil).AssertOK()
+
+	// lost updates are prevented by If-Match.
+	editor := client.WithHeader("If-Match", etag)
+	var user conditionalUser
+	editor.Call("(*conditionalUserAPI).Put", nil, conditionalUpdateUser{ID: 1, Name: "gw1"}).AssertOK().DecodePayload(&user)
+	assert.Equal(t, "gw1", user.Name)
+	editor.Call("(*conditionalUserAPI).Put", nil, conditionalUpdateUser{ID: 1, Name: "gw2"}).
+		AssertError(http.StatusPreconditionFailed, gw.ErrPreconditionFailed.Error())
+	client.WithHeader("If-Unmodified-Since", lastModified).
+		Call("(*conditionalUserAPI).Put", nil, conditionalUpdateUser{ID: 1, Name: "gw2"}).AssertStatus(http.StatusPreconditionFailed)
+
+	resp = client.WithHeader("If-None-Match", etag).Call("(*conditionalUserAPI).Detail", detail, nil).AssertOK()
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	resp.DecodePayload(&user)
+	assert.Equal(t, "gw1", user.Name)
+	client.WithHeader("If-Match", resp.Header.Get("ETag")).
+		Call("(*conditionalUserAPI).Put", nil, conditionalUpdateUser{ID: 1, Name: "gw2"}).AssertOK()
+}
+
+type conditionalNote struct {
+	ID       uint64
+	TenantId uint64
+	Text     string
+}
+
+type conditionalNoteQuery struct {
+	ID uint64 `uri:"id" binding:"required"`
+}
+
+type conditionalUpdateNote struct {
+	ID   uint64 `json:"id" binding:"required"`
+	Text string `json:"text" binding:"required"`
+}
+
+type conditionalNoteAPI struct {
+}
+
+func (n *conditionalNoteAPI) Name() string {
+	return "notes"
+}
+
+func (n *conditionalNoteAPI) Detail(user gw.User, store gw.IStore, in *conditionalNoteQuery) (*conditionalNote, error) {
+	var out conditionalNote
+	err := store.GetDbStore().Where("id = ? and tenant_id = ?", in.ID, user.TenantId).First(&out).Error
+	return &out, err
+}
+
+func (n *conditionalNoteAPI) Put(c *gw.Context, in conditionalUpdateNote) (*conditionalNote, error) {
+	var note conditionalNote
+	db := c.Store().GetDbStore()
+	if err := db.Where("id = ? and tenant_id = ?", in.ID, c.User().TenantId).First(&note).Error; err != nil {
+		return nil, err
+	}
+	if err := c.CheckPreconditions(&note); err != nil {
+		return nil, err
+	}
+	note.Text = in.Text
+	return &note, db.Save(&note).Error
+}
+
+func TestServer_ConditionalRequests_BodyHashETag(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.RegisterRestAPIs(&conditionalNoteAPI{})
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			db := state.Store().GetDbStore()
+			_ = db.AutoMigrate(&conditionalNote{})
+			db.Create(&conditionalNote{ID: 1, TenantId: 10, Text: "gw"})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	detail := gwtest.Params{"id": "1"}
+
+	// the models without ModifiedAt has the body hash ETag, it's can be used by If-Match.
+	resp := client.Call("(*conditionalNoteAPI).Detail", detail, nil).AssertOK()
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Empty(t, resp.Header.Get("Last-Modified"))
+	client.WithHeader("If-None-Match", etag).Call("(*conditionalNoteAPI).Detail", detail, nil).AssertStatus(http.StatusNotModified)
+
+	editor := client.WithHeader("If-Match", etag)
+	var note conditionalNote
+	editor.Call("(*conditionalNoteAPI).Put", nil, conditionalUpdateNote{ID: 1, Text: "gw1"}).AssertOK().DecodePayload(&note)
+	assert.Equal(t, "gw1", note.Text)
+	editor.Call("(*conditionalNoteAPI).Put", nil, conditionalUpdateNote{ID: 1, Text: "gw2"}).
+		AssertError(http.StatusPreconditionFailed, gw.ErrPreconditionFailed.Error())
+
+	resp = client.Call("(*conditionalNoteAPI).Detail", detail, nil).AssertOK()
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	client.WithHeader("If-Match", resp.Header.Get("ETag")).
+		Call("(*conditionalNoteAPI).Put", nil, conditionalUpdateNote{ID: 1, Text: "gw2"}).AssertOK()
+}
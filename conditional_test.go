@@ -0,0 +1,82 @@
+package gw
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/backend/gwdb"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type conditionalModel struct {
+	ID uint64
+	gwdb.HasModificationState
+	Name string
+}
+
+func TestValidatorOf(t *testing.T) {
+	v := ValidatorOf(map[string]string{"Name": "gw"})
+	assert.False(t, v.Weak())
+	assert.Nil(t, v.LastModified)
+	assert.Equal(t, v, ValidatorOf(map[string]string{"Name": "gw"}))
+	assert.NotEqual(t, v, ValidatorOf(map[string]string{"Name": "gw2"}))
+
+	// no ModifiedAt, the body hash ETag.
+	assert.False(t, ValidatorOf(&conditionalModel{ID: 1}).Weak())
+	assert.Nil(t, ValidatorOf(&conditionalModel{ID: 1}).LastModified)
+
+	modifiedAt := time.Date(2020, 6, 1, 8, 0, 0, 500, time.UTC)
+	m := &conditionalModel{ID: 1, HasModificationState: gwdb.HasModificationState{ModifiedAt: &modifiedAt}}
+	v = ValidatorOf(m)
+	assert.False(t, v.Weak())
+	assert.Equal(t, time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC), *v.LastModified)
+	// the strong ETag are not changed by the other fields.
+	m2 := *m
+	m2.Name = "gw"
+	assert.Equal(t, v, ValidatorOf(m2))
+	m2.ID = 2
+	assert.NotEqual(t, v.ETag, ValidatorOf(m2).ETag)
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	lastModified := time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC)
+	strong := Validator{ETag: `"abc"`, LastModified: &lastModified}
+	weak := Validator{ETag: `W/"abc"`}
+	before := lastModified.Add(-time.Hour).Format(http.TimeFormat)
+	after := lastModified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		method string
+		header map[string]string
+		v      Validator
+		want   int
+	}{
+		{http.MethodGet, nil, strong, 0},
+		{http.MethodGet, map[string]string{"If-None-Match": `"abc"`}, strong, http.StatusNotModified},
+		{http.MethodGet, map[string]string{"If-None-Match": `"x", W/"abc"`}, weak, http.StatusNotModified},
+		{http.MethodGet, map[string]string{"If-None-Match": `"x"`}, strong, 0},
+		{http.MethodGet, map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": after}, strong, 0},
+		{http.MethodGet, map[string]string{"If-Modified-Since": after}, strong, http.StatusNotModified},
+		{http.MethodGet, map[string]string{"If-Modified-Since": before}, strong, 0},
+		{http.MethodGet, map[string]string{"If-Modified-Since": after}, weak, 0},
+		{http.MethodPut, map[string]string{"If-Match": `"abc"`}, strong, 0},
+		{http.MethodPut, map[string]string{"If-Match": `"x"`}, strong, http.StatusPreconditionFailed},
+		{http.MethodPut, map[string]string{"If-Match": `W/"abc"`}, weak, http.StatusPreconditionFailed},
+		{http.MethodPut, map[string]string{"If-Match": "*"}, weak, 0},
+		{http.MethodPut, map[string]string{"If-Unmodified-Since": after}, strong, 0},
+		{http.MethodPut, map[string]string{"If-Unmodified-Since": before}, strong, http.StatusPreconditionFailed},
+		{http.MethodPut, map[string]string{"If-Match": `"abc"`, "If-Unmodified-Since": before}, strong, 0},
+		{http.MethodPut, map[string]string{"If-None-Match": "*"}, strong, http.StatusPreconditionFailed},
+		{http.MethodPut, map[string]string{"If-Modified-Since": after}, strong, 0},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(tt.method, "/", nil)
+		for k, v := range tt.header {
+			c.Request.Header.Set(k, v)
+		}
+		assert.Equal(t, tt.want, evaluatePreconditions(c, tt.v), "%s %v", tt.method, tt.header)
+	}
+}
@@ -84,6 +84,45 @@ type ApplicationConfig struct {
 	customJsonStrMaps map[string]string
 }
 
+// RateLimitPolicy represents a global rate limit policy of the urls(<METHOD>:/<path>),
+// the path can be has * suffix(prefix match), empty urls means all requests.
+type RateLimitPolicy struct {
+	Name      string   `yaml:"name" toml:"name" json:"name"`
+	Algorithm string   `yaml:"algorithm" toml:"algorithm" json:"algorithm"`
+	Limit     int      `yaml:"limit" toml:"limit" json:"limit,string"`
+	Window    int      `yaml:"window" toml:"window" json:"window,string"`
+	Key       string   `yaml:"key" toml:"key" json:"key"`
+	Store     string   `yaml:"store" toml:"store" json:"store"`
+	Urls      []string `yaml:"urls" toml:"urls" json:"urls"`
+}
+
+// CorsPolicy represents the CORS policy of the paths, the path can be has * suffix(prefix match), empty paths means all requests.
+//
+// The origins can be "*", a origin(https://gw.com) or a wildcard subdomain origin(https://*.gw.com).
+type CorsPolicy struct {
+	Paths            []string `yaml:"paths" toml:"paths" json:"paths"`
+	AllowOrigins     []string `yaml:"allowOrigins" toml:"allowOrigins" json:"allowOrigins"`
+	AllowMethods     []string `yaml:"allowMethods" toml:"allowMethods" json:"allowMethods"`
+	AllowHeaders     []string `yaml:"allowHeaders" toml:"allowHeaders" json:"allowHeaders"`
+	ExposeHeaders    []string `yaml:"exposeHeaders" toml:"exposeHeaders" json:"exposeHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials" toml:"allowCredentials" json:"allowCredentials"`
+	MaxAge           int      `yaml:"maxAge" toml:"maxAge" json:"maxAge,string"`
+}
+
+// BodyLimitRule represents the max request body size of the path, the path can be has * suffix(prefix match).
+type BodyLimitRule struct {
+	Path    string `yaml:"path" toml:"path" json:"path"`
+	MaxSize int64  `yaml:"maxSize" toml:"maxSize" json:"maxSize,string"`
+}
+
+// TracingExporter represents a span exporter, the name can be stdout, otlp or the registered exporters(gw.RegisterSpanExporter).
+type TracingExporter struct {
+	Name     string            `yaml:"name" toml:"name" json:"name"`
+	Endpoint string            `yaml:"endpoint" toml:"endpoint" json:"endpoint"`
+	Headers  map[string]string `yaml:"headers" toml:"headers" json:"headers"`
+	Timeout  int               `yaml:"timeout" toml:"timeout" json:"timeout,string"`
+}
+
 // allow urls
 type AllowUrl struct {
 	Name string   `yaml:"name" toml:"name" json:"name"`
@@ -97,6 +136,25 @@ type AllowUrl struct {
 type Server struct {
 	Name       string `yaml:"name" toml:"name" json:"name"`
 	ListenAddr string `yaml:"listenAddr" toml:"listenAddr" json:"listenAddr"`
+	TLS        TLS    `yaml:"tls" toml:"tls" json:"tls"`
+}
+
+// Server TLS/mTLS
+type TLS struct {
+	Enabled     bool `yaml:"enabled" toml:"enabled" json:"enabled"`
+	Certificate struct {
+		Key    string `yaml:"key" toml:"key" json:"key"`
+		Cert   string `yaml:"cert" toml:"cert" json:"cert"`
+		Format string `yaml:"format" toml:"format" json:"format"`
+	} `yaml:"certificate" toml:"certificate" json:"certificate"`
+	MinVersion   string   `yaml:"minVersion" toml:"minVersion" json:"minVersion"`
+	CipherSuites []string `yaml:"cipherSuites" toml:"cipherSuites" json:"cipherSuites"`
+	ClientAuth   struct {
+		Mode   string `yaml:"mode" toml:"mode" json:"mode"`
+		CA     string `yaml:"ca" toml:"ca" json:"ca"`
+		Format string `yaml:"format" toml:"format" json:"format"`
+	} `yaml:"clientAuth" toml:"clientAuth" json:"clientAuth"`
+	ReloadInterval int `yaml:"reloadInterval" toml:"reloadInterval" json:"reloadInterval,string"`
 }
 
 //
@@ -205,11 +263,27 @@ type Security struct {
 			Methods []string `yaml:"methods" toml:"methods" json:"methods"`
 		} `yaml:"logout" toml:"logout" json:"logout"`
 	} `yaml:"authServer" toml:"authServer" json:"authServer"`
+	Cors struct {
+		Enabled  bool         `yaml:"enabled" toml:"enabled" json:"enabled"`
+		Policies []CorsPolicy `yaml:"policies" toml:"policies" json:"policies"`
+	} `yaml:"cors" toml:"cors" json:"cors"`
+	Headers struct {
+		Enabled               bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+		HSTS                  string `yaml:"hsts" toml:"hsts" json:"hsts"`
+		ContentSecurityPolicy string `yaml:"contentSecurityPolicy" toml:"contentSecurityPolicy" json:"contentSecurityPolicy"`
+		FrameOptions          string `yaml:"frameOptions" toml:"frameOptions" json:"frameOptions"`
+		ReferrerPolicy        string `yaml:"referrerPolicy" toml:"referrerPolicy" json:"referrerPolicy"`
+		ContentTypeOptions    string `yaml:"contentTypeOptions" toml:"contentTypeOptions" json:"contentTypeOptions"`
+	} `yaml:"headers" toml:"headers" json:"headers"`
 	Limit struct {
 		Pagination struct {
 			MinPageSize int `yaml:"minPageSize" toml:"minPageSize" json:"minPageSize,string"`
 			MaxPageSize int `yaml:"maxPageSize" toml:"maxPageSize" json:"maxPageSize,string"`
 		} `yaml:"pagination" toml:"pagination" json:"pagination"`
+		Body struct {
+			MaxSize int64           `yaml:"maxSize" toml:"maxSize" json:"maxSize,string"`
+			Rules   []BodyLimitRule `yaml:"rules" toml:"rules" json:"rules"`
+		} `yaml:"body" toml:"body" json:"body"`
 	} `yaml:"limit" toml:"limit" json:"limit"`
 }
 
@@ -225,6 +299,20 @@ type Service struct {
 		Enabled bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
 		Router  string `yaml:"router" toml:"router" json:"router"`
 	} `yaml:"pprof" toml:"pprof" json:"pprof"`
+	Health struct {
+		Enabled   bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+		Liveness  string `yaml:"liveness" toml:"liveness" json:"liveness"`
+		Readiness string `yaml:"readiness" toml:"readiness" json:"readiness"`
+		Timeout   int    `yaml:"timeout" toml:"timeout" json:"timeout,string"`
+	} `yaml:"health" toml:"health" json:"health"`
+	Metrics struct {
+		Enabled bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+		Router  string `yaml:"router" toml:"router" json:"router"`
+	} `yaml:"metrics" toml:"metrics" json:"metrics"`
+	OpenAPI struct {
+		Enabled bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+		Router  string `yaml:"router" toml:"router" json:"router"`
+	} `yaml:"openapi" toml:"openapi" json:"openapi"`
 	ServiceDiscovery struct {
 		Enabled        bool `yaml:"enabled" toml:"enabled" json:"enabled"`
 		RegistryCenter struct {
@@ -251,10 +339,56 @@ type Settings struct {
 		Redis    int `yaml:"redis" toml:"redis" json:"redis,string"`
 		MongoDB  int `yaml:"mongo" toml:"mongo" json:"mongo,string"`
 		Database int `yaml:"database" toml:"database" json:"database,string"`
+		ShutDown int `yaml:"shutdown" toml:"shutdown" json:"shutdown,string"`
 	} `yaml:"timeoutControl" toml:"timeoutControl" json:"timeoutControl"`
 	ExpirationTimeControl struct {
 		Session int `yaml:"session" toml:"session" json:"session,string"`
 	} `yaml:"expirationTimeControl" toml:"expirationTimeControl" json:"expirationTimeControl"`
+	Errors struct {
+		ProblemDetails struct {
+			Enabled     bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+			TypeBaseUrl string `yaml:"typeBaseUrl" toml:"typeBaseUrl" json:"typeBaseUrl"`
+		} `yaml:"problemDetails" toml:"problemDetails" json:"problemDetails"`
+	} `yaml:"errors" toml:"errors" json:"errors"`
+	RateLimit struct {
+		Store    string            `yaml:"store" toml:"store" json:"store"`
+		Policies []RateLimitPolicy `yaml:"policies" toml:"policies" json:"policies"`
+	} `yaml:"rateLimit" toml:"rateLimit" json:"rateLimit"`
+	Idempotency struct {
+		Store       string `yaml:"store" toml:"store" json:"store"`
+		TTL         int    `yaml:"ttl" toml:"ttl" json:"ttl,string"`
+		LockTimeout int    `yaml:"lockTimeout" toml:"lockTimeout" json:"lockTimeout,string"`
+	} `yaml:"idempotency" toml:"idempotency" json:"idempotency"`
+	ResponseCache struct {
+		Store       string `yaml:"store" toml:"store" json:"store"`
+		TTL         int    `yaml:"ttl" toml:"ttl" json:"ttl,string"`
+		LockTimeout int    `yaml:"lockTimeout" toml:"lockTimeout" json:"lockTimeout,string"`
+	} `yaml:"responseCache" toml:"responseCache" json:"responseCache"`
+	Compression struct {
+		Enabled   bool     `yaml:"enabled" toml:"enabled" json:"enabled"`
+		Encodings []string `yaml:"encodings" toml:"encodings" json:"encodings"`
+		Level     int      `yaml:"level" toml:"level" json:"level,string"`
+		MinSize   int      `yaml:"minSize" toml:"minSize" json:"minSize,string"`
+		MimeTypes []string `yaml:"mimeTypes" toml:"mimeTypes" json:"mimeTypes"`
+	} `yaml:"compression" toml:"compression" json:"compression"`
+	Tracing struct {
+		Enabled        bool              `yaml:"enabled" toml:"enabled" json:"enabled"`
+		SampleRatio    float64           `yaml:"sampleRatio" toml:"sampleRatio" json:"sampleRatio,string"`
+		ResponseHeader string            `yaml:"responseHeader" toml:"responseHeader" json:"responseHeader"`
+		Exporters      []TracingExporter `yaml:"exporters" toml:"exporters" json:"exporters"`
+	} `yaml:"tracing" toml:"tracing" json:"tracing"`
+	I18n struct {
+		DefaultLocale string `yaml:"defaultLocale" toml:"defaultLocale" json:"defaultLocale"`
+		Dir           string `yaml:"dir" toml:"dir" json:"dir"`
+	} `yaml:"i18n" toml:"i18n" json:"i18n"`
+	WebSocket struct {
+		MaxMessageSize    int64   `yaml:"maxMessageSize" toml:"maxMessageSize" json:"maxMessageSize,string"`
+		MessagesPerSecond float64 `yaml:"messagesPerSecond" toml:"messagesPerSecond" json:"messagesPerSecond,string"`
+		Burst             int     `yaml:"burst" toml:"burst" json:"burst,string"`
+		PingInterval      int     `yaml:"pingInterval" toml:"pingInterval" json:"pingInterval,string"`
+		WriteTimeout      int     `yaml:"writeTimeout" toml:"writeTimeout" json:"writeTimeout,string"`
+		SendBuffer        int     `yaml:"sendBuffer" toml:"sendBuffer" json:"sendBuffer,string"`
+	} `yaml:"webSocket" toml:"webSocket" json:"webSocket"`
 }
 
 func (cnf ApplicationConfig) String() string {
@@ -351,6 +485,106 @@ func (cnf *ApplicationConfig) Compile() {
 	}
 }
 
+// Validate returns a error if the ApplicationConfig has invalid items.
+func (cnf *ApplicationConfig) Validate() error {
+	pagination := cnf.Security.Limit.Pagination
+	if pagination.MinPageSize < 0 || pagination.MaxPageSize < 0 {
+		return fmt.Errorf("security.limit.pagination, page size should be not negative")
+	}
+	if pagination.MaxPageSize > 0 && pagination.MinPageSize > pagination.MaxPageSize {
+		return fmt.Errorf("security.limit.pagination, minPageSize(%d) greater than maxPageSize(%d)",
+			pagination.MinPageSize, pagination.MaxPageSize)
+	}
+	for _, allowUrl := range cnf.Security.Auth.AllowUrls {
+		for _, url := range allowUrl.Urls {
+			items := strings.SplitN(url, ":", 2)
+			if len(items) != 2 || items[0] == "" || !strings.HasPrefix(items[1], "/") {
+				return fmt.Errorf("security.auth.allowUrls(%s), invalid url: %s, should be <METHOD>:/<path>", allowUrl.Name, url)
+			}
+		}
+	}
+	if cnf.Security.Auth.Cookie.MaxAge < 0 {
+		return fmt.Errorf("security.auth.cookie.maxAge should be not negative")
+	}
+	for i, p := range cnf.Security.Cors.Policies {
+		for _, path := range p.Paths {
+			if !strings.HasPrefix(path, "/") {
+				return fmt.Errorf("security.cors.policies[%d], invalid path: %s, should be /<path>", i, path)
+			}
+		}
+		if p.MaxAge < 0 {
+			return fmt.Errorf("security.cors.policies[%d], maxAge should be not negative", i)
+		}
+		for _, origin := range p.AllowOrigins {
+			if origin == "*" && p.AllowCredentials {
+				return fmt.Errorf("security.cors.policies[%d], the \"*\" origin can not be used with allowCredentials", i)
+			}
+		}
+	}
+	body := cnf.Security.Limit.Body
+	if body.MaxSize < 0 {
+		return fmt.Errorf("security.limit.body.maxSize should be not negative")
+	}
+	for _, r := range body.Rules {
+		if !strings.HasPrefix(r.Path, "/") || r.MaxSize < 0 {
+			return fmt.Errorf("security.limit.body.rules, invalid rule: %s(%d), path should be /<path> and maxSize should be not negative", r.Path, r.MaxSize)
+		}
+	}
+	compression := cnf.Settings.Compression
+	if compression.MinSize < 0 {
+		return fmt.Errorf("settings.compression.minSize should be not negative")
+	}
+	if compression.Level < -2 || compression.Level > 11 {
+		return fmt.Errorf("settings.compression.level(%d) should be in [-2, 11]", compression.Level)
+	}
+	tracing := cnf.Settings.Tracing
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		return fmt.Errorf("settings.tracing.sampleRatio(%v) should be in [0, 1]", tracing.SampleRatio)
+	}
+	for i, e := range tracing.Exporters {
+		if e.Name == "" {
+			return fmt.Errorf("settings.tracing.exporters[%d], name are required", i)
+		}
+		if e.Name == "otlp" && e.Endpoint == "" {
+			return fmt.Errorf("settings.tracing.exporters[%d], endpoint of the otlp exporter are required", i)
+		}
+		if e.Timeout < 0 {
+			return fmt.Errorf("settings.tracing.exporters[%d], timeout should be not negative", i)
+		}
+	}
+	for _, p := range cnf.Settings.RateLimit.Policies {
+		if p.Name == "" {
+			return fmt.Errorf("settings.rateLimit.policies, name are required")
+		}
+		if p.Algorithm != "" && p.Algorithm != "tokenBucket" && p.Algorithm != "slidingWindow" {
+			return fmt.Errorf("settings.rateLimit.policies(%s), invalid algorithm: %s, should be tokenBucket/slidingWindow", p.Name, p.Algorithm)
+		}
+		if p.Limit <= 0 || p.Window <= 0 {
+			return fmt.Errorf("settings.rateLimit.policies(%s), limit and window should be positive", p.Name)
+		}
+		if p.Key != "" {
+			for _, k := range strings.Split(p.Key, ",") {
+				switch strings.TrimSpace(k) {
+				case "ip", "user", "tenant", "accessKey", "route":
+				default:
+					return fmt.Errorf("settings.rateLimit.policies(%s), invalid key: %s, should be ip/user/tenant/accessKey/route", p.Name, k)
+				}
+			}
+		}
+		for _, url := range p.Urls {
+			items := strings.SplitN(url, ":", 2)
+			if len(items) != 2 || items[0] == "" || !strings.HasPrefix(items[1], "/") {
+				return fmt.Errorf("settings.rateLimit.policies(%s), invalid url: %s, should be <METHOD>:/<path>", p.Name, url)
+			}
+		}
+	}
+	timeout := cnf.Settings.TimeoutControl
+	if timeout.HTTP < 0 || timeout.Redis < 0 || timeout.Database < 0 || timeout.MongoDB < 0 || timeout.ShutDown < 0 {
+		return fmt.Errorf("settings.timeoutControl, timeout should be not negative")
+	}
+	return nil
+}
+
 // ============ End of configuration items ============= //
 
 // Extension defines.
@@ -447,6 +681,24 @@ func NewConfigWithBootConfig(bcs *BootConfig) *ApplicationConfig {
 	return cnf
 }
 
+// NewConfigFromBytes returns a ApplicationConfig that parsed from bytes, formatter can be yaml,yml,toml,json etc.
+func NewConfigFromBytes(formatter string, b []byte) (*ApplicationConfig, error) {
+	formatter = strings.TrimLeft(formatter, ".")
+	parser, ok := suffixParsers[fmt.Sprintf(".%s", formatter)]
+	if !ok {
+		return nil, fmt.Errorf("not supports app config formatter: %s", formatter)
+	}
+	var outPrepare interface{}
+	if err := parser(b, &outPrepare); err != nil {
+		return nil, err
+	}
+	cnf := &ApplicationConfig{}
+	if err := TemplateParser(outPrepare, cnf); err != nil {
+		return nil, err
+	}
+	return cnf, nil
+}
+
 func NewBootConfigFromBytes(formatter string, bytes []byte) *BootConfig {
 	logger.Debug("exec LoadBootConfigFromBytes(...), formatter: %s", formatter)
 	formatter = strings.TrimLeft(formatter, ".")
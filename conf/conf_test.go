@@ -2,6 +2,7 @@ package conf
 
 import (
 	"gopkg.in/yaml.v2"
+	"strings"
 	"testing"
 )
 
@@ -206,3 +207,76 @@ func TestApplicationConfig_ParseCustomPathTo(t *testing.T) {
 	}{}
 	_ = appcnf.ParseCustomPathTo("gwpro", &s)
 }
+
+func TestDiff(t *testing.T) {
+	var old, new ApplicationConfig
+	old.Security.Limit.Pagination.MaxPageSize = 2000
+	old.Security.Crypto.Protect.Secret = "old-secret"
+	new.Security.Limit.Pagination.MaxPageSize = 1000
+	new.Security.Crypto.Protect.Secret = "new-secret"
+	new.Security.Auth.AllowUrls = []AllowUrl{{Name: "auth", Urls: []string{"POST:/api/v1/login"}}}
+	var changes = Diff(&old, &new)
+	var items = strings.Join(changes, "\n")
+	if !strings.Contains(items, "~ security.limit.pagination.maxPageSize: \"2000\" -> \"1000\"") {
+		t.Errorf("missing pagination changes, got: %s", items)
+	}
+	if strings.Contains(items, "old-secret") || strings.Contains(items, "new-secret") {
+		t.Errorf("secret should be masked, got: %s", items)
+	}
+	if !strings.Contains(items, "security.auth.allowUrls") {
+		t.Errorf("missing allowUrls changes, got: %s", items)
+	}
+	if len(Diff(&old, &old)) != 0 {
+		t.Errorf("same config should be has no changes")
+	}
+	old.Backend.Cache = []Cache{{Name: "primary", Driver: "redis", Addr: "127.0.0.1", Port: 6379}}
+	for i := 0; i < 10; i++ {
+		if changes := Diff(&old, &old); len(changes) != 0 {
+			t.Errorf("same slice items should be has no changes, got: %s", strings.Join(changes, "\n"))
+		}
+	}
+}
+
+func TestApplicationConfig_Validate(t *testing.T) {
+	var cnf ApplicationConfig
+	cnf.Security.Limit.Pagination.MinPageSize = 20
+	cnf.Security.Limit.Pagination.MaxPageSize = 2000
+	cnf.Security.Auth.AllowUrls = []AllowUrl{{Name: "auth", Urls: []string{"POST:/api/v1/login"}}}
+	if err := cnf.Validate(); err != nil {
+		t.Errorf("validate fail, err: %v", err)
+	}
+	cnf.Security.Limit.Pagination.MinPageSize = 3000
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("minPageSize greater than maxPageSize should be invalid")
+	}
+	cnf.Security.Limit.Pagination.MinPageSize = 20
+	cnf.Security.Auth.AllowUrls[0].Urls = []string{"/api/v1/login"}
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("allow url without method should be invalid")
+	}
+	cnf.Security.Auth.AllowUrls[0].Urls = []string{"POST:/api/v1/login"}
+	cnf.Security.Cors.Policies = []CorsPolicy{{AllowOrigins: []string{"*"}, AllowCredentials: true}}
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("cors allow origin * with credentials should be invalid")
+	}
+	cnf.Security.Cors.Policies = nil
+	cnf.Security.Limit.Body.Rules = []BodyLimitRule{{Path: "api/v1/upload", MaxSize: 1024}}
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("body limit rule path without / prefix should be invalid")
+	}
+	cnf.Security.Limit.Body.Rules = nil
+	cnf.Settings.Compression.MinSize = -1
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("negative compression minSize should be invalid")
+	}
+	cnf.Settings.Compression.MinSize = 0
+	cnf.Settings.Tracing.SampleRatio = 1.5
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("tracing sampleRatio greater than 1 should be invalid")
+	}
+	cnf.Settings.Tracing.SampleRatio = 1
+	cnf.Settings.Tracing.Exporters = []TracingExporter{{Name: "otlp"}}
+	if err := cnf.Validate(); err == nil {
+		t.Errorf("otlp exporter without endpoint should be invalid")
+	}
+}
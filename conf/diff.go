@@ -0,0 +1,97 @@
+package conf
+
+import (
+	"fmt"
+	json "github.com/json-iterator/go"
+	"sort"
+	"strings"
+)
+
+var (
+	diffMaskedValue = "******"
+	diffSecretKeys  = []string{"password", "secret", "salt", "privatekey"}
+	// the map keys are sorted, so the values of the slice items(such as backend.db) are comparable.
+	diffJSON = json.ConfigCompatibleWithStandardLibrary
+)
+
+// Diff returns the changes between old and new ApplicationConfig, one item per changed path.
+//
+// items looks like:
+//
+// ~ security.limit.pagination.maxPageSize: "2000" -> "1000"
+//
+// + settings.headerKey.traceKey: "X-Trace-Id"
+//
+// - custom.gwpro.backend.db: "primary"
+//
+// The values of secret items (password, secret, salt etc.) are masked.
+func Diff(old, new *ApplicationConfig) []string {
+	var oldItems = flattenConfig(old)
+	var newItems = flattenConfig(new)
+	var keys = make([]string, 0, len(oldItems)+len(newItems))
+	for k := range oldItems {
+		keys = append(keys, k)
+	}
+	for k := range newItems {
+		if _, ok := oldItems[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var changes []string
+	for _, k := range keys {
+		o, inOld := oldItems[k]
+		n, inNew := newItems[k]
+		switch {
+		case inOld && !inNew:
+			changes = append(changes, fmt.Sprintf("- %s: %s", k, maskDiffValue(k, o)))
+		case !inOld && inNew:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", k, maskDiffValue(k, n)))
+		case o != n:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", k, maskDiffValue(k, o), maskDiffValue(k, n)))
+		}
+	}
+	return changes
+}
+
+func maskDiffValue(key, value string) string {
+	var lowerKey = strings.ToLower(key)
+	for _, k := range diffSecretKeys {
+		if strings.Contains(lowerKey, k) {
+			return diffMaskedValue
+		}
+	}
+	return value
+}
+
+func flattenConfig(cnf *ApplicationConfig) map[string]string {
+	var items = make(map[string]string)
+	if cnf == nil {
+		return items
+	}
+	b, err := json.Marshal(cnf)
+	if err != nil {
+		panic(fmt.Sprintf("flatten config fail on json.Marshal(), err: %v", err))
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		panic(fmt.Sprintf("flatten config fail on json.Unmarshal(), err: %v", err))
+	}
+	flattenConfigItem("", out, items)
+	return items
+}
+
+func flattenConfigItem(prefix string, value interface{}, items map[string]string) {
+	if mps, ok := value.(map[string]interface{}); ok && len(mps) > 0 {
+		for k, v := range mps {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenConfigItem(key, v, items)
+		}
+		return
+	}
+	b, _ := diffJSON.Marshal(value)
+	items[prefix] = string(b)
+}
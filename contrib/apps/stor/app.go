@@ -25,6 +25,7 @@ func (a App) Router() string {
 
 func (a App) Register(router *gw.RouterGroup) {
 	router.GET("object/create", api.CreateObject)
+	router.POST("object/create", api.CreateObject, gw.NewIdempotencyDecorator(0))
 	router.POST("object/modify", api.ModifyObject)
 }
 
@@ -43,6 +43,7 @@ type UserProfile struct {
 	Address  string `gorm:"type:varchar(256)"`
 	PostCode string `gorm:"type:varchar(16)"`
 	BirthDay *time.Time
+	Locale   string `gorm:"type:varchar(16)"` // the preferred locale, such as zh-CN, en.
 	gwdb.HasCreationState
 	gwdb.HasModificationState
 }
@@ -134,6 +134,10 @@ func (u UserManager) QueryByUser(tenantId uint64, passport, password string) (gw
 	if user.IsEmpty() {
 		return gw.EmptyUser, gw.ErrorUserNotFound
 	}
+	var profile Db.UserProfile
+	if err = u.Backend().Select("locale").Take(&profile, "tenant_id=? and user_id=?", model.TenantId, model.ID).Error; err == nil {
+		user.Locale = profile.Locale
+	}
 	_, perms, err := u.PermissionManager().QueryByUser(model.TenantId, model.ID, gw.DefaultPageExpr)
 	if err != nil {
 		return gw.EmptyUser, err
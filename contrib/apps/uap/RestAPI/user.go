@@ -32,6 +32,13 @@ func (u User) Post(ctx *gw.Context) {
 
 }
 
+// SetupOnPostDecorator, the retried user creations(same Idempotency-Key) are replayed.
+func (u User) SetupOnPostDecorator() []gw.Decorator {
+	return []gw.Decorator{
+		gw.NewIdempotencyDecorator(0),
+	}
+}
+
 // Put, Creation & decorators
 func (u User) Put(ctx *gw.Context) {
 }
@@ -1,10 +1,18 @@
 package gw
 
-import "sync"
+import (
+	"sync"
+)
 
+// Decorator represents a Before/After handler of the routers.
+//
+// Priority orders the decorators of a router, the Before of the lower priority decorators are called first,
+// and the After are called in reverse order(the lower priority decorators are called last),
+// the decorators that has same priority are called by the declared order.
 type Decorator struct {
 	Catalog  string
 	MetaData interface{}
+	Priority int
 	Before   DecoratorHandler
 	After    DecoratorHandler
 }
@@ -0,0 +1,178 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/logger"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	idempotencyDecoratorCatalog   = "gw_framework_idempotency"
+	idempotencyKeyPrefix          = "gw-idem"
+	idempotencyStateKey           = "gw-idempotency"
+	idempotencyKeyMaxLength       = 255
+	idempotencyLockPollInterval   = 20 * time.Millisecond
+	defaultIdempotencyTTL         = 24 * time.Hour
+	defaultIdempotencyLockTimeout = 30 * time.Second
+	IdempotencyKeyHeader          = "Idempotency-Key"
+	IdempotentReplayedHeader      = "Idempotent-Replayed"
+)
+
+var (
+	ErrIdempotencyKeyMismatch = NewError(http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyMismatch,
+		"the idempotency key has been used by a different request")
+	ErrIdempotencyKeyInProgress = NewError(http.StatusConflict, ErrCodeIdempotencyKeyInProgress,
+		"a request with the same idempotency key is in progress")
+	ErrIdempotencyKeyInvalid = ErrBadRequest.WithMessage("invalid idempotency key")
+)
+
+// idempotencyRecord represents the stored first response of a idempotency key.
+type idempotencyRecord struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+type idempotencyState struct {
+	store  lockedStore
+	key    string
+	record *idempotencyRecord
+	ttl    time.Duration
+}
+
+// NewIdempotencyDecorator returns a Decorator that honours the Idempotency-Key header of POST/PUT/PATCH/DELETE requests.
+//
+// The first response(status, headers and body) of a key are stored in the cache store(Settings.Idempotency.Store),
+// the replays that has same key and payload are responded by the stored response(with Idempotent-Replayed: true header),
+// a key reused with a different payload are responded by 422, the concurrent duplicates are serialised by a lock.
+// ttl is the expiration of the stored responses, 0 means Settings.Idempotency.TTL.
+//
+// The keys are scoped by the tenant and user(the client IP for the anonymous requests), the 5xx, 409 and 429 responses are not stored, so the clients can retry.
+func NewIdempotencyDecorator(ttl time.Duration) Decorator {
+	return Decorator{
+		Catalog:  idempotencyDecoratorCatalog,
+		MetaData: ttl,
+		Before: func(c *Context) (status int, err error, payload interface{}) {
+			return idempotencyBefore(c, ttl)
+		},
+		After: idempotencyAfter,
+	}
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func idempotencyBefore(c *Context, ttl time.Duration) (int, error, interface{}) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" || !isUnsafeMethod(c.Request.Method) {
+		return 0, nil, nil
+	}
+	if len(key) > idempotencyKeyMaxLength {
+		return http.StatusBadRequest, ErrIdempotencyKeyInvalid, nil
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return http.StatusBadRequest, ErrBadRequest.WithCause(err), nil
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + "\n" + c.Request.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+	s := c.server
+	cnf := s.Config().Settings.Idempotency
+	if ttl <= 0 {
+		ttl = durationOrDefault(cnf.TTL, defaultIdempotencyTTL)
+	}
+	lockTimeout := durationOrDefault(cnf.LockTimeout, defaultIdempotencyLockTimeout)
+	store := s.lockedStores.get(s, cnf.Store, "idempotency")
+	storeKey := fmt.Sprintf("%s:%s:%s", idempotencyKeyPrefix, idempotencyScopeOf(c), key)
+	var record idempotencyRecord
+	loaded, err := loadOrLock(c, store, storeKey, lockTimeout, idempotencyLockPollInterval, func(value []byte) (bool, error) {
+		return true, json.Unmarshal(value, &record)
+	})
+	if err == errLockWaitTimeout {
+		return http.StatusConflict, ErrIdempotencyKeyInProgress, nil
+	}
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternalServerError.WithCause(err), nil
+	}
+	if loaded {
+		if record.Fingerprint != fingerprint {
+			return http.StatusUnprocessableEntity, ErrIdempotencyKeyMismatch, nil
+		}
+		replayIdempotencyRecord(c.Context, &record)
+		return record.Status, nil, nil
+	}
+	c.Set(idempotencyStateKey, &idempotencyState{
+		store:  store,
+		key:    storeKey,
+		record: &idempotencyRecord{Fingerprint: fingerprint},
+		ttl:    ttl,
+	})
+	return 0, nil, nil
+}
+
+// idempotencyScopeOf returns the scope of the idempotency keys, the anonymous requests are scoped by the client IP,
+// so the anonymous clients are not replayed the responses of each other.
+func idempotencyScopeOf(c *Context) string {
+	user := c.User()
+	if !user.IsAuth() {
+		return "ip:" + c.ClientIP()
+	}
+	return fmt.Sprintf("%d:%d", user.TenantId, user.ID)
+}
+
+func idempotencyAfter(c *Context) (int, error, interface{}) {
+	obj, ok := c.Get(idempotencyStateKey)
+	if !ok {
+		return 0, nil, nil
+	}
+	state := obj.(*idempotencyState)
+	buf := c.ResponseBuffer()
+	if buf == nil {
+		return 0, nil, nil
+	}
+	status := buf.Status()
+	if status >= http.StatusInternalServerError || status == http.StatusConflict || status == http.StatusTooManyRequests {
+		return 0, nil, nil
+	}
+	record := state.record
+	record.Status = status
+	record.Body = buf.Bytes()
+	record.Header = buf.changedHeader()
+	record.Header.Del("Set-Cookie")
+	record.Header.Del("Date")
+	b, err := json.Marshal(record)
+	if err == nil {
+		err = state.store.save(context.Background(), state.key, b, state.ttl)
+	}
+	if err != nil {
+		logger.Error("save idempotency key: %s response fail, err: %v", state.key, err)
+	}
+	return 0, nil, nil
+}
+
+func replayIdempotencyRecord(c *gin.Context, record *idempotencyRecord) {
+	header := c.Writer.Header()
+	replayHeader(header, record.Header)
+	header.Set(IdempotentReplayedHeader, "true")
+	c.Writer.WriteHeader(record.Status)
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(record.Body)
+}
@@ -0,0 +1,175 @@
+package gw_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var idempotencyOrderSeq uint64
+
+func idempotencyCreateOrder(c *gw.Context) {
+	var in struct {
+		Sku string `json:"sku" binding:"required"`
+	}
+	if c.Bind(&in) != nil {
+		return
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.JSON200(gin.H{"Id": atomic.AddUint64(&idempotencyOrderSeq, 1), "Sku": in.Sku})
+}
+
+func TestServer_Idempotency(t *testing.T) {
+	type order struct {
+		Id  uint64
+		Sku string
+	}
+	for _, store := range []string{"", "primary"} {
+		server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+			opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+				cnf := gwtest.DefaultConfig()
+				cnf.Settings.Idempotency.Store = store
+				cnf.Security.Auth.AllowUrls = append(cnf.Security.Auth.AllowUrls,
+					conf.AllowUrl{Name: "idempotency", Urls: []string{"POST:/api/v1/tester/orders"}})
+				return cnf
+			}
+		}, &gwtest.App{
+			RegisterFunc: func(router *gw.RouterGroup) {
+				router.POST("orders", idempotencyCreateOrder, gw.NewIdempotencyDecorator(0))
+			},
+		})
+		client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+		keyed := client.WithHeader(gw.IdempotencyKeyHeader, "order-1")
+
+		var first, replayed order
+		resp := keyed.Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&first)
+		assert.Empty(t, resp.Header.Get(gw.IdempotentReplayedHeader))
+		resp = keyed.Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&replayed)
+		assert.Equal(t, "true", resp.Header.Get(gw.IdempotentReplayedHeader))
+		assert.Equal(t, first, replayed)
+
+		resp = keyed.Call("idempotencyCreateOrder", nil, gin.H{"sku": "b"}).AssertStatus(http.StatusUnprocessableEntity)
+		assert.Equal(t, gw.ErrCodeIdempotencyKeyMismatch, resp.Envelope().Code)
+
+		// no key, or other users are not replayed.
+		client.Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&replayed)
+		assert.NotEqual(t, first.Id, replayed.Id)
+		server.Client().LoginAs(gw.User{ID: 2, TenantId: 10, Passport: "other"}).WithHeader(gw.IdempotencyKeyHeader, "order-1").
+			Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&replayed)
+		assert.NotEqual(t, first.Id, replayed.Id)
+
+		// the anonymous requests are scoped by the client IP.
+		anonymous := server.Client().WithHeader(gw.IdempotencyKeyHeader, "order-1")
+		anonymous.WithHeader("X-Forwarded-For", "10.0.0.1").
+			Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&first)
+		resp = anonymous.WithHeader("X-Forwarded-For", "10.0.0.1").
+			Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&replayed)
+		assert.Equal(t, "true", resp.Header.Get(gw.IdempotentReplayedHeader))
+		assert.Equal(t, first, replayed)
+		resp = anonymous.WithHeader("X-Forwarded-For", "10.0.0.2").
+			Call("idempotencyCreateOrder", nil, gin.H{"sku": "a"}).AssertOK().DecodePayload(&replayed)
+		assert.Empty(t, resp.Header.Get(gw.IdempotentReplayedHeader))
+		assert.NotEqual(t, first.Id, replayed.Id)
+
+		// the invalid requests are replayed also.
+		keyed = client.WithHeader(gw.IdempotencyKeyHeader, "order-2")
+		keyed.Call("idempotencyCreateOrder", nil, gin.H{}).AssertStatus(http.StatusBadRequest)
+		resp = keyed.Call("idempotencyCreateOrder", nil, gin.H{}).AssertStatus(http.StatusBadRequest)
+		assert.Equal(t, "true", resp.Header.Get(gw.IdempotentReplayedHeader))
+
+		// the concurrent duplicates are serialised.
+		keyed = client.WithHeader(gw.IdempotencyKeyHeader, "order-3")
+		var wg sync.WaitGroup
+		var orders = make([]order, 5)
+		for i := range orders {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				keyed.Call("idempotencyCreateOrder", nil, gin.H{"sku": "c"}).AssertOK().DecodePayload(&orders[i])
+			}(i)
+		}
+		wg.Wait()
+		for _, o := range orders {
+			assert.Equal(t, orders[0], o, store)
+		}
+	}
+}
+
+func TestServer_Idempotency_LongRunning(t *testing.T) {
+	for _, store := range []string{"", "primary"} {
+		var executed int32
+		started := make(chan struct{})
+		server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+			opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+				cnf := gwtest.DefaultConfig()
+				cnf.Settings.Idempotency.Store = store
+				cnf.Settings.Idempotency.LockTimeout = 60
+				return cnf
+			}
+		}, &gwtest.App{
+			RegisterFunc: func(router *gw.RouterGroup) {
+				router.POST("reports", func(c *gw.Context) {
+					atomic.AddInt32(&executed, 1)
+					close(started)
+					// runs longer than the lock timeout.
+					time.Sleep(300 * time.Millisecond)
+					c.JSON200("done")
+				}, gw.NewIdempotencyDecorator(0))
+			},
+		})
+		keyed := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"}).
+			WithHeader(gw.IdempotencyKeyHeader, "report-1")
+		path := "/api/v1/tester/reports"
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			keyed.Post(path, gin.H{}).AssertOK()
+		}()
+		<-started
+		// the lock are kept by the owner, the duplicate are not executed.
+		resp := keyed.Post(path, gin.H{}).AssertStatus(http.StatusConflict)
+		assert.Equal(t, gw.ErrCodeIdempotencyKeyInProgress, resp.Envelope().Code, store)
+		<-done
+		assert.Equal(t, int32(1), atomic.LoadInt32(&executed), store)
+	}
+}
+
+func idempotencyCreatePayment(c *gw.Context) {
+	c.Header("Location", "/payments/1")
+	c.JSON200("paid")
+}
+
+func TestServer_Idempotency_Headers(t *testing.T) {
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Security.Cors.Enabled = true
+			cnf.Security.Cors.Policies = []conf.CorsPolicy{{AllowOrigins: []string{"https://*.gw-framework.com"}}}
+			cnf.Settings.Tracing.Enabled = true
+			cnf.Settings.Tracing.SampleRatio = 1
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.POST("payments", idempotencyCreatePayment, gw.NewIdempotencyDecorator(0))
+		},
+	})
+	keyed := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"}).WithHeader(gw.IdempotencyKeyHeader, "payment-1")
+	first := keyed.WithHeader("Origin", "https://a.gw-framework.com").Call("idempotencyCreatePayment", nil, nil).AssertOK()
+	replayed := keyed.WithHeader("Origin", "https://b.gw-framework.com").Call("idempotencyCreatePayment", nil, nil).AssertOK()
+	assert.Equal(t, "true", replayed.Header.Get(gw.IdempotentReplayedHeader))
+
+	// the headers of the handler are replayed, the per-request headers are not.
+	assert.Equal(t, "/payments/1", replayed.Header.Get("Location"))
+	assert.Equal(t, first.Header.Get("Content-Type"), replayed.Header.Get("Content-Type"))
+	assert.Equal(t, "https://b.gw-framework.com", replayed.Header.Get("Access-Control-Allow-Origin"))
+	assert.NotEqual(t, first.Header.Get("X-Trace-Id"), replayed.Header.Get("X-Trace-Id"))
+}
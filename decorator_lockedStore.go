@@ -0,0 +1,308 @@
+package gw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/oceanho/gw/logger"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// errLockWaitTimeout is returned by loadOrLock if the lock owner has not finished in time(or the request are done).
+	errLockWaitTimeout = fmt.Errorf("wait for the lock owner timeout")
+)
+
+var redisUnlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+var redisRefreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// lockedStore represents the key-value store of the decorators(such as response cache, idempotency),
+// the producers of a key are serialised by a lock, see loadOrLock.
+type lockedStore interface {
+	// get returns the value of key, returns nil if the key not exists.
+	get(ctx context.Context, key string) ([]byte, error)
+	save(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	lock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	unlock(ctx context.Context, key, token string) error
+	// refresh extends the ttl of the lock, returns false if the lock are not owned by the token.
+	refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// counters returns the values of the counters, the not exists counters are 0.
+	counters(ctx context.Context, keys []string) ([]int64, error)
+	incr(ctx context.Context, keys []string) error
+}
+
+// loadOrLock loads the value of key by load(it's returns false if the value can not be used, such as stale),
+// or locks the key(key:lock) for the caller to produce the value if it's missed.
+// The concurrent callers of a missed key are waiting for the lock owner, they are loaded the value that saved by the owner.
+//
+// It's returns true if the value are loaded, false if the caller owns the lock, the lock are refreshed(by lockTimeout)
+// while the request runs, and released after the request finished(the value should be saved before, such as in the After decorators).
+// errLockWaitTimeout are returned if the lock owner has not finished in lockTimeout.
+func loadOrLock(c *Context, store lockedStore, key string, lockTimeout, pollInterval time.Duration,
+	load func(value []byte) (bool, error)) (bool, error) {
+	lockKey := key + ":lock"
+	token := c.server.IDGenerator.NewStrID()
+	deadline := time.Now().Add(lockTimeout)
+	for locked := false; ; {
+		loaded, err := loadLockedStoreValue(c, store, key, load)
+		if err != nil || loaded {
+			if locked {
+				_ = store.unlock(context.Background(), lockKey, token)
+			}
+			return loaded, err
+		}
+		if locked {
+			break
+		}
+		// the value are loaded again after locked, it's may be saved by the lock owner before.
+		if locked, err = store.lock(c, lockKey, token, lockTimeout); err != nil {
+			return false, err
+		}
+		if locked {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return false, errLockWaitTimeout
+		}
+		select {
+		case <-c.Done():
+			return false, errLockWaitTimeout
+		case <-time.After(pollInterval):
+		}
+	}
+	// the lock are refreshed while the handler runs(it's may be longer than the lockTimeout), so the concurrent duplicates
+	// can not lock it, and it's released after the request finished, even if the handler panics.
+	requestCtx := c.Request.Context()
+	go func() {
+		ticker := time.NewTicker(lockRefreshInterval(lockTimeout))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-requestCtx.Done():
+				if err := store.unlock(context.Background(), lockKey, token); err != nil {
+					logger.Error("unlock: %s fail, err: %v", lockKey, err)
+				}
+				return
+			case <-ticker.C:
+				if _, err := store.refresh(context.Background(), lockKey, token, lockTimeout); err != nil {
+					logger.Warn("refresh lock: %s fail, err: %v", lockKey, err)
+				}
+			}
+		}
+	}()
+	return false, nil
+}
+
+// lockRefreshInterval returns the refresh interval of the lock, it's a third of the lock ttl.
+func lockRefreshInterval(ttl time.Duration) time.Duration {
+	if interval := ttl / 3; interval > time.Millisecond {
+		return interval
+	}
+	return time.Millisecond
+}
+
+func loadLockedStoreValue(c *Context, store lockedStore, key string, load func(value []byte) (bool, error)) (bool, error) {
+	value, err := store.get(c, key)
+	if err != nil || value == nil {
+		return false, err
+	}
+	return load(value)
+}
+
+// lockedStores represents the locked stores of the server, the cache stores are got by IStore.GetCacheStoreByName.
+type lockedStores struct {
+	memory *memoryLockedStore
+	caches *cacheStoreResolver
+}
+
+func newLockedStores() *lockedStores {
+	return &lockedStores{
+		memory: newMemoryLockedStore(),
+		caches: newCacheStoreResolver(),
+	}
+}
+
+// get returns the cache store of name, the in-process store are used if name is empty(or the cache store not available),
+// usage is the decorator that uses the store(such as idempotency), it's used for logging.
+func (ls *lockedStores) get(s *HostServer, name, usage string) lockedStore {
+	if name == "" {
+		return ls.memory
+	}
+	if client, ok := ls.caches.resolve(s, name, usage); ok {
+		return redisLockedStore{client: client}
+	}
+	return ls.memory
+}
+
+// memoryLockedStore represents a in-process locked store.
+type memoryLockedStore struct {
+	locker    sync.Mutex
+	values    map[string]memoryLockedValue
+	counts    map[string]int64
+	lastSweep time.Time
+}
+
+type memoryLockedValue struct {
+	value    []byte
+	token    string
+	expireAt time.Time
+}
+
+func newMemoryLockedStore() *memoryLockedStore {
+	return &memoryLockedStore{
+		values:    make(map[string]memoryLockedValue),
+		counts:    make(map[string]int64),
+		lastSweep: time.Now(),
+	}
+}
+
+// value returns the value of key, the expired values are removed every rateLimitSweepInterval.
+func (ms *memoryLockedStore) value(key string) (memoryLockedValue, bool) {
+	now := time.Now()
+	if now.Sub(ms.lastSweep) > rateLimitSweepInterval {
+		for k, v := range ms.values {
+			if now.After(v.expireAt) {
+				delete(ms.values, k)
+			}
+		}
+		ms.lastSweep = now
+	}
+	v, ok := ms.values[key]
+	if !ok || now.After(v.expireAt) {
+		return v, false
+	}
+	return v, true
+}
+
+func (ms *memoryLockedStore) get(ctx context.Context, key string) ([]byte, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	v, _ := ms.value(key)
+	return v.value, nil
+}
+
+func (ms *memoryLockedStore) save(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	ms.values[key] = memoryLockedValue{value: value, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (ms *memoryLockedStore) lock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	if _, ok := ms.value(key); ok {
+		return false, nil
+	}
+	ms.values[key] = memoryLockedValue{token: token, expireAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (ms *memoryLockedStore) unlock(ctx context.Context, key, token string) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	if v, ok := ms.values[key]; ok && v.token == token {
+		delete(ms.values, key)
+	}
+	return nil
+}
+
+func (ms *memoryLockedStore) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	v, ok := ms.value(key)
+	if !ok || v.token != token {
+		return false, nil
+	}
+	v.expireAt = time.Now().Add(ttl)
+	ms.values[key] = v
+	return true, nil
+}
+
+func (ms *memoryLockedStore) counters(ctx context.Context, keys []string) ([]int64, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	var values = make([]int64, len(keys))
+	for i, key := range keys {
+		values[i] = ms.counts[key]
+	}
+	return values, nil
+}
+
+func (ms *memoryLockedStore) incr(ctx context.Context, keys []string) error {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	for _, key := range keys {
+		ms.counts[key]++
+	}
+	return nil
+}
+
+// redisLockedStore represents a Redis locked store.
+type redisLockedStore struct {
+	client *redis.Client
+}
+
+func (rs redisLockedStore) get(ctx context.Context, key string) ([]byte, error) {
+	b, err := rs.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (rs redisLockedStore) save(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return rs.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (rs redisLockedStore) lock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return rs.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+func (rs redisLockedStore) unlock(ctx context.Context, key, token string) error {
+	return redisUnlockScript.Run(ctx, rs.client, []string{key}, token).Err()
+}
+
+func (rs redisLockedStore) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	n, err := redisRefreshScript.Run(ctx, rs.client, []string{key}, token, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (rs redisLockedStore) counters(ctx context.Context, keys []string) ([]int64, error) {
+	var values = make([]int64, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	results, err := rs.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range results {
+		if s, ok := r.(string); ok {
+			values[i], _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return values, nil
+}
+
+func (rs redisLockedStore) incr(ctx context.Context, keys []string) error {
+	pipe := rs.client.Pipeline()
+	for _, key := range keys {
+		pipe.Incr(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
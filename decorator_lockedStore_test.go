@@ -0,0 +1,57 @@
+package gw
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLockedStores(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.Nil(t, err)
+	t.Cleanup(mr.Close)
+	stores := map[string]lockedStore{
+		"memory": newMemoryLockedStore(),
+		"redis":  redisLockedStore{client: redis.NewClient(&redis.Options{Addr: mr.Addr()})},
+	}
+	for name, store := range stores {
+		ctx := context.Background()
+		value, err := store.get(ctx, "k")
+		assert.Nil(t, err, name)
+		assert.Nil(t, value, name)
+		assert.Nil(t, store.save(ctx, "k", []byte("ok"), time.Minute), name)
+		value, _ = store.get(ctx, "k")
+		assert.Equal(t, []byte("ok"), value, name)
+
+		counters, err := store.counters(ctx, []string{"a", "b"})
+		assert.Nil(t, err, name)
+		assert.Equal(t, []int64{0, 0}, counters, name)
+		assert.Nil(t, store.incr(ctx, []string{"b"}), name)
+		counters, _ = store.counters(ctx, []string{"a", "b"})
+		assert.Equal(t, []int64{0, 1}, counters, name)
+
+		locked, err := store.lock(ctx, "k:lock", "t1", time.Minute)
+		assert.Nil(t, err, name)
+		assert.True(t, locked, name)
+		locked, _ = store.lock(ctx, "k:lock", "t2", time.Minute)
+		assert.False(t, locked, name)
+		assert.Nil(t, store.unlock(ctx, "k:lock", "t2"), name)
+		locked, _ = store.lock(ctx, "k:lock", "t2", time.Minute)
+		assert.False(t, locked, name)
+		assert.Nil(t, store.unlock(ctx, "k:lock", "t1"), name)
+		locked, _ = store.lock(ctx, "k:lock", "t2", time.Minute)
+		assert.True(t, locked, name)
+
+		refreshed, err := store.refresh(ctx, "k:lock", "t1", time.Minute)
+		assert.Nil(t, err, name)
+		assert.False(t, refreshed, name)
+		refreshed, err = store.refresh(ctx, "k:lock", "t2", time.Minute)
+		assert.Nil(t, err, name)
+		assert.True(t, refreshed, name)
+		refreshed, _ = store.refresh(ctx, "missing:lock", "t2", time.Minute)
+		assert.False(t, refreshed, name)
+	}
+}
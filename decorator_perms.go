@@ -10,11 +10,13 @@ import (
 const permissionDecoratorCatalog = "gw_framework_permission"
 
 var (
-	ErrUnauthorized        = fmt.Errorf("has no credentitals")
-	ErrInternalServerError = fmt.Errorf("server internal error")
-	ErrBadRequest          = fmt.Errorf("bad request")
-	ErrNotFoundRequest     = fmt.Errorf("not found")
-	ErrPermissionDenied    = fmt.Errorf("permission denied")
+	ErrUnauthorized        = NewError(http.StatusUnauthorized, ErrCodeUnauthorized, "has no credentitals")
+	ErrInternalServerError = NewError(http.StatusInternalServerError, ErrCodeInternalError, "server internal error")
+	ErrBadRequest          = NewError(http.StatusBadRequest, ErrCodeBadRequest, "bad request")
+	ErrNotFoundRequest     = NewError(http.StatusNotFound, ErrCodeNotFound, "not found")
+	ErrPermissionDenied    = NewError(http.StatusForbidden, ErrCodePermissionDenied, "permission denied")
+
+	errPermissionDeniedNeedMsg = "Permission Denied, need:(%s)"
 )
 
 type PermissionDecorator struct {
@@ -158,7 +160,7 @@ func NewPermissionDecorator(perms ...Permission) Decorator {
 	for idx := 0; idx < len(perms); idx++ {
 		names[idx] = perms[idx].Name
 	}
-	msg := fmt.Sprintf("Permission Deined, need:(%s)", strings.Join(names, "|"))
+	need := strings.Join(names, "|")
 	return Decorator{
 		MetaData: perms,
 		Before: func(c *Context) (status int, err error, payload interface{}) {
@@ -166,7 +168,7 @@ func NewPermissionDecorator(perms ...Permission) Decorator {
 			if s.PermissionManager.Checker().Check(c.User(), perms...) {
 				return 0, nil, nil
 			}
-			return http.StatusForbidden, ErrPermissionDenied, msg
+			return http.StatusForbidden, ErrPermissionDenied, c.T(errPermissionDeniedNeedMsg, need)
 		},
 		After: nil,
 	}
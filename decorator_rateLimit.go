@@ -0,0 +1,479 @@
+package gw
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/logger"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitDecoratorCatalog = "gw_framework_rate_limit"
+	rateLimitKeyPrefix        = "gw-rl"
+	rateLimitSweepInterval    = time.Minute
+)
+
+// The rate limit algorithms.
+const (
+	RateLimitTokenBucket   = "tokenBucket"
+	RateLimitSlidingWindow = "slidingWindow"
+)
+
+// The rate limit key strategies, it's can be combined by comma, such as "tenant,route".
+const (
+	RateLimitKeyIP        = "ip"
+	RateLimitKeyUser      = "user"
+	RateLimitKeyTenant    = "tenant"
+	RateLimitKeyAccessKey = "accessKey"
+	RateLimitKeyRoute     = "route"
+)
+
+var (
+	ErrTooManyRequests = NewError(http.StatusTooManyRequests, ErrCodeTooManyRequests, "too many requests")
+)
+
+// RateLimitPolicy represents a rate limit policy.
+//
+// The token bucket allows Limit requests burst, and refills Limit tokens per Window.
+// The sliding window allows Limit requests in any Window(weighted by previous and current fixed windows).
+type RateLimitPolicy struct {
+	Name      string        // the policies that has same name are shared the limit states, empty means generated by the policy.
+	Algorithm string        // RateLimitTokenBucket or RateLimitSlidingWindow, empty means RateLimitSlidingWindow.
+	Limit     int           // the max requests of per window(sliding window), the bucket capacity(token bucket).
+	Window    time.Duration // the window(sliding window), the time of refill Limit tokens(token bucket).
+	Key       string        // the key strategies, empty means RateLimitKeyIP.
+	Store     string        // the cache store name of IStore, empty means Settings.RateLimit.Store.
+	urls      []rateLimitUrl
+}
+
+// rateLimitUrl represents a <METHOD>:/<path> url of the config-driven policies.
+type rateLimitUrl struct {
+	method string
+	path   string
+}
+
+// RateLimitResult represents the result of a rate limit check.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // the time of the limit fully reset.
+	RetryAfter time.Duration // the time of next request can be allowed, 0 if Allowed.
+}
+
+// NewRateLimitDecorator returns a Decorator that limits the requests rate of the router,
+// the rejected requests are responded by 429 with the Retry-After header.
+func NewRateLimitDecorator(policy RateLimitPolicy) Decorator {
+	policy, err := policy.normalize()
+	if err != nil {
+		panic(err.Error())
+	}
+	return Decorator{
+		Catalog:  rateLimitDecoratorCatalog,
+		MetaData: policy,
+		Before: func(c *Context) (status int, err error, payload interface{}) {
+			if rateLimit(c.server, c.Context, policy) {
+				return 0, nil, nil
+			}
+			return http.StatusTooManyRequests, ErrTooManyRequests, nil
+		},
+	}
+}
+
+// compileRateLimitPolicies returns the policies that defined by Settings.RateLimit.Policies,
+// it's called when the ApplicationConfig are loaded(or reloaded), so the invalid policies are rejected before serving.
+func compileRateLimitPolicies(cnf *conf.ApplicationConfig) ([]RateLimitPolicy, error) {
+	var policies = make([]RateLimitPolicy, 0, len(cnf.Settings.RateLimit.Policies))
+	for _, p := range cnf.Settings.RateLimit.Policies {
+		policy := RateLimitPolicy{
+			Name:      p.Name,
+			Algorithm: p.Algorithm,
+			Limit:     p.Limit,
+			Window:    time.Duration(p.Window) * time.Millisecond,
+			Key:       p.Key,
+			Store:     p.Store,
+		}
+		for _, url := range p.Urls {
+			items := strings.SplitN(url, ":", 2)
+			if len(items) != 2 || items[0] == "" || !strings.HasPrefix(items[1], "/") {
+				return nil, fmt.Errorf("rate limit policy: %s, invalid url: %s, should be <METHOD>:/<path>", p.Name, url)
+			}
+			policy.urls = append(policy.urls, rateLimitUrl{method: items[0], path: items[1]})
+		}
+		policy, err := policy.normalize()
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func (p RateLimitPolicy) normalize() (RateLimitPolicy, error) {
+	if p.Limit < 1 || p.Window <= 0 {
+		return p, fmt.Errorf("rate limit policy: %s, limit and window should be positive", p.Name)
+	}
+	if p.Algorithm == "" {
+		p.Algorithm = RateLimitSlidingWindow
+	}
+	if p.Algorithm != RateLimitTokenBucket && p.Algorithm != RateLimitSlidingWindow {
+		return p, fmt.Errorf("rate limit policy: %s, invalid algorithm: %s", p.Name, p.Algorithm)
+	}
+	if p.Key == "" {
+		p.Key = RateLimitKeyIP
+	}
+	for _, k := range strings.Split(p.Key, ",") {
+		switch strings.TrimSpace(k) {
+		case RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyTenant, RateLimitKeyAccessKey, RateLimitKeyRoute:
+		default:
+			return p, fmt.Errorf("rate limit policy: %s, invalid key: %s", p.Name, k)
+		}
+	}
+	if p.Name == "" {
+		p.Name = fmt.Sprintf("%s-%d-%d-%s", p.Algorithm, p.Limit, p.Window.Milliseconds(), p.Key)
+	}
+	return p, nil
+}
+
+// keyOf returns the state key of the request, the user/tenant/accessKey strategies are fallback to ip if it's absent.
+func (p RateLimitPolicy) keyOf(c *gin.Context) string {
+	var sb strings.Builder
+	sb.WriteString(rateLimitKeyPrefix)
+	sb.WriteString(":")
+	sb.WriteString(p.Name)
+	user := getUser(c)
+	for _, k := range strings.Split(p.Key, ",") {
+		sb.WriteString(":")
+		switch strings.TrimSpace(k) {
+		case RateLimitKeyUser:
+			if user.IsAuth() {
+				sb.WriteString("u" + strconv.FormatUint(user.ID, 10))
+				continue
+			}
+		case RateLimitKeyTenant:
+			if user.IsAuth() {
+				sb.WriteString("t" + strconv.FormatUint(user.TenantId, 10))
+				continue
+			}
+		case RateLimitKeyAccessKey:
+			if ak := (AksAuthParamResolver{}).Resolve(c).Passport; ak != "" {
+				sb.WriteString("ak" + ak)
+				continue
+			}
+		case RateLimitKeyRoute:
+			sb.WriteString(c.Request.Method + c.FullPath())
+			continue
+		}
+		sb.WriteString("ip" + c.ClientIP())
+	}
+	return sb.String()
+}
+
+// match returns true if the request matches the urls of the policy.
+func (p RateLimitPolicy) match(c *gin.Context) bool {
+	if len(p.urls) == 0 {
+		return true
+	}
+	for _, url := range p.urls {
+		if url.method != "*" && !strings.EqualFold(url.method, c.Request.Method) {
+			continue
+		}
+		if matchPath(url.path, c.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// allow checks the request key by the store.
+func (p RateLimitPolicy) allow(ctx context.Context, store rateLimitStore, key string, now time.Time) (RateLimitResult, error) {
+	var result = RateLimitResult{Limit: p.Limit}
+	window := float64(p.Window.Milliseconds())
+	if p.Algorithm == RateLimitTokenBucket {
+		rate := float64(p.Limit) / window // tokens per millisecond.
+		allowed, tokens, err := store.tokenBucket(ctx, key, p.Limit, rate, p.Window, now)
+		if err != nil {
+			return result, err
+		}
+		result.Allowed = allowed
+		result.Remaining = int(math.Floor(tokens))
+		result.Reset = msDuration((float64(p.Limit) - tokens) / rate)
+		if !allowed {
+			result.RetryAfter = msDuration((1 - tokens) / rate)
+		}
+		return result, nil
+	}
+	start := now.UnixNano() / int64(time.Millisecond)
+	start -= start % p.Window.Milliseconds()
+	elapsed := float64(now.UnixNano()/int64(time.Millisecond) - start)
+	allowed, prev, curr, err := store.slidingWindow(ctx, key, p.Limit, p.Window, start, elapsed)
+	if err != nil {
+		return result, err
+	}
+	limit := float64(p.Limit)
+	count := float64(prev)*(window-elapsed)/window + float64(curr)
+	result.Allowed = allowed
+	result.Remaining = int(math.Max(0, limit-math.Ceil(count)))
+	result.Reset = msDuration(2*window - elapsed)
+	if !allowed {
+		if float64(curr)+1 > limit {
+			// waits the current window as the previous, and it's weight decreased enough.
+			result.RetryAfter = msDuration(window - elapsed + window*(1-(limit-1)/float64(curr)))
+		} else {
+			result.RetryAfter = msDuration(window - elapsed - (limit-1-float64(curr))*window/float64(prev))
+		}
+	}
+	return result, nil
+}
+
+func msDuration(ms float64) time.Duration {
+	if ms < 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// rateLimitStore represents the state store of rate limiters.
+type rateLimitStore interface {
+	tokenBucket(ctx context.Context, key string, capacity int, rate float64, window time.Duration, now time.Time) (allowed bool, tokens float64, err error)
+	slidingWindow(ctx context.Context, key string, limit int, window time.Duration, start int64, elapsed float64) (allowed bool, prev, curr int64, err error)
+}
+
+// rateLimit checks the request by the policy, sets the RateLimit-* headers and the Retry-After header if it's rejected.
+// The in-process store are used if the cache store are not available.
+func rateLimit(s *HostServer, c *gin.Context, policy RateLimitPolicy) bool {
+	storeName := policy.Store
+	if storeName == "" {
+		storeName = s.Config().Settings.RateLimit.Store
+	}
+	var key = policy.keyOf(c)
+	var now = time.Now()
+	result, err := policy.allow(c.Request.Context(), s.rateLimitStores.get(s, storeName), key, now)
+	if err != nil {
+		logger.Warn("rate limit policy: %s, store: %s fail, uses in-process store, err: %v", policy.Name, storeName, err)
+		result, _ = policy.allow(c.Request.Context(), s.rateLimitStores.memory, key, now)
+	}
+	header := c.Writer.Header()
+	remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining"))
+	if err != nil || result.Remaining <= remaining {
+		// responds the most restrictive policy if the request matches multiple policies.
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+	}
+	if !result.Allowed {
+		retryAfter := ceilSeconds(result.RetryAfter)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		header.Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	return result.Allowed
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// GW framework global rate limit Middleware, the policies are defined by Settings.RateLimit.Policies.
+// The policies are compiled when the ApplicationConfig has been loaded(or reloaded).
+func gwRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := getHostServer(c)
+		policies, _ := s.rateLimitPolicies.Load().([]RateLimitPolicy)
+		for _, p := range policies {
+			if p.match(c) && !rateLimit(s, c, p) {
+				respErr(c, getRequestId(s, c), http.StatusTooManyRequests, ErrTooManyRequests, nil)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// rateLimitStores represents the rate limit stores of the server, the cache stores are got by IStore.GetCacheStoreByName.
+type rateLimitStores struct {
+	memory *memoryRateLimitStore
+	caches *cacheStoreResolver
+}
+
+func newRateLimitStores() *rateLimitStores {
+	return &rateLimitStores{
+		memory: newMemoryRateLimitStore(),
+		caches: newCacheStoreResolver(),
+	}
+}
+
+// get returns the cache store of name, the in-process store are used if name is empty(or the cache store not available).
+func (rs *rateLimitStores) get(s *HostServer, name string) rateLimitStore {
+	if name == "" {
+		return rs.memory
+	}
+	if client, ok := rs.caches.resolve(s, name, "rate limit"); ok {
+		return redisRateLimitStore{client: client}
+	}
+	return rs.memory
+}
+
+// memoryRateLimitStore represents a in-process rate limit store.
+type memoryRateLimitStore struct {
+	locker    sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+type rateLimitEntry struct {
+	tokens   float64
+	last     time.Time
+	start    int64
+	prev     int64
+	curr     int64
+	expireAt time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		entries:   make(map[string]*rateLimitEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// entry returns the entry of key, the expired entries are removed every rateLimitSweepInterval.
+func (ms *memoryRateLimitStore) entry(key string, now time.Time, ttl time.Duration) (*rateLimitEntry, bool) {
+	if now.Sub(ms.lastSweep) > rateLimitSweepInterval {
+		for k, e := range ms.entries {
+			if now.After(e.expireAt) {
+				delete(ms.entries, k)
+			}
+		}
+		ms.lastSweep = now
+	}
+	e, ok := ms.entries[key]
+	if !ok || now.After(e.expireAt) {
+		e = &rateLimitEntry{}
+		ms.entries[key] = e
+		ok = false
+	}
+	e.expireAt = now.Add(ttl)
+	return e, ok
+}
+
+func (ms *memoryRateLimitStore) tokenBucket(ctx context.Context, key string, capacity int,
+	rate float64, window time.Duration, now time.Time) (bool, float64, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	e, ok := ms.entry(key, now, window)
+	if !ok {
+		e.tokens, e.last = float64(capacity), now
+	}
+	e.tokens = math.Min(float64(capacity), e.tokens+float64(now.Sub(e.last).Milliseconds())*rate)
+	e.last = now
+	if e.tokens < 1 {
+		return false, e.tokens, nil
+	}
+	e.tokens--
+	return true, e.tokens, nil
+}
+
+func (ms *memoryRateLimitStore) slidingWindow(ctx context.Context, key string, limit int,
+	window time.Duration, start int64, elapsed float64) (bool, int64, int64, error) {
+	ms.locker.Lock()
+	defer ms.locker.Unlock()
+	now := time.Unix(0, start*int64(time.Millisecond)).Add(msDuration(elapsed))
+	e, _ := ms.entry(key, now, 2*window)
+	if e.start != start {
+		if e.start == start-window.Milliseconds() {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.start, e.curr = start, 0
+	}
+	w := float64(window.Milliseconds())
+	if float64(e.prev)*(w-elapsed)/w+float64(e.curr)+1 > float64(limit) {
+		return false, e.prev, e.curr, nil
+	}
+	e.curr++
+	return true, e.prev, e.curr, nil
+}
+
+// redisRateLimitStore represents a Redis rate limit store, the states are updated by Lua scripts atomically.
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+var redisTokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+var redisSlidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * (window - elapsed) / window + curr + 1 > limit then
+	return {0, prev, curr}
+end
+curr = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, prev, curr}
+`)
+
+func (rs redisRateLimitStore) tokenBucket(ctx context.Context, key string, capacity int,
+	rate float64, window time.Duration, now time.Time) (bool, float64, error) {
+	ret, err := redisTokenBucketScript.Run(ctx, rs.client, []string{key}, capacity,
+		strconv.FormatFloat(rate, 'f', -1, 64), now.UnixNano()/int64(time.Millisecond), window.Milliseconds()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := ret.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("invalid token bucket script result: %v", ret)
+	}
+	tokens, err := strconv.ParseFloat(fmt.Sprintf("%v", values[1]), 64)
+	return values[0] == int64(1), tokens, err
+}
+
+func (rs redisRateLimitStore) slidingWindow(ctx context.Context, key string, limit int,
+	window time.Duration, start int64, elapsed float64) (bool, int64, int64, error) {
+	keys := []string{
+		fmt.Sprintf("%s:%d", key, start),
+		fmt.Sprintf("%s:%d", key, start-window.Milliseconds()),
+	}
+	ret, err := redisSlidingWindowScript.Run(ctx, rs.client, keys, limit, window.Milliseconds(), int64(elapsed)).Result()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	values, ok := ret.([]interface{})
+	if !ok || len(values) != 3 {
+		return false, 0, 0, fmt.Errorf("invalid sliding window script result: %v", ret)
+	}
+	prev, _ := values[1].(int64)
+	curr, _ := values[2].(int64)
+	return values[0] == int64(1), prev, curr, nil
+}
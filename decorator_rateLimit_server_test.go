@@ -0,0 +1,72 @@
+package gw_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func rateLimitHello(c *gw.Context) {
+	c.JSON200("hello")
+}
+
+func rateLimitLimited(c *gw.Context) {
+	c.JSON200("ok")
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Settings.RateLimit.Policies = []conf.RateLimitPolicy{{
+				Name:   "gw-login",
+				Limit:  2,
+				Window: 60000,
+				Key:    gw.RateLimitKeyIP,
+				Urls:   []string{"POST:" + cnf.Security.AuthServer.LogIn.Url},
+			}}
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("hello", rateLimitHello)
+			router.GET("limited", rateLimitLimited, gw.NewRateLimitDecorator(gw.RateLimitPolicy{
+				Algorithm: gw.RateLimitTokenBucket,
+				Limit:     2,
+				Window:    time.Minute,
+				Key:       gw.RateLimitKeyUser,
+				Store:     "primary",
+			}))
+		},
+	})
+
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	resp := client.Call("rateLimitHello", nil, nil).AssertOK()
+	assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+	resp = client.Call("rateLimitLimited", nil, nil).AssertOK()
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", resp.Header.Get("RateLimit-Policy"))
+	client.Call("rateLimitLimited", nil, nil).AssertOK()
+	resp = client.Call("rateLimitLimited", nil, nil).AssertError(http.StatusTooManyRequests, gw.ErrTooManyRequests.Error())
+	assert.Equal(t, gw.ErrCodeTooManyRequests, resp.Envelope().Code)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+	// per user keys.
+	server.Client().LoginAs(gw.User{ID: 2, TenantId: 10, Passport: "other"}).Call("rateLimitLimited", nil, nil).AssertOK()
+
+	// the global policy protects the login API.
+	login := server.Config().Security.AuthServer.LogIn.Url
+	for i := 0; i < 2; i++ {
+		resp = server.Client().Post(login, gin.H{"passport": "gw", "secret": "bad"})
+		assert.NotEqual(t, http.StatusTooManyRequests, resp.StatusCode)
+	}
+	resp = server.Client().Post(login, gin.H{"passport": "gw", "secret": "bad"}).AssertStatus(http.StatusTooManyRequests)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	assert.NotEqual(t, http.StatusTooManyRequests, server.Client().Get(login).StatusCode)
+}
@@ -0,0 +1,98 @@
+package gw
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/oceanho/gw/conf"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testRateLimitStores(t *testing.T) map[string]rateLimitStore {
+	mr, err := miniredis.Run()
+	assert.Nil(t, err)
+	t.Cleanup(mr.Close)
+	return map[string]rateLimitStore{
+		"memory": newMemoryRateLimitStore(),
+		"redis":  redisRateLimitStore{client: redis.NewClient(&redis.Options{Addr: mr.Addr()})},
+	}
+}
+
+func TestRateLimitPolicy_TokenBucket(t *testing.T) {
+	policy, err := RateLimitPolicy{Algorithm: RateLimitTokenBucket, Limit: 2, Window: 2 * time.Second}.normalize()
+	assert.Nil(t, err)
+	now := time.Unix(1600000000, 0)
+	for name, store := range testRateLimitStores(t) {
+		ctx := context.Background()
+		r, err := policy.allow(ctx, store, "k", now)
+		assert.Nil(t, err, name)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, r, name)
+		r, _ = policy.allow(ctx, store, "k", now)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, r, name)
+		r, _ = policy.allow(ctx, store, "k", now.Add(500*time.Millisecond))
+		assert.False(t, r.Allowed, name)
+		assert.Equal(t, 500*time.Millisecond, r.RetryAfter, name)
+		r, _ = policy.allow(ctx, store, "k", now.Add(time.Second))
+		assert.True(t, r.Allowed, name)
+		r, _ = policy.allow(ctx, store, "other", now.Add(time.Second))
+		assert.Equal(t, 1, r.Remaining, name)
+	}
+}
+
+func TestRateLimitPolicy_SlidingWindow(t *testing.T) {
+	policy, err := RateLimitPolicy{Limit: 2, Window: 10 * time.Second}.normalize()
+	assert.Nil(t, err)
+	assert.Equal(t, RateLimitSlidingWindow, policy.Algorithm)
+	assert.Equal(t, RateLimitKeyIP, policy.Key)
+	now := time.Unix(1600000000, 0)
+	for name, store := range testRateLimitStores(t) {
+		ctx := context.Background()
+		r, err := policy.allow(ctx, store, "k", now)
+		assert.Nil(t, err, name)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: 20 * time.Second}, r, name)
+		r, _ = policy.allow(ctx, store, "k", now.Add(time.Second))
+		assert.True(t, r.Allowed, name)
+		r, _ = policy.allow(ctx, store, "k", now.Add(2*time.Second))
+		assert.False(t, r.Allowed, name)
+		// the next window: 2 * (10-5)/10 + 0 = 1, allowed one request.
+		r, _ = policy.allow(ctx, store, "k", now.Add(15*time.Second))
+		assert.True(t, r.Allowed, name)
+		r, _ = policy.allow(ctx, store, "k", now.Add(15*time.Second))
+		assert.False(t, r.Allowed, name)
+		assert.Equal(t, 5*time.Second, r.RetryAfter, name)
+	}
+}
+
+func TestRateLimitPolicy_normalize(t *testing.T) {
+	for _, p := range []RateLimitPolicy{
+		{Limit: 0, Window: time.Second},
+		{Limit: 1, Window: time.Second, Algorithm: "fixed"},
+		{Limit: 1, Window: time.Second, Key: "ip,session"},
+	} {
+		_, err := p.normalize()
+		assert.NotNil(t, err)
+	}
+	assert.Panics(t, func() { NewRateLimitDecorator(RateLimitPolicy{Limit: 1, Window: time.Second, Key: "ip,foo"}) })
+	p, err := RateLimitPolicy{Limit: 1, Window: time.Second, Key: "tenant,route"}.normalize()
+	assert.Nil(t, err)
+	assert.Equal(t, "slidingWindow-1-1000-tenant,route", p.Name)
+}
+
+func TestCompileRateLimitPolicies(t *testing.T) {
+	var cnf conf.ApplicationConfig
+	cnf.Settings.RateLimit.Policies = []conf.RateLimitPolicy{{Name: "api", Limit: 1, Window: 1000, Urls: []string{"POST:/api/*"}}}
+	policies, err := compileRateLimitPolicies(&cnf)
+	assert.Nil(t, err)
+	assert.Equal(t, []rateLimitUrl{{method: "POST", path: "/api/*"}}, policies[0].urls)
+
+	cnf.Settings.RateLimit.Policies[0].Urls = []string{"/api/*"}
+	_, err = compileRateLimitPolicies(&cnf)
+	assert.NotNil(t, err)
+
+	cnf.Settings.RateLimit.Policies[0].Urls = nil
+	cnf.Settings.RateLimit.Policies[0].Key = "ip,foo"
+	_, err = compileRateLimitPolicies(&cnf)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,280 @@
+package gw
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	responseCacheDecoratorCatalog   = "gw_framework_response_cache"
+	responseCacheKeyPrefix          = "gw-cache"
+	responseCacheTagKeyPrefix       = "gw-cache-tag"
+	responseCacheStateKey           = "gw-response-cache"
+	responseCacheLockPollInterval   = 20 * time.Millisecond
+	defaultResponseCacheTTL         = 60 * time.Second
+	defaultResponseCacheLockTimeout = 5 * time.Second
+	ResponseCacheHeader             = "X-Cache"
+)
+
+// responseCachePolicy represents the MetaData of a response cache Decorator.
+type responseCachePolicy struct {
+	ttl    time.Duration
+	models []reflect.Type
+	tags   []string
+}
+
+// responseCacheEntry represents a cached response, Tags are the tag versions when the response are generated.
+type responseCacheEntry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Tags   map[string]int64
+}
+
+type responseCacheState struct {
+	store lockedStore
+	key   string
+	entry *responseCacheEntry
+	ttl   time.Duration
+}
+
+// NewResponseCacheDecorator returns a cache-aside Decorator of the GET routers.
+//
+// The responses(200 only) are stored in the cache store(Settings.ResponseCache.Store), they are keyed by the url path,
+// the normalized query, the Accept/Accept-Language headers, the tenant and the user.
+// ttl is the expiration of the cached responses, 0 means Settings.ResponseCache.TTL.
+//
+// models are the gorm models that the response depends on, the cached responses are tagged by the model types,
+// and evicted automatically by the DbOpProcessor CreateAfter/UpdateAfter/DeleteAfter hooks of the models.
+// The concurrent misses of a key are serialised by a lock, only the lock owner queries the backends.
+func NewResponseCacheDecorator(ttl time.Duration, models ...interface{}) Decorator {
+	policy := responseCachePolicy{ttl: ttl}
+	for _, m := range models {
+		typer := reflect.TypeOf(m)
+		for typer.Kind() == reflect.Ptr {
+			typer = typer.Elem()
+		}
+		policy.models = append(policy.models, typer)
+		policy.tags = append(policy.tags, responseCacheTagOf(typer))
+	}
+	return Decorator{
+		Catalog:  responseCacheDecoratorCatalog,
+		MetaData: policy,
+		Before: func(c *Context) (status int, err error, payload interface{}) {
+			return responseCacheBefore(c, policy)
+		},
+		After: responseCacheAfter,
+	}
+}
+
+func responseCacheTagOf(typer reflect.Type) string {
+	return typer.String()
+}
+
+// responseCacheKeyOf returns the cache key of the request, the query params are sorted.
+func responseCacheKeyOf(c *Context) string {
+	query := c.Request.URL.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	hash := sha256.New()
+	hash.Write([]byte(strings.Join([]string{
+		c.Request.URL.Path,
+		query.Encode(),
+		c.GetHeader("Accept"),
+		c.Locale(),
+	}, "\n")))
+	user := c.User()
+	return fmt.Sprintf("%s:%d:%d:%s", responseCacheKeyPrefix, user.TenantId, user.ID, hex.EncodeToString(hash.Sum(nil)))
+}
+
+func responseCacheBefore(c *Context, policy responseCachePolicy) (int, error, interface{}) {
+	if c.Request.Method != http.MethodGet {
+		return 0, nil, nil
+	}
+	s := c.server
+	cnf := s.Config().Settings.ResponseCache
+	ttl := policy.ttl
+	if ttl <= 0 {
+		ttl = durationOrDefault(cnf.TTL, defaultResponseCacheTTL)
+	}
+	lockTimeout := durationOrDefault(cnf.LockTimeout, defaultResponseCacheLockTimeout)
+	store := s.lockedStores.get(s, cnf.Store, "response cache")
+	key := responseCacheKeyOf(c)
+	// the tag versions are got before the handler called, so the responses of the concurrent writes are stale at once.
+	versions, err := responseCacheVersions(c, store, policy.tags)
+	if err != nil {
+		logger.Error("get response cache tag versions fail, err: %v", err)
+		return 0, nil, nil
+	}
+	var entry responseCacheEntry
+	loaded, err := loadOrLock(c, store, key, lockTimeout, responseCacheLockPollInterval, func(value []byte) (bool, error) {
+		entry = responseCacheEntry{}
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return false, err
+		}
+		return entry.fresh(versions), nil
+	})
+	if err != nil {
+		// the handler are called(without caching) if the lock owner are not finished in time.
+		if err != errLockWaitTimeout {
+			logger.Error("get response cache: %s fail, err: %v", key, err)
+		}
+		return 0, nil, nil
+	}
+	if loaded {
+		replayResponseCacheEntry(c.Context, &entry)
+		return entry.Status, nil, nil
+	}
+	c.Header(ResponseCacheHeader, "MISS")
+	c.Set(responseCacheStateKey, &responseCacheState{
+		store: store,
+		key:   key,
+		entry: &responseCacheEntry{Tags: versions},
+		ttl:   ttl,
+	})
+	return 0, nil, nil
+}
+
+func responseCacheAfter(c *Context) (int, error, interface{}) {
+	obj, ok := c.Get(responseCacheStateKey)
+	if !ok {
+		return 0, nil, nil
+	}
+	state := obj.(*responseCacheState)
+	buf := c.ResponseBuffer()
+	if buf == nil || buf.Status() != http.StatusOK || buf.Header().Get("Set-Cookie") != "" {
+		return 0, nil, nil
+	}
+	entry := state.entry
+	entry.Status = buf.Status()
+	// the body are rendered first, the Content-Type header are set by rendering.
+	entry.Body = buf.Bytes()
+	entry.Header = buf.changedHeader()
+	entry.Header.Del("Date")
+	entry.Header.Del(ResponseCacheHeader)
+	b, err := json.Marshal(entry)
+	if err == nil {
+		err = state.store.save(context.Background(), state.key, b, state.ttl)
+	}
+	if err != nil {
+		logger.Error("save response cache: %s fail, err: %v", state.key, err)
+	}
+	return 0, nil, nil
+}
+
+// fresh returns true if the tags of the entry are not evicted.
+func (e *responseCacheEntry) fresh(versions map[string]int64) bool {
+	for tag, v := range versions {
+		if e.Tags[tag] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// replayResponseCacheEntry response the cached entry, it's response 304 if the If-None-Match/If-Modified-Since matched.
+func replayResponseCacheEntry(c *gin.Context, entry *responseCacheEntry) {
+	header := c.Writer.Header()
+	replayHeader(header, entry.Header)
+	header.Set(ResponseCacheHeader, "HIT")
+	v := Validator{ETag: entry.Header.Get("ETag")}
+	if t, ok := parseHttpTime(entry.Header.Get("Last-Modified")); ok {
+		v.LastModified = &t
+	}
+	if (v.ETag != "" || v.LastModified != nil) && evaluatePreconditions(c, v) == http.StatusNotModified {
+		c.Writer.WriteHeader(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Writer.WriteHeader(entry.Status)
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(entry.Body)
+}
+
+// registerResponseCacheInvalidators registers the DbOpProcessor hooks that evict the cached responses of the models,
+// the models are collected from the response cache decorators of the routers.
+func registerResponseCacheInvalidators(s *HostServer) {
+	if s.router == nil {
+		return
+	}
+	var registered = make(map[reflect.Type]bool)
+	for _, r := range s.router.routerInfos {
+		for _, d := range FilterDecorator(func(d Decorator) bool {
+			return d.Catalog == responseCacheDecoratorCatalog
+		}, r.Decorators...) {
+			for _, typer := range d.MetaData.(responseCachePolicy).models {
+				if registered[typer] {
+					continue
+				}
+				registered[typer] = true
+				tag := responseCacheTagOf(typer)
+				handler := func(db *gorm.DB, ctx *Context, model interface{}) error {
+					if db.Error != nil {
+						return nil
+					}
+					return invalidateResponseCache(ctx.server, tag)
+				}
+				model := reflect.New(typer).Interface()
+				s.DbOpProcessor.CreateAfter().Register(handler, model)
+				s.DbOpProcessor.UpdateAfter().Register(handler, model)
+				s.DbOpProcessor.DeleteAfter().Register(handler, model)
+			}
+		}
+	}
+}
+
+func invalidateResponseCache(s *HostServer, tags ...string) error {
+	store := s.lockedStores.get(s, s.Config().Settings.ResponseCache.Store, "response cache")
+	if err := store.incr(context.Background(), responseCacheTagKeysOf(tags)); err != nil {
+		logger.Error("evict response cache tags: %s fail, err: %v", strings.Join(tags, ","), err)
+		return err
+	}
+	return nil
+}
+
+// InvalidateResponseCache evicts the cached responses that tagged by the models,
+// It's used for the writes that not trigger the DbOpProcessor hooks(such as raw SQL).
+func (c *Context) InvalidateResponseCache(models ...interface{}) error {
+	var tags = make([]string, 0, len(models))
+	for _, m := range models {
+		typer := reflect.TypeOf(m)
+		for typer.Kind() == reflect.Ptr {
+			typer = typer.Elem()
+		}
+		tags = append(tags, responseCacheTagOf(typer))
+	}
+	return invalidateResponseCache(c.server, tags...)
+}
+
+func responseCacheTagKeysOf(tags []string) []string {
+	var keys = make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = fmt.Sprintf("%s:%s", responseCacheTagKeyPrefix, tag)
+	}
+	return keys
+}
+
+// responseCacheVersions returns the versions of the tags, the version of a tag are increased on evicted.
+func responseCacheVersions(ctx context.Context, store lockedStore, tags []string) (map[string]int64, error) {
+	values, err := store.counters(ctx, responseCacheTagKeysOf(tags))
+	if err != nil {
+		return nil, err
+	}
+	var versions = make(map[string]int64, len(tags))
+	for i, tag := range tags {
+		versions[tag] = values[i]
+	}
+	return versions, nil
+}
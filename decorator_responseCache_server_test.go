@@ -0,0 +1,142 @@
+package gw_test
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type responseCacheUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+}
+
+var responseCacheListCalls int64
+
+func responseCacheListUsers(c *gw.Context) {
+	atomic.AddInt64(&responseCacheListCalls, 1)
+	if c.Query("slow") != "" {
+		time.Sleep(50 * time.Millisecond)
+	}
+	var users []responseCacheUser
+	if err := c.Store().GetDbStore().Where("tenant_id = ?", c.User().TenantId).Order("id").Find(&users).Error; err != nil {
+		c.JSON500Msg(0, err)
+		return
+	}
+	c.JSON200(users)
+}
+
+func responseCacheCreateUser(c *gw.Context) {
+	var in struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if c.Bind(&in) != nil {
+		return
+	}
+	user := responseCacheUser{TenantId: c.User().TenantId, Name: in.Name}
+	if err := c.Store().GetDbStore().Create(&user).Error; err != nil {
+		c.JSON500Msg(0, err)
+		return
+	}
+	c.JSON200(user)
+}
+
+func TestServer_ResponseCache(t *testing.T) {
+	for _, store := range []string{"", "primary"} {
+		server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+			opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+				cnf := gwtest.DefaultConfig()
+				cnf.Settings.ResponseCache.Store = store
+				return cnf
+			}
+		}, &gwtest.App{
+			RegisterFunc: func(router *gw.RouterGroup) {
+				router.GET("users", responseCacheListUsers, gw.NewResponseCacheDecorator(time.Minute, responseCacheUser{}))
+				router.POST("users", responseCacheCreateUser)
+			},
+			MigrateFunc: func(state *gw.ServerState) {
+				db := state.Store().GetDbStore()
+				_ = db.AutoMigrate(&responseCacheUser{})
+				db.Create(&responseCacheUser{ID: 1, TenantId: 10, Name: "gw"})
+			},
+		})
+		client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+		calls := atomic.LoadInt64(&responseCacheListCalls)
+
+		var users []responseCacheUser
+		resp := client.Call("responseCacheListUsers", gwtest.Params{"b": "2", "a": "1"}, nil).AssertOK().DecodePayload(&users)
+		assert.Equal(t, "MISS", resp.Header.Get(gw.ResponseCacheHeader), store)
+		assert.Len(t, users, 1)
+		resp = client.Call("responseCacheListUsers", gwtest.Params{"a": "1", "b": "2"}, nil).AssertOK()
+		assert.Equal(t, "HIT", resp.Header.Get(gw.ResponseCacheHeader), store)
+		assert.Equal(t, calls+1, atomic.LoadInt64(&responseCacheListCalls), store)
+
+		// the other users and queries are not shared.
+		server.Client().LoginAs(gw.User{ID: 2, TenantId: 10, Passport: "other"}).
+			Call("responseCacheListUsers", gwtest.Params{"a": "1", "b": "2"}, nil).AssertOK()
+		client.Call("responseCacheListUsers", gwtest.Params{"a": "2"}, nil).AssertOK()
+		assert.Equal(t, calls+3, atomic.LoadInt64(&responseCacheListCalls), store)
+
+		// the writes of the model evict the cached responses.
+		client.Call("responseCacheCreateUser", nil, map[string]string{"name": "new"}).AssertOK()
+		resp = client.Call("responseCacheListUsers", gwtest.Params{"a": "1", "b": "2"}, nil).AssertOK().DecodePayload(&users)
+		assert.Equal(t, "MISS", resp.Header.Get(gw.ResponseCacheHeader), store)
+		assert.Len(t, users, 2)
+		client.Call("responseCacheListUsers", gwtest.Params{"a": "1", "b": "2"}, nil).AssertOK()
+		assert.Equal(t, calls+4, atomic.LoadInt64(&responseCacheListCalls), store)
+
+		// the concurrent misses are serialised.
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.Call("responseCacheListUsers", gwtest.Params{"slow": "1"}, nil).AssertOK()
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, calls+5, atomic.LoadInt64(&responseCacheListCalls), store)
+	}
+}
+
+func responseCacheHello(c *gw.Context) {
+	c.Header("X-Hello", "gw")
+	c.JSON200("hello")
+}
+
+func TestServer_ResponseCache_Headers(t *testing.T) {
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Security.Cors.Enabled = true
+			cnf.Security.Cors.Policies = []conf.CorsPolicy{{AllowOrigins: []string{"https://*.gw-framework.com"}}}
+			cnf.Settings.Tracing.Enabled = true
+			cnf.Settings.Tracing.SampleRatio = 1
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("hello", responseCacheHello, gw.NewResponseCacheDecorator(time.Minute))
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	miss := client.WithHeader("Origin", "https://a.gw-framework.com").Call("responseCacheHello", nil, nil).AssertOK()
+	assert.Equal(t, "MISS", miss.Header.Get(gw.ResponseCacheHeader))
+	hit := client.WithHeader("Origin", "https://b.gw-framework.com").Call("responseCacheHello", nil, nil).AssertOK()
+	assert.Equal(t, "HIT", hit.Header.Get(gw.ResponseCacheHeader))
+
+	// the headers of the handler are replayed.
+	assert.Equal(t, "gw", hit.Header.Get("X-Hello"))
+	assert.Equal(t, miss.Header.Get("Content-Type"), hit.Header.Get("Content-Type"))
+	// the per-request headers are not.
+	assert.Equal(t, "https://b.gw-framework.com", hit.Header.Get("Access-Control-Allow-Origin"))
+	assert.Len(t, hit.Header.Get("X-Trace-Id"), 32)
+	assert.NotEqual(t, miss.Header.Get("X-Trace-Id"), hit.Header.Get("X-Trace-Id"))
+	assert.Equal(t, miss.Header.Values("Vary"), hit.Header.Values("Vary"))
+}
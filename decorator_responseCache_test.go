@@ -0,0 +1,48 @@
+package gw
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type responseCacheModel struct {
+	ID uint64
+}
+
+func TestResponseCacheVersions(t *testing.T) {
+	store := newMemoryLockedStore()
+	ctx := context.Background()
+	tags := []string{"a", "b"}
+	versions, err := responseCacheVersions(ctx, store, tags)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"a": 0, "b": 0}, versions)
+	entry := &responseCacheEntry{Status: 200, Body: []byte("ok"), Tags: versions}
+	assert.True(t, entry.fresh(versions))
+
+	assert.Nil(t, store.incr(ctx, responseCacheTagKeysOf([]string{"b"})))
+	versions, _ = responseCacheVersions(ctx, store, tags)
+	assert.Equal(t, map[string]int64{"a": 0, "b": 1}, versions)
+	assert.False(t, entry.fresh(versions))
+	assert.True(t, entry.fresh(map[string]int64{"a": 0}))
+}
+
+func TestNewResponseCacheDecorator(t *testing.T) {
+	d := NewResponseCacheDecorator(time.Minute, &responseCacheModel{})
+	policy := d.MetaData.(responseCachePolicy)
+	assert.Equal(t, time.Minute, policy.ttl)
+	assert.Equal(t, []reflect.Type{reflect.TypeOf(responseCacheModel{})}, policy.models)
+	assert.Equal(t, []string{"gw.responseCacheModel"}, policy.tags)
+}
+
+func TestDbOpTyperHandlers_Register(t *testing.T) {
+	processor := NewDbOpProcessor()
+	handler := func(db *gorm.DB, ctx *Context, model interface{}) error {
+		return nil
+	}
+	processor.UpdateAfter().Register(handler, responseCacheModel{}).Register(handler, &responseCacheModel{})
+	assert.Len(t, processor.UpdateAfter().handlers[reflect.TypeOf(responseCacheModel{})], 2)
+}
@@ -0,0 +1,89 @@
+package gw
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+const timeoutDecoratorCatalog = "gw_framework_timeout"
+
+var (
+	ErrRequestTimeout  = NewError(http.StatusGatewayTimeout, ErrCodeRequestTimeout, "request timeout")
+	ErrRequestCanceled = NewError(http.StatusServiceUnavailable, ErrCodeRequestCanceled, "request canceled")
+	errDefault503Msg   = "Service Unavailable"
+	errDefault504Msg   = "Gateway Timeout"
+)
+
+// NewTimeoutDecorator returns a Decorator that overrides the http timeout(Settings.TimeoutControl.HTTP) of the router.
+// timeout <= 0 means the router has no timeout.
+func NewTimeoutDecorator(timeout time.Duration) Decorator {
+	if timeout <= 0 {
+		timeout = -1
+	}
+	return Decorator{
+		Catalog:  timeoutDecoratorCatalog,
+		MetaData: timeout,
+	}
+}
+
+func routerTimeout(decorators ...Decorator) time.Duration {
+	var timeout time.Duration
+	for _, d := range decorators {
+		if d.Catalog != timeoutDecoratorCatalog {
+			continue
+		}
+		if t, ok := d.MetaData.(time.Duration); ok {
+			timeout = t
+		}
+	}
+	return timeout
+}
+
+// newRequestContext returns the request context with the router timeout(or server-wide timeout),
+// and replace the http.Request's context, so the deadline can be reached by the http.Request.Context() too.
+func newRequestContext(s *HostServer, c *Context, timeout time.Duration) context.CancelFunc {
+	if timeout == 0 {
+		timeout = time.Duration(s.Config().Settings.TimeoutControl.HTTP) * time.Millisecond
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(c.Request.Context(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(c.Request.Context())
+	}
+	c.ctx = ctx
+	c.Request = c.Request.WithContext(ctx)
+	return cancel
+}
+
+// storeDbContextSetup make the db operations of the request are bound to the request context.
+func storeDbContextSetup(c *Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c)
+}
+
+// storeCacheContextSetup make the redis client's Context() returns the request context.
+func storeCacheContextSetup(c *Context, client *redis.Client, user User) *redis.Client {
+	return client.WithContext(c)
+}
+
+// abortIfDone returns true if the request context are done,
+// a 504(deadline exceeded) or 503(canceled) response will be sent if nothing has been written.
+func abortIfDone(s *HostServer, c *Context) bool {
+	err := c.ctx.Err()
+	if err == nil {
+		return false
+	}
+	if !c.Writer.Written() {
+		errMsg, msg := ErrRequestCanceled, errDefault503Msg
+		if err == context.DeadlineExceeded {
+			errMsg, msg = ErrRequestTimeout, errDefault504Msg
+		}
+		c.Abort()
+		respErr(c.Context, c.requestId, 0, errMsg, c.T(msg))
+	}
+	return true
+}
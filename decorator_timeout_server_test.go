@@ -0,0 +1,38 @@
+package gw_test
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type timeoutUser struct {
+	ID   uint64
+	Name string
+}
+
+var timeoutDbErr = make(chan error, 1)
+
+func timeoutSlow(c *gw.Context) {
+	<-c.Done()
+	var count int64
+	timeoutDbErr <- c.Store().GetDbStore().Model(&timeoutUser{}).Count(&count).Error
+}
+
+func TestServer_Timeout(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("slow", timeoutSlow, gw.NewTimeoutDecorator(20*time.Millisecond))
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			_ = state.Store().GetDbStore().AutoMigrate(&timeoutUser{})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	client.Call("timeoutSlow", nil, nil).AssertError(http.StatusGatewayTimeout, gw.ErrRequestTimeout.Error())
+	// db operations are bound to the request context.
+	assert.NotNil(t, <-timeoutDbErr)
+}
@@ -235,6 +235,17 @@ func (d *DefaultDIProviderImpl) RegisterWithName(name string, actual interface{}
 	return true
 }
 
+// IsRegistered returns true if the typer has been registered.
+func (d *DefaultDIProviderImpl) IsRegistered(typer reflect.Type) bool {
+	d.locker.Lock()
+	defer d.locker.Unlock()
+	if _, ok := d.typerMappers[typer]; ok {
+		return true
+	}
+	_, ok := d.objectTypers[gwreflect.GetPkgFullName(typer)]
+	return ok
+}
+
 func (d *DefaultDIProviderImpl) Resolve(typerName string) interface{} {
 	return d.ResolveWithState(d.state.Store(), typerName)
 }
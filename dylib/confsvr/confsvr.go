@@ -1,11 +1,13 @@
 package main
 
 import (
+	"github.com/oceanho/gw"
 	"github.com/oceanho/gw/contrib/apps/confsvr"
 )
 
-var AppPlugin confsvr.App
-
-func init() {
-	AppPlugin = confsvr.New()
+var AppPlugin = gw.PluginManifest{
+	GwVersion: gw.Version,
+	New: func() gw.App {
+		return confsvr.New()
+	},
 }
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"github.com/oceanho/gw"
 	"github.com/oceanho/gw/contrib/apps/stor"
 )
 
-var AppPlugin stor.App
-
-func init() {
-	AppPlugin = stor.New()
+var AppPlugin = gw.PluginManifest{
+	GwVersion: gw.Version,
+	New: func() gw.App {
+		return stor.New()
+	},
 }
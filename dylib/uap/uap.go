@@ -1,11 +1,13 @@
 package main
 
 import (
+	"github.com/oceanho/gw"
 	"github.com/oceanho/gw/contrib/apps/uap"
 )
 
-var AppPlugin uap.App
-
-func init() {
-	AppPlugin = uap.New()
+var AppPlugin = gw.PluginManifest{
+	GwVersion: gw.Version,
+	New: func() gw.App {
+		return uap.New()
+	},
 }
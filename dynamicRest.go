@@ -18,6 +18,7 @@ func init() {
 		"Get",
 		"Get",
 		func(relativePath, handlerActionName string, r *RouterGroup, dynamicCaller DynamicCaller) {
+			dynamicCaller.conditional = true
 			r.createRouter("GET", relativePath, func(ctx *Context) {
 				handleDynamicApi(ctx, dynamicCaller)
 			}, handlerActionName, dynamicCaller.decorators...)
@@ -29,6 +30,7 @@ func init() {
 		func(relativePath, handlerActionName string, r *RouterGroup, dynamicCaller DynamicCaller) {
 			relativePath = strings.TrimRight(relativePath, "/")
 			relativePath = fmt.Sprintf("%s/detail/:id", relativePath)
+			dynamicCaller.conditional = true
 			r.createRouter("GET", relativePath, func(ctx *Context) {
 				handleDynamicApi(ctx, dynamicCaller)
 			}, handlerActionName, dynamicCaller.decorators...)
@@ -156,11 +158,13 @@ type DynamicCaller struct {
 	decorators         []Decorator
 	bindingFuncPkgName string
 	argsOrderlyBinder  []restArgsBinder
+	returns            restReturns
+	conditional        bool
 }
 
 type restArgsBinder struct {
 	dataType reflect.Type
-	bindFunc func(p reflect.Type, c *Context) reflect.Value
+	bindFunc func(p reflect.Type, c *Context) (reflect.Value, error)
 }
 
 // RegisterRestAPIs register a collection HTTP routes by gw.IDynamicRestAPI.
@@ -199,17 +203,9 @@ func registerRestAPIImpl(router *RouterGroup, restAPIs ...IDynamicRestAPI) {
 				if ok {
 					apiSpecifyDecorators = val.MethodByName(name).Call(nil)[0].Interface().([]Decorator)
 				}
-				// FIXME(Ocean): how to check the arguments type is *gw.Context.
-				n := 1
 				prefix := fmt.Sprintf("invalid operation, method: %s.%s", restPkgId, m.Name)
-				if m.Type.NumOut() != 0 {
-					panic(fmt.Sprintf("%s, should be not return any values.", prefix))
-				}
-				dynBinders := make([]restArgsBinder, n)
-				dynBinders[0] = restArgsBinder{
-					dataType: reflect.TypeOf(&Context{}),
-					bindFunc: ctxBinder,
-				}
+				handler := val.MethodByName(m.Name)
+				dynBinders, returns, schema := analyzeRestHandler(prefix, handler.Type())
 				var decorators []Decorator
 				// OnXBefore
 				name = fmt.Sprintf("On%sBefore", m.Name)
@@ -245,13 +241,16 @@ func registerRestAPIImpl(router *RouterGroup, restAPIs ...IDynamicRestAPI) {
 						decorators = append(decorators, decorator...)
 					}
 				}
+				decorators = append(decorators, newApiSchemaDecorator(schema))
 				bindingFuncPkgName := fmt.Sprintf("%s.%s", restPkgId, m.Name)
 				dynCaller := DynamicCaller{
-					argInNumber:        n,
+					argInNumber:        len(dynBinders),
+					retOutNumber:       handler.Type().NumOut(),
 					decorators:         decorators,
 					bindingFuncPkgName: bindingFuncPkgName,
-					handler:            val.MethodByName(m.Name),
+					handler:            handler,
 					argsOrderlyBinder:  dynBinders,
+					returns:            returns,
 				}
 				dyApiRegister.register(relativePath, bindingFuncPkgName, router, dynCaller)
 			}
@@ -261,21 +260,26 @@ func registerRestAPIImpl(router *RouterGroup, restAPIs ...IDynamicRestAPI) {
 
 // handleDynamicApi ...
 func handleDynamicApi(c *Context, dynamicCaller DynamicCaller) {
-	dynamicCaller.handler.Call(dynamicCaller.makeArgs(c))
+	args, err := dynamicCaller.makeArgs(c)
+	if err != nil {
+		c.respBindErr(err)
+		return
+	}
+	dynamicCaller.render(c, dynamicCaller.handler.Call(args))
 }
 
-func (d DynamicCaller) makeArgs(ctx *Context) []reflect.Value {
+func (d DynamicCaller) makeArgs(ctx *Context) ([]reflect.Value, error) {
 	if d.argInNumber > 0 {
 		var args = make([]reflect.Value, d.argInNumber)
 		for i := 0; i < d.argInNumber; i++ {
 			binder := d.argsOrderlyBinder[i]
-			args[i] = binder.bindFunc(binder.dataType, ctx)
+			arg, err := binder.bindFunc(binder.dataType, ctx)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = arg
 		}
-		return args
+		return args, nil
 	}
-	return nil
-}
-
-func ctxBinder(typ reflect.Type, ctx *Context) reflect.Value {
-	return reflect.ValueOf(ctx)
+	return nil, nil
 }
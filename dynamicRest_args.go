@@ -0,0 +1,196 @@
+package gw
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"reflect"
+)
+
+var (
+	contextTyper = reflect.TypeOf(&Context{})
+	userTyper    = reflect.TypeOf(User{})
+	storeTyper   = reflect.TypeOf((*IStore)(nil)).Elem()
+	errorTyper   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// diTyperRegistry represents a IDIProvider that can be tells the typer has been registered or not.
+type diTyperRegistry interface {
+	IsRegistered(typer reflect.Type) bool
+}
+
+// restReturns represents the return values layout of a dynamic rest API handler.
+type restReturns struct {
+	valueIdx int // -1 means no value returns.
+	errIdx   int // -1 means no error returns.
+}
+
+// analyzeRestHandler analyze the arguments and return values of a dynamic rest API handler at registration.
+//
+// Supports arguments:
+//
+// *gw.Context, gw.User, gw.IStore, interfaces(resolved by DI), struct or *struct(resolved by DI if registered,
+// otherwise bind from path, query, JSON or form and validated), only one struct argument are allowed.
+//
+// Supports return values: (), (error), (T), (T, error), the error can be are any pointer that implements error(such as *gw.Error).
+//
+// The returned apiSchema describes the request models and the response value for the OpenAPI document.
+func analyzeRestHandler(prefix string, handlerTyper reflect.Type) ([]restArgsBinder, restReturns, *apiSchema) {
+	var binders = make([]restArgsBinder, handlerTyper.NumIn())
+	var schema = &apiSchema{}
+	var modelIdx = -1
+	for i := 0; i < handlerTyper.NumIn(); i++ {
+		typ := handlerTyper.In(i)
+		binder := restArgsBinder{dataType: typ}
+		switch {
+		case typ == contextTyper:
+			binder.bindFunc = ctxBinder
+		case typ == userTyper:
+			binder.bindFunc = userBinder
+		case typ == storeTyper:
+			binder.bindFunc = storeBinder
+		case typ.Kind() == reflect.Interface:
+			binder.bindFunc = diBinder
+		case typ.Kind() == reflect.Struct, typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct:
+			if modelIdx >= 0 {
+				panic(fmt.Sprintf("%s, argument(%d) and argument(%d) are both bind from the request, only one struct argument are allowed.", prefix, modelIdx, i))
+			}
+			modelIdx = i
+			binder.bindFunc = modelBinder
+			schema.in = append(schema.in, typ)
+		default:
+			panic(fmt.Sprintf("%s, not supports argument(%d) type: %s", prefix, i, typ))
+		}
+		binders[i] = binder
+	}
+	var returns = restReturns{valueIdx: -1, errIdx: -1}
+	switch handlerTyper.NumOut() {
+	case 0:
+	case 1:
+		if isErrorTyper(handlerTyper.Out(0)) {
+			returns.errIdx = 0
+		} else {
+			returns.valueIdx = 0
+		}
+	case 2:
+		if !isErrorTyper(handlerTyper.Out(1)) {
+			panic(fmt.Sprintf("%s, the second return value should be are error.", prefix))
+		}
+		returns.valueIdx, returns.errIdx = 0, 1
+	default:
+		panic(fmt.Sprintf("%s, should be returns (), (error), (T) or (T, error).", prefix))
+	}
+	if returns.valueIdx >= 0 {
+		schema.out = handlerTyper.Out(returns.valueIdx)
+	}
+	return binders, returns, schema
+}
+
+// isErrorTyper returns true if the typ is the error interface or a pointer that implements it(such as *gw.Error).
+func isErrorTyper(typ reflect.Type) bool {
+	return (typ.Kind() == reflect.Interface || typ.Kind() == reflect.Ptr) && typ.Implements(errorTyper)
+}
+
+func ctxBinder(typ reflect.Type, ctx *Context) (reflect.Value, error) {
+	return reflect.ValueOf(ctx), nil
+}
+
+func userBinder(typ reflect.Type, ctx *Context) (reflect.Value, error) {
+	return reflect.ValueOf(ctx.User()), nil
+}
+
+func storeBinder(typ reflect.Type, ctx *Context) (reflect.Value, error) {
+	return reflect.ValueOf(ctx.Store()), nil
+}
+
+func diBinder(typ reflect.Type, ctx *Context) (reflect.Value, error) {
+	val := reflect.ValueOf(ctx.server.DIProvider.ResolveByTyperWithState(ctx.Store(), typ))
+	if !val.IsValid() {
+		return reflect.Zero(typ), nil
+	}
+	if typ.Kind() == reflect.Ptr && val.Kind() != reflect.Ptr {
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		return ptr, nil
+	}
+	if typ.Kind() != reflect.Ptr && typ.Kind() != reflect.Interface && val.Kind() == reflect.Ptr {
+		return val.Elem(), nil
+	}
+	return val, nil
+}
+
+func modelBinder(typ reflect.Type, ctx *Context) (reflect.Value, error) {
+	if di, ok := ctx.server.DIProvider.(diTyperRegistry); ok && di.IsRegistered(typ) {
+		return diBinder(typ, ctx)
+	}
+	var isPtr = typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	val := reflect.New(typ)
+	if err := bindRequestModel(ctx, val.Interface()); err != nil {
+		return val, err
+	}
+	if isPtr {
+		return val, nil
+	}
+	return val.Elem(), nil
+}
+
+// bindRequestModel binds the obj from path(uri tag), query(form tag), JSON or form body, then validate it.
+func bindRequestModel(c *Context, obj interface{}) error {
+	if len(c.Params) > 0 {
+		params := make(map[string][]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		if err := skipValidationErr(binding.Uri.BindUri(params, obj)); err != nil {
+			return err
+		}
+	}
+	if c.Request.URL.RawQuery != "" {
+		if err := skipValidationErr(c.ShouldBindWith(obj, binding.Query)); err != nil {
+			return err
+		}
+	}
+	if c.Request.Body != nil && c.Request.Body != http.NoBody && c.Request.ContentLength != 0 {
+		b := bindingOf(c.Request.Method, c.ContentType())
+		if err := skipValidationErr(c.ShouldBindWith(obj, b)); err != nil {
+			return err
+		}
+	}
+	return c.Validate(obj)
+}
+
+// skipValidationErr skip the validation errors of partial bindings, the obj are validated after all bindings.
+func skipValidationErr(err error) error {
+	if _, ok := err.(validator.ValidationErrors); ok {
+		return nil
+	}
+	return err
+}
+
+// render the return values of the dynamic rest API handler by RespBodyBuildFunc.
+func (d DynamicCaller) render(c *Context, rets []reflect.Value) {
+	if d.returns.errIdx >= 0 && !rets[d.returns.errIdx].IsNil() {
+		if c.Writer.Written() {
+			return
+		}
+		err := rets[d.returns.errIdx].Interface().(error)
+		respErr(c.Context, c.requestId, 0, err, nil)
+		return
+	}
+	if c.Writer.Written() || (d.returns.valueIdx < 0 && d.returns.errIdx < 0) {
+		return
+	}
+	var payload interface{}
+	if d.returns.valueIdx >= 0 {
+		payload = rets[d.returns.valueIdx].Interface()
+	}
+	if d.conditional && d.returns.valueIdx >= 0 {
+		c.conditionalJSON200(payload)
+		return
+	}
+	c.JSON200(payload)
+}
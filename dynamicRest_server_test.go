@@ -0,0 +1,103 @@
+package gw_test
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type restUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+}
+
+type restUserQuery struct {
+	ID uint64 `uri:"id" binding:"required"`
+}
+
+type restDeleteUser struct {
+	ID uint64 `form:"id" binding:"required"`
+}
+
+type restCreateUser struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type restUserAPI struct {
+}
+
+func (u *restUserAPI) Name() string {
+	return "users"
+}
+
+func (u *restUserAPI) Detail(user gw.User, store gw.IStore, in *restUserQuery) (*restUser, error) {
+	var out restUser
+	err := store.GetDbStore().Where("id = ? and tenant_id = ?", in.ID, user.TenantId).First(&out).Error
+	return &out, err
+}
+
+func (u *restUserAPI) Post(c *gw.Context, in restCreateUser) (restUser, error) {
+	out := restUser{TenantId: c.User().TenantId, Name: in.Name}
+	err := c.Store().GetDbStore().Create(&out).Error
+	return out, err
+}
+
+func (u *restUserAPI) Delete(user gw.User, store gw.IStore, in restDeleteUser) *gw.Error {
+	result := store.GetDbStore().Where("id = ? and tenant_id = ?", in.ID, user.TenantId).Delete(&restUser{})
+	if result.RowsAffected == 0 {
+		return gw.ErrNotFoundRequest
+	}
+	return nil
+}
+
+type restTwoModelAPI struct {
+}
+
+func (u *restTwoModelAPI) Name() string {
+	return "two"
+}
+
+func (u *restTwoModelAPI) Post(in restCreateUser, other restCreateUser) error {
+	return nil
+}
+
+func TestServer_DynamicRestAPI(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.RegisterRestAPIs(&restUserAPI{})
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			db := state.Store().GetDbStore()
+			_ = db.AutoMigrate(&restUser{})
+			db.Create(&restUser{ID: 1, TenantId: 10, Name: "gw"})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+
+	var user restUser
+	client.Call("(*restUserAPI).Detail", gwtest.Params{"id": "1"}, nil).AssertOK().DecodePayload(&user)
+	assert.Equal(t, "gw", user.Name)
+	client.Call("(*restUserAPI).Detail", gwtest.Params{"id": "2"}, nil).AssertError(http.StatusNotFound, gw.ErrNotFoundRequest.Error())
+
+	client.Call("(*restUserAPI).Post", nil, map[string]string{"name": "new"}).AssertOK().DecodePayload(&user)
+	assert.Equal(t, "new", user.Name)
+	assert.Equal(t, uint64(10), user.TenantId)
+	client.Call("(*restUserAPI).Post", nil, map[string]string{}).AssertError(http.StatusBadRequest, "required")
+
+	// the *gw.Error returns are treated as the error.
+	client.Call("(*restUserAPI).Delete", gwtest.Params{"id": "1"}, nil).AssertOK()
+	client.Call("(*restUserAPI).Delete", gwtest.Params{"id": "1"}, nil).AssertError(http.StatusNotFound, gw.ErrNotFoundRequest.Error())
+}
+
+func TestServer_DynamicRestAPI_TwoModels(t *testing.T) {
+	assert.Panics(t, func() {
+		gwtest.NewServer(t, &gwtest.App{
+			RegisterFunc: func(router *gw.RouterGroup) {
+				router.RegisterRestAPIs(&restTwoModelAPI{})
+			},
+		})
+	})
+}
@@ -0,0 +1,279 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/oceanho/gw/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+)
+
+const (
+	MIMEProblemJSON = "application/problem+json"
+)
+
+// The stable machine codes of gw framework errors.
+const (
+	ErrCodeBadRequest               = "bad_request"
+	ErrCodeUnauthorized             = "unauthorized"
+	ErrCodePermissionDenied         = "permission_denied"
+	ErrCodeNotFound                 = "not_found"
+	ErrCodeNotAcceptable            = "not_acceptable"
+	ErrCodeTooManyRequests          = "too_many_requests"
+	ErrCodePreconditionFailed       = "precondition_failed"
+	ErrCodeRequestEntityTooLarge    = "request_entity_too_large"
+	ErrCodeIdempotencyKeyMismatch   = "idempotency_key_mismatch"
+	ErrCodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	ErrCodeValidationFailed         = "validation_failed"
+	ErrCodeInternalError            = "internal_error"
+	ErrCodeRequestCanceled          = "request_canceled"
+	ErrCodeRequestTimeout           = "request_timeout"
+	ErrCodeServiceUnavailable       = "service_unavailable"
+)
+
+// Error represents a typed application error, it's carrying the http status, a stable machine code(for API consumers),
+// a message key(translated into the request locale by the Translator), the details and a optional internal cause.
+//
+// The cause are logged but never serialized, Error() returns the public message only.
+// errors.Is(err, target) matches the errors that has same Code, so the sentinel errors can be compared as
+//
+//	errors.Is(err, gw.ErrPermissionDenied)
+type Error struct {
+	Status     int
+	Code       string
+	MessageKey string
+	Message    string
+	Details    interface{}
+	Cause      error
+	args       []interface{}
+}
+
+// NewError returns a Error, message is the default public message, it's also used as message key.
+func NewError(status int, code, message string) *Error {
+	return &Error{
+		Status:     status,
+		Code:       code,
+		MessageKey: message,
+		Message:    message,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.MessageKey != "" {
+		return e.MessageKey
+	}
+	return e.Code
+}
+
+// Unwrap returns the internal cause.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is returns true if the target are a *Error that has same Code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) clone() *Error {
+	err := *e
+	return &err
+}
+
+// WithCause returns a copy of the Error with the internal cause.
+func (e *Error) WithCause(cause error) *Error {
+	err := e.clone()
+	err.Cause = cause
+	return err
+}
+
+// WithDetails returns a copy of the Error with the details, the details are serialized as the response payload.
+func (e *Error) WithDetails(details interface{}) *Error {
+	err := e.clone()
+	err.Details = details
+	return err
+}
+
+// WithMessage returns a copy of the Error with the public message, args are formatted by fmt.Sprintf.
+// The message(not formatted) are also used as message key.
+func (e *Error) WithMessage(message string, args ...interface{}) *Error {
+	err := e.clone()
+	err.MessageKey = message
+	err.args = args
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	err.Message = message
+	return err
+}
+
+// WithMessageKey returns a copy of the Error with the message key, args are the translation args.
+func (e *Error) WithMessageKey(key string, args ...interface{}) *Error {
+	err := e.clone()
+	err.MessageKey = key
+	err.args = args
+	return err
+}
+
+// localize returns a copy of the Error that the public message translated into locale,
+// the Error are returned as it is if the message key has no translation.
+func (e *Error) localize(t *Translator, locale string) *Error {
+	if e.MessageKey == "" {
+		return e
+	}
+	msg, ok := t.Lookup(locale, e.MessageKey)
+	if !ok {
+		return e
+	}
+	err := e.clone()
+	err.Message = formatMessage(msg, e.args)
+	return err
+}
+
+// AsError converts the err to a *Error, returns nil if err is nil.
+//
+// *Error(wrapped also) are returned as it is, ValidationErrors are converted to validation_failed(400) errors,
+// the well-known errors(gorm.ErrRecordNotFound, context errors) are converted to the matched errors,
+// other errors are converted to internal_error(500) errors, the error are kept as the internal cause only.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return ErrValidationFailed.WithMessage(errs.Error()).WithDetails(gin.H{"Errors": errs})
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFoundRequest.WithCause(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrRequestTimeout.WithCause(err)
+	case errors.Is(err, context.Canceled):
+		return ErrRequestCanceled.WithCause(err)
+	}
+	return ErrInternalServerError.WithCause(err)
+}
+
+// errorOf returns a *Error of the http status and the error message,
+// the *Error(and ValidationErrors) are returned as it is(the http status of the error are used).
+// The message of the 5xx errors are not public, the error are kept as the internal cause only.
+func errorOf(status int, errMsg interface{}) *Error {
+	err, ok := errMsg.(error)
+	if !ok {
+		return NewError(status, errCodeOf(status), fmt.Sprintf("%v", errMsg))
+	}
+	var e *Error
+	var errs ValidationErrors
+	if errors.As(err, &e) || errors.As(err, &errs) {
+		return AsError(err)
+	}
+	if status == http.StatusInternalServerError {
+		return ErrInternalServerError.WithCause(err)
+	}
+	if status > http.StatusInternalServerError {
+		return NewError(status, errCodeOf(status), http.StatusText(status)).WithCause(err)
+	}
+	return NewError(status, errCodeOf(status), err.Error()).WithCause(err)
+}
+
+func errCodeOf(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodePermissionDenied
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusNotAcceptable:
+		return ErrCodeNotAcceptable
+	case http.StatusTooManyRequests:
+		return ErrCodeTooManyRequests
+	case http.StatusPreconditionFailed:
+		return ErrCodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodeRequestEntityTooLarge
+	case http.StatusServiceUnavailable:
+		return ErrCodeServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrCodeRequestTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrCodeInternalError
+	}
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+// ProblemDetails represents a RFC 7807 problem details(application/problem+json) response body.
+type ProblemDetails struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      string      `json:"code"`
+	RequestId string      `json:"requestId,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// useProblemDetails returns true if the errors should be responded as RFC 7807 problem details,
+// it's enabled by Settings.Errors.ProblemDetails or the request Accept application/problem+json.
+func useProblemDetails(s *HostServer, c *gin.Context) bool {
+	if s.Config().Settings.Errors.ProblemDetails.Enabled {
+		return true
+	}
+	return strings.Contains(strings.ToLower(c.GetHeader("Accept")), MIMEProblemJSON)
+}
+
+// respErr responds the error by RespBodyBuildFunc(or RFC 7807 problem details),
+// status is the envelope Status, 0 means uses the http status of the error.
+// The internal cause of the error are always logged but never responded, the message are translated into the request locale.
+func respErr(c *gin.Context, requestId string, status int, err error, payload interface{}) {
+	s := getHostServer(c)
+	e := AsError(err)
+	locale := resolveLocale(s, c)
+	e = e.localize(s.Translator, locale)
+	c.Header("Content-Language", locale)
+	if e.Status >= http.StatusInternalServerError || e.Cause != nil {
+		logger.ErrorCtx(c.Request.Context(), "requestId: %s, %s %s, status: %d, code: %s, err: %s, cause: %v",
+			requestId, c.Request.Method, c.Request.URL.Path, e.Status, e.Code, e.Error(), e.Cause)
+	}
+	if payload == nil {
+		payload = e.Details
+	}
+	if useProblemDetails(s, c) {
+		problemType := "about:blank"
+		if base := s.Config().Settings.Errors.ProblemDetails.TypeBaseUrl; base != "" {
+			problemType = strings.TrimRight(base, "/") + "/" + e.Code
+		}
+		c.Header("Content-Type", MIMEProblemJSON)
+		c.Render(e.Status, render.JSON{Data: ProblemDetails{
+			Type:      problemType,
+			Title:     http.StatusText(e.Status),
+			Status:    e.Status,
+			Detail:    e.Error(),
+			Instance:  c.Request.URL.Path,
+			Code:      e.Code,
+			RequestId: requestId,
+			Details:   payload,
+		}})
+		return
+	}
+	if status == 0 {
+		status = e.Status
+	}
+	renderResp(c, e.Status, s.RespBodyBuildFunc(status, requestId, e, payload))
+}
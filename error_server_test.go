@@ -0,0 +1,46 @@
+package gw_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func errorForbidden(c *gw.Context) {
+	c.JSON500Msg(0, gw.ErrPermissionDenied.WithDetails("admin only").WithCause(fmt.Errorf("secret cause")))
+}
+
+func TestServer_Error(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("forbidden", errorForbidden)
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	resp := client.Call("errorForbidden", nil, nil).AssertError(http.StatusForbidden, gw.ErrPermissionDenied.Error())
+	assert.Equal(t, gw.ErrCodePermissionDenied, resp.Envelope().Code)
+	assert.NotContains(t, string(resp.Body), "secret cause")
+
+	resp = client.WithHeader("Accept", gw.MIMEProblemJSON).Call("errorForbidden", nil, nil).AssertStatus(http.StatusForbidden)
+	assert.Equal(t, gw.MIMEProblemJSON, resp.Header.Get("Content-Type"))
+	var problem gw.ProblemDetails
+	assert.Nil(t, json.Unmarshal(resp.Body, &problem))
+	assert.NotEmpty(t, problem.RequestId)
+	assert.Equal(t, gw.ProblemDetails{
+		Type:      "about:blank",
+		Title:     "Forbidden",
+		Status:    http.StatusForbidden,
+		Detail:    gw.ErrPermissionDenied.Error(),
+		Instance:  client.Router("errorForbidden").UrlPath,
+		Code:      gw.ErrCodePermissionDenied,
+		RequestId: problem.RequestId,
+		Details:   "admin only",
+	}, problem)
+
+	resp = server.Client().Call("errorForbidden", nil, nil).AssertStatus(http.StatusUnauthorized)
+	assert.Equal(t, gw.ErrCodeUnauthorized, resp.Envelope().Code)
+}
@@ -0,0 +1,52 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	cause := fmt.Errorf("dial tcp: connection refused")
+	err := fmt.Errorf("query user: %w", ErrInternalServerError.WithCause(cause))
+	assert.True(t, errors.Is(err, ErrInternalServerError))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, errors.Is(err, ErrPermissionDenied))
+
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusInternalServerError, e.Status)
+	assert.Equal(t, "server internal error", e.Error())
+	assert.Equal(t, ErrCodeInternalError, AsError(err).Code)
+
+	cause = fmt.Errorf("dial tcp 10.0.0.1:3306: connection refused")
+	e = AsError(cause)
+	assert.Equal(t, http.StatusInternalServerError, e.Status)
+	assert.Equal(t, ErrCodeInternalError, e.Code)
+	assert.Equal(t, "server internal error", e.Error())
+	assert.Equal(t, cause, e.Cause)
+	e = AsError(fmt.Errorf("query user: %w", gorm.ErrRecordNotFound))
+	assert.Equal(t, http.StatusNotFound, e.Status)
+	assert.Equal(t, ErrCodeNotFound, e.Code)
+	assert.Equal(t, ErrCodeRequestTimeout, AsError(context.DeadlineExceeded).Code)
+	assert.Equal(t, ErrCodeRequestCanceled, AsError(context.Canceled).Code)
+
+	e = errorOf(http.StatusNotFound, fmt.Errorf("record not found"))
+	assert.Equal(t, ErrCodeNotFound, e.Code)
+	e = errorOf(http.StatusInternalServerError, cause)
+	assert.Equal(t, "server internal error", e.Error())
+	e = errorOf(http.StatusBadRequest, ErrPermissionDenied)
+	assert.Equal(t, http.StatusForbidden, e.Status)
+	e = errorOf(http.StatusBadRequest, ValidationErrors{{Field: "name", Rule: "required", Message: "name is required"}})
+	assert.Equal(t, ErrCodeValidationFailed, e.Code)
+	assert.Equal(t, "name is required", e.Error())
+	// a bare 503 are not a canceled request.
+	e = errorOf(http.StatusServiceUnavailable, "maintenance")
+	assert.Equal(t, ErrCodeServiceUnavailable, e.Code)
+	e = errorOf(http.StatusServiceUnavailable, ErrRequestCanceled)
+	assert.Equal(t, ErrCodeRequestCanceled, e.Code)
+}
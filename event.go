@@ -5,6 +5,7 @@ import (
 	"github.com/oceanho/gw/libs/gwjsoner"
 	"github.com/oceanho/gw/logger"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -52,6 +53,7 @@ type DefaultEventManagerImpl struct {
 	locker      sync.Mutex
 	isReady     bool
 	eventChan   chan IEvent
+	pending     int64
 	idGenerator IdentifierGenerator
 	subscribers map[string][]*EventSubscriber
 }
@@ -60,10 +62,16 @@ func (d *DefaultEventManagerImpl) Publish(event IEvent) error {
 	if !d.isReady {
 		return ErrorEventChannelHasNotReady
 	}
+	atomic.AddInt64(&d.pending, 1)
 	d.eventChan <- event
 	return nil
 }
 
+// QueueDepth returns the number of the published events that waiting to be dispatched.
+func (d *DefaultEventManagerImpl) QueueDepth() int {
+	return int(atomic.LoadInt64(&d.pending))
+}
+
 func (d *DefaultEventManagerImpl) Subscribe(eventName string, handler EventHandler) (subscriberId string) {
 	d.locker.Lock()
 	defer d.locker.Unlock()
@@ -136,6 +144,7 @@ func DefaultEventManager(state *ServerState) IEventManager {
 				}()
 				break
 			case event := <-m.eventChan:
+				atomic.AddInt64(&m.pending, -1)
 				metaInfo := event.MetaInfo()
 				if subscribers, ok := m.subscribers[metaInfo.Name]; ok {
 					for _, sub := range subscribers {
@@ -2,6 +2,7 @@ package gw
 
 import (
 	"github.com/oceanho/gw/libs/gwjsoner"
+	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
@@ -31,3 +32,17 @@ func TestDefaultEventManager(t *testing.T) {
 	_ = eventManager.Publish(event)
 	server.ShutDown()
 }
+
+func TestDefaultEventManagerImpl_QueueDepth(t *testing.T) {
+	m := &DefaultEventManagerImpl{isReady: true, eventChan: make(chan IEvent)}
+	done := make(chan struct{})
+	go func() {
+		_ = m.Publish(TesterEvent{})
+		close(done)
+	}()
+	for m.QueueDepth() == 0 {
+	}
+	assert.Equal(t, 1, m.QueueDepth())
+	<-m.eventChan
+	<-done
+}
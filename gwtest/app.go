@@ -0,0 +1,78 @@
+package gwtest
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+)
+
+// App represents a small gw.App for the feature tests, the nil funcs are no-op.
+//
+// Example:
+//
+//	server := gwtest.NewServer(t, &gwtest.App{
+//		RegisterFunc: func(router *gw.RouterGroup) {
+//			router.GET("ping", Ping)
+//		},
+//	})
+type App struct {
+	// AppName is the App.Name(), default is gwtest.app.
+	AppName string
+	// RouterPath is the App.Router(), default is tester.
+	RouterPath          string
+	RegisterFunc        func(router *gw.RouterGroup)
+	UseFunc             func(option *gw.ServerOption)
+	MigrateFunc         func(state *gw.ServerState)
+	OnStartFunc         func(state *gw.ServerState)
+	OnShutDownFunc      func(state *gw.ServerState)
+	OnConfigChangedFunc func(old, new *conf.ApplicationConfig)
+}
+
+func (a *App) Name() string {
+	if a.AppName == "" {
+		return "gwtest.app"
+	}
+	return a.AppName
+}
+
+func (a *App) Router() string {
+	if a.RouterPath == "" {
+		return "tester"
+	}
+	return a.RouterPath
+}
+
+func (a *App) Register(router *gw.RouterGroup) {
+	if a.RegisterFunc != nil {
+		a.RegisterFunc(router)
+	}
+}
+
+func (a *App) Use(option *gw.ServerOption) {
+	if a.UseFunc != nil {
+		a.UseFunc(option)
+	}
+}
+
+func (a *App) Migrate(state *gw.ServerState) {
+	if a.MigrateFunc != nil {
+		a.MigrateFunc(state)
+	}
+}
+
+func (a *App) OnStart(state *gw.ServerState) {
+	if a.OnStartFunc != nil {
+		a.OnStartFunc(state)
+	}
+}
+
+func (a *App) OnShutDown(state *gw.ServerState) {
+	if a.OnShutDownFunc != nil {
+		a.OnShutDownFunc(state)
+	}
+}
+
+func (a *App) OnConfigChanged(old, new *conf.ApplicationConfig) {
+	if a.OnConfigChangedFunc != nil {
+		a.OnConfigChangedFunc(old, new)
+	}
+}
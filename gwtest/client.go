@@ -0,0 +1,240 @@
+package gwtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/utils/secure"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Params represents the path params(:id, *path) and query params of a router call.
+type Params map[string]string
+
+// Client represents a http client of the gwtest Server.
+type Client struct {
+	server *Server
+	t      testing.TB
+	header http.Header
+	sid    string
+	User   gw.User
+}
+
+func newClient(s *Server) *Client {
+	return &Client{
+		server: s,
+		t:      s.t,
+		header: make(http.Header),
+		User:   gw.EmptyUser,
+	}
+}
+
+// LoginAs returns a new Client that logged in as the user(and the user's tenant).
+//
+// The session are saved by the server's ISessionStateManager, and it's credential are sent by the X-Auth-Token header.
+func (c *Client) LoginAs(user gw.User) *Client {
+	c.t.Helper()
+	if user.PermMaps == nil && len(user.Permissions) > 0 {
+		user.PermMaps = make(map[string]gw.Permission)
+		for _, p := range user.Permissions {
+			user.PermMaps[p.IdStr()] = p
+		}
+	}
+	sid := secure.RandomStr(32)
+	if err := c.server.State().SessionStateManager().Save(sid, user); err != nil {
+		c.t.Fatalf("gwtest: save session of user: %s, err: %v", user.Passport, err)
+	}
+	credential, err := c.server.SessionCredential(sid)
+	if err != nil {
+		c.t.Fatalf("gwtest: create credential of user: %s, err: %v", user.Passport, err)
+	}
+	client := c.clone()
+	client.sid = sid
+	client.User = user
+	client.header.Set("X-Auth-Token", credential)
+	return client
+}
+
+// Logout removes the session of the Client.
+func (c *Client) Logout() {
+	if c.sid == "" {
+		return
+	}
+	_ = c.server.RemoveSession(c.sid)
+}
+
+// WithHeader returns a new Client that send requests with the header.
+func (c *Client) WithHeader(key, value string) *Client {
+	client := c.clone()
+	client.header.Set(key, value)
+	return client
+}
+
+// Header returns a copy of the headers that sent by the Client, such as the session header of LoginAs(...).
+func (c *Client) Header() http.Header {
+	return c.header.Clone()
+}
+
+func (c *Client) clone() *Client {
+	client := *c
+	client.header = c.header.Clone()
+	return &client
+}
+
+// Router returns the router that registered on the Server by name.
+//
+// The name can be the full handler action name(RouterInfo.Name()) or a unique suffix of it,
+// such as api.GetUser, GetUser or RestAPI.(*User).Get
+func (c *Client) Router(name string) gw.RouterInfo {
+	c.t.Helper()
+	var matched []gw.RouterInfo
+	for _, r := range c.server.GetRouters() {
+		full := r.Name()
+		short := strings.TrimSuffix(full, "(ctx *Context)")
+		if full == name || short == name {
+			return r
+		}
+		if strings.HasSuffix(short, "."+name) || strings.HasSuffix(short, "/"+name) {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) == 0 {
+		c.t.Fatalf("gwtest: router %s not found", name)
+	}
+	if len(matched) > 1 {
+		var names = make([]string, 0, len(matched))
+		for _, r := range matched {
+			names = append(names, r.Name())
+		}
+		c.t.Fatalf("gwtest: router %s are ambiguous, matched: %s", name, strings.Join(names, ", "))
+	}
+	return matched[0]
+}
+
+// Call calls the router that registered on the Server by name, see Router(...).
+//
+// The params that not in the router's path will be sent as query params.
+func (c *Client) Call(name string, params Params, body interface{}) *Response {
+	c.t.Helper()
+	r := c.Router(name)
+	method := r.Method
+	if method == "any" {
+		method = http.MethodGet
+	}
+	return c.Do(method, c.routerPath(r, params), body)
+}
+
+// DialWebSocket dials the WebSocket router that registered on the Server by name, see Router(...).
+//
+// The http response of the handshake are returned when the dial fail(such as http 401, 403).
+func (c *Client) DialWebSocket(name string, params Params) (*websocket.Conn, *http.Response, error) {
+	c.t.Helper()
+	r := c.Router(name)
+	wsURL := "ws" + strings.TrimPrefix(c.server.HTTP.URL, "http") + c.routerPath(r, params)
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, c.header.Clone())
+	if conn != nil {
+		c.t.Cleanup(func() {
+			_ = conn.Close()
+		})
+	}
+	return conn, resp, err
+}
+
+// routerPath returns the url path(with query) of the router call.
+func (c *Client) routerPath(r gw.RouterInfo, params Params) string {
+	c.t.Helper()
+	var query = make(url.Values)
+	var segments = strings.Split(r.UrlPath, "/")
+	var used = make(map[string]bool)
+	for i, seg := range segments {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		key := seg[1:]
+		val, ok := params[key]
+		if !ok && seg[0] == ':' {
+			c.t.Fatalf("gwtest: router %s missing path param: %s", r.UrlPath, key)
+		}
+		segments[i] = url.PathEscape(val)
+		used[key] = true
+	}
+	for k, v := range params {
+		if !used[k] {
+			query.Set(k, v)
+		}
+	}
+	urlPath := strings.Join(segments, "/")
+	if len(query) > 0 {
+		urlPath = fmt.Sprintf("%s?%s", urlPath, query.Encode())
+	}
+	return urlPath
+}
+
+// Do sends a http request to the Server.
+//
+// The body can be nil, a io.Reader, []byte, string, url.Values(sent as form) or any object(sent as JSON).
+func (c *Client) Do(method, urlPath string, body interface{}) *Response {
+	c.t.Helper()
+	var reader io.Reader
+	var contentType string
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	case []byte:
+		reader = bytes.NewReader(b)
+	case string:
+		reader = strings.NewReader(b)
+	case url.Values:
+		reader = strings.NewReader(b.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	default:
+		bs, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatalf("gwtest: marshal request body, err: %v", err)
+		}
+		reader = bytes.NewReader(bs)
+		contentType = "application/json"
+	}
+	req, err := http.NewRequest(method, c.server.HTTP.URL+urlPath, reader)
+	if err != nil {
+		c.t.Fatalf("gwtest: create request %s %s, err: %v", method, urlPath, err)
+	}
+	req.Header = c.header.Clone()
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.server.HTTP.Client().Do(req)
+	if err != nil {
+		c.t.Fatalf("gwtest: request %s %s, err: %v", method, urlPath, err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("gwtest: read response %s %s, err: %v", method, urlPath, err)
+	}
+	return &Response{
+		Response: resp,
+		Body:     b,
+		t:        c.t,
+	}
+}
+
+// Get sends a http GET request to the Server.
+func (c *Client) Get(urlPath string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, urlPath, nil)
+}
+
+// Post sends a http POST request to the Server.
+func (c *Client) Post(urlPath string, body interface{}) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, urlPath, body)
+}
@@ -0,0 +1,176 @@
+// Package gwtest provides a in-process HostServer for gw apps testing.
+//
+// The server are backed by in-memory SQLite databases and a embedded Redis-compatible cache,
+// served by httptest.Server, so the app tests does not requires real MySQL or Redis.
+//
+// Example:
+//
+//	func TestMyApp(t *testing.T) {
+//		server := gwtest.NewServer(t, myapp.New())
+//		client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 1, Passport: "tester"})
+//		client.Call("api.GetUser", gwtest.Params{"id": "1"}, nil).AssertOK().DecodePayload(&user)
+//	}
+package gwtest
+
+import (
+	"fmt"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const defaultConfig = `
+version: 1.0
+server:
+  listenAddr: "127.0.0.1:0"
+  name: "gwtest"
+service:
+  name: "gwtest"
+  prefix: "/api/v1"
+  version: "gwtest"
+  metrics:
+    enabled: True
+    router: /metrics
+  openapi:
+    enabled: True
+    router: /openapi.json
+backend:
+  db:
+  - name: primary
+    driver: sqlite
+  cache:
+  - name: primary
+    driver: redis
+security:
+  crypto:
+    hash:
+      alg: "sha256"
+      salt: "gwtest-hash-salt-for-testing-only"
+    protect:
+      alg: "aes"
+      secret: "gwtestProtectSecretForTesting123"
+  auth:
+    paramKey:
+      passport: "passport"
+      secret: "secret"
+      tenantId: "tenantId"
+      verifyCode: "verifyCode"
+    paramPattern:
+      passport: '^\S{1,64}$'
+      secret: '^\S{1,64}$'
+      verifyCode: '^[0-9]{0,8}$'
+    session:
+      defaultStore:
+        name: primary
+        prefix: gw-sid
+    permission:
+      defaultStore:
+        type: db
+        name: primary
+    cookie:
+      key: "_gid"
+      path: "/"
+      maxAge: "7200"
+  authServer:
+    enableAuthServe: True
+    login:
+      url: "{{ .service.prefix }}/gw/auth/login"
+    logout:
+      url: "{{ .service.prefix }}/gw/auth/logout"
+  limit:
+    pagination:
+      minPageSize: "20"
+      maxPageSize: "2000"
+settings:
+  gw:
+    printRouterInfo:
+      disabled: True
+  headerKey:
+    requestIdKey: "X-Request-Id"
+  timeoutControl:
+    redis: "1000"
+    database: "2000"
+    http: "2000"
+    shutdown: "1000"
+  expirationTimeControl:
+    session: "7200"
+`
+
+var serverSeq uint64
+
+// DefaultConfig returns the default ApplicationConfig of the gwtest Server.
+func DefaultConfig() *conf.ApplicationConfig {
+	cnf, err := conf.NewConfigFromBytes("yaml", []byte(defaultConfig))
+	if err != nil {
+		panic(fmt.Sprintf("parse gwtest default config fail, err: %v", err))
+	}
+	return cnf
+}
+
+// Server represents a gw HostServer that served by httptest.Server.
+type Server struct {
+	*gw.HostServer
+	HTTP  *httptest.Server
+	Store *Store
+	t     testing.TB
+}
+
+// NewServer returns a started Server with apps, the Server will be closed when the test finished.
+func NewServer(t testing.TB, apps ...gw.App) *Server {
+	return NewServerWithOption(t, nil, apps...)
+}
+
+// NewServerWithOption returns a started Server with apps,
+// optionHandler can be change the ServerOption before the server compiled, such as the AppConfigHandler.
+func NewServerWithOption(t testing.TB, optionHandler func(opts *gw.ServerOption), apps ...gw.App) *Server {
+	t.Helper()
+	opts := gw.NewServerOption(&conf.BootConfig{})
+	opts.Name = fmt.Sprintf("gwtest-%d", atomic.AddUint64(&serverSeq, 1))
+	opts.Addr = "127.0.0.1:0"
+	opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+		return DefaultConfig()
+	}
+	if optionHandler != nil {
+		optionHandler(opts)
+	}
+	var store *Store
+	opts.BackendStoreHandler = func(cnf *conf.ApplicationConfig) gw.IStore {
+		var err error
+		store, err = NewStore(cnf)
+		if err != nil {
+			t.Fatalf("gwtest: create store, err: %v", err)
+		}
+		return store
+	}
+	s := &Server{
+		HostServer: gw.NewServerWithOption(opts),
+		t:          t,
+	}
+	s.Register(apps...)
+	handler := s.Handler()
+	if handler == nil {
+		t.Fatalf("gwtest: server %s has no http handler", opts.Name)
+	}
+	s.Store = store
+	s.HTTP = httptest.NewServer(handler)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close shutdown the HostServer, the httptest.Server and the Store.
+// It's waiting for the HostServer shut down, so the server name are released.
+func (s *Server) Close() {
+	s.HTTP.Close()
+	s.HostServer.ShutDown()
+	<-s.HostServer.ShutDownDone()
+	if s.Store != nil {
+		s.Store.Close()
+	}
+}
+
+// Client returns a anonymous Client of the Server.
+func (s *Server) Client() *Client {
+	return newClient(s)
+}
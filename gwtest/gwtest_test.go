@@ -0,0 +1,85 @@
+package gwtest
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type testerUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+}
+
+type testerApp struct {
+}
+
+func (t testerApp) Name() string {
+	return "gwtest.tester"
+}
+
+func (t testerApp) Router() string {
+	return "tester"
+}
+
+func (t testerApp) Register(router *gw.RouterGroup) {
+	router.GET("user/:id", GetUser)
+}
+
+func (t testerApp) Use(option *gw.ServerOption) {
+}
+
+func (t testerApp) Migrate(state *gw.ServerState) {
+	db := state.Store().GetDbStore()
+	_ = db.AutoMigrate(&testerUser{})
+	db.Create(&testerUser{ID: 1, TenantId: 10, Name: "gw"})
+}
+
+func (t testerApp) OnStart(state *gw.ServerState) {
+}
+
+func (t testerApp) OnShutDown(state *gw.ServerState) {
+}
+
+func GetUser(c *gw.Context) {
+	var user testerUser
+	err := c.Store().GetDbStore().Where("id = ? and tenant_id = ?", c.Param("id"), c.User().TenantId).First(&user).Error
+	if err != nil {
+		c.JSON404Msg(404, err)
+		return
+	}
+	c.JSON200(user)
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(t, testerApp{})
+	anonymous := server.Client()
+	anonymous.Call("GetUser", Params{"id": "1"}, nil).AssertStatus(http.StatusUnauthorized)
+
+	var user testerUser
+	client := anonymous.LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	client.Call("gwtest.GetUser", Params{"id": "1"}, nil).AssertOK().DecodePayload(&user)
+	assert.Equal(t, "gw", user.Name)
+
+	other := anonymous.LoginAs(gw.User{ID: 2, TenantId: 20, Passport: "other"})
+	other.Call("GetUser", Params{"id": "1"}, nil).AssertStatus(http.StatusNotFound)
+
+	client.Logout()
+	client.Call("GetUser", Params{"id": "1"}, nil).AssertStatus(http.StatusUnauthorized)
+
+	assert.Nil(t, server.Store.GetCacheStore().Ping(server.Store.GetCacheStore().Context()).Err())
+}
+
+func TestServer_Close(t *testing.T) {
+	server := NewServer(t, testerApp{})
+	state := server.State()
+	server.Close()
+	assert.Equal(t, state, server.State())
+	// the closed server are unregistered from gw, the name can be used by a new server.
+	opts := gw.NewServerOption(&conf.BootConfig{})
+	opts.Name = server.Name
+	assert.True(t, server.HostServer != gw.NewServerWithOption(opts))
+}
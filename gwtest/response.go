@@ -0,0 +1,89 @@
+package gwtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Envelope represents the standard gw response body(gw.DefaultRespBodyBuildFunc).
+type Envelope struct {
+	Status    int
+	Code      string
+	Error     string
+	RequestId string
+	Payload   json.RawMessage
+}
+
+// Response represents a http response of the gwtest Server.
+type Response struct {
+	*http.Response
+	Body []byte
+	t    testing.TB
+}
+
+// Envelope returns the decoded standard response body.
+func (r *Response) Envelope() Envelope {
+	r.t.Helper()
+	var env Envelope
+	if err := json.Unmarshal(r.Body, &env); err != nil {
+		r.t.Fatalf("gwtest: decode response envelope, err: %v, body: %s", err, r.Body)
+	}
+	return env
+}
+
+// AssertStatus asserts the http status code of the response.
+func (r *Response) AssertStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Fatalf("gwtest: %s %s, expect http status %d, but got %d, body: %s",
+			r.Request.Method, r.Request.URL.Path, code, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// AssertOK asserts the response are http 200, and the envelope are success(Status = 0, without Error).
+func (r *Response) AssertOK() *Response {
+	r.t.Helper()
+	r.AssertStatus(http.StatusOK)
+	env := r.Envelope()
+	if env.Status != 0 || env.Error != "" {
+		r.t.Fatalf("gwtest: %s %s, expect success envelope, but got status: %d, error: %s",
+			r.Request.Method, r.Request.URL.Path, env.Status, env.Error)
+	}
+	return r
+}
+
+// AssertError asserts the http status code, and the envelope Error contains the message.
+func (r *Response) AssertError(code int, message string) *Response {
+	r.t.Helper()
+	r.AssertStatus(code)
+	env := r.Envelope()
+	if env.Error == "" || !strings.Contains(env.Error, message) {
+		r.t.Fatalf("gwtest: %s %s, expect envelope error contains %q, but got %q",
+			r.Request.Method, r.Request.URL.Path, message, env.Error)
+	}
+	return r
+}
+
+// AssertEnvelopeStatus asserts the envelope Status of the response.
+func (r *Response) AssertEnvelopeStatus(status int) *Response {
+	r.t.Helper()
+	env := r.Envelope()
+	if env.Status != status {
+		r.t.Fatalf("gwtest: %s %s, expect envelope status %d, but got %d",
+			r.Request.Method, r.Request.URL.Path, status, env.Status)
+	}
+	return r
+}
+
+// DecodePayload decodes the envelope Payload into out.
+func (r *Response) DecodePayload(out interface{}) *Response {
+	r.t.Helper()
+	env := r.Envelope()
+	if err := json.Unmarshal(env.Payload, out); err != nil {
+		r.t.Fatalf("gwtest: decode response payload, err: %v, payload: %s", err, env.Payload)
+	}
+	return r
+}
@@ -0,0 +1,95 @@
+package gwtest
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"sync/atomic"
+)
+
+var storeSeq uint64
+
+// Store represents a gw.IStore that backed by in-memory SQLite databases and a embedded Redis-compatible server.
+//
+// Every conf.Backend.Db item has a isolated SQLite database,
+// every conf.Backend.Cache item has a isolated Redis database(SELECT n) on the embedded server.
+type Store struct {
+	Redis  *miniredis.Miniredis
+	dbs    map[string]*gorm.DB
+	caches map[string]*redis.Client
+}
+
+// NewStore returns a Store that creates backends by the ApplicationConfig.
+func NewStore(cnf *conf.ApplicationConfig) (*Store, error) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		return nil, fmt.Errorf("start embedded redis, err: %v", err)
+	}
+	store := &Store{
+		Redis:  mr,
+		dbs:    make(map[string]*gorm.DB),
+		caches: make(map[string]*redis.Client),
+	}
+	seq := atomic.AddUint64(&storeSeq, 1)
+	for _, d := range cnf.Backend.Db {
+		dsn := fmt.Sprintf("file:gwtest-%d-%s?mode=memory&cache=shared", seq, d.Name)
+		db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("open sqlite db: %s, err: %v", d.Name, err)
+		}
+		gw.RegisterDbCallbacks(db)
+		store.dbs[d.Name] = db
+	}
+	for i, c := range cnf.Backend.Cache {
+		store.caches[c.Name] = redis.NewClient(&redis.Options{
+			Addr: mr.Addr(),
+			DB:   i,
+		})
+	}
+	return store, nil
+}
+
+func (s *Store) GetDbStore() *gorm.DB {
+	return s.GetDbStoreByName("primary")
+}
+
+func (s *Store) GetDbStoreByName(name string) *gorm.DB {
+	db, ok := s.dbs[name]
+	if !ok {
+		panic(fmt.Sprintf("got db: %s fail. not found.", name))
+	}
+	return db
+}
+
+func (s *Store) GetCacheStore() *redis.Client {
+	return s.GetCacheStoreByName("primary")
+}
+
+func (s *Store) GetCacheStoreByName(name string) *redis.Client {
+	client, ok := s.caches[name]
+	if !ok {
+		panic(fmt.Sprintf("got cache: %s fail. not found.", name))
+	}
+	return client
+}
+
+// Close closes all of the databases, redis clients and the embedded redis server.
+func (s *Store) Close() {
+	for _, db := range s.dbs {
+		if sqlDb, err := db.DB(); err == nil {
+			_ = sqlDb.Close()
+		}
+	}
+	for _, client := range s.caches {
+		_ = client.Close()
+	}
+	s.Redis.Close()
+}
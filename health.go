@@ -0,0 +1,186 @@
+package gw
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HealthStatusUp   = "UP"
+	HealthStatusDown = "DOWN"
+)
+
+var (
+	healthDefaultTimeout  = time.Second
+	ErrServerNotReady     = fmt.Errorf("server not ready")
+	ErrServerShuttingDown = fmt.Errorf("server is shutting down")
+)
+
+// HealthCheckHandler represents a health check handler, returns a error if the checked object are unhealthy.
+type HealthCheckHandler func(ctx context.Context, state *ServerState) error
+
+// HealthCheckResult represents a result of a health check.
+type HealthCheckResult struct {
+	Name      string
+	Status    string
+	LatencyMs float64
+	Error     string `json:",omitempty"`
+}
+
+type healthCheck struct {
+	name    string
+	handler HealthCheckHandler
+}
+
+// HealthChecker represents a readiness health checks registry.
+//
+// The backend stores(conf.Backend.Db and conf.Backend.Cache) are checked by default,
+// apps can be register custom checks by ServerState.HealthChecker().Register(...)
+type HealthChecker struct {
+	locker sync.Mutex
+	checks []healthCheck
+}
+
+func newHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		checks: make([]healthCheck, 0, 4),
+	}
+}
+
+// Register register a custom health check into the readiness checks.
+func (h *HealthChecker) Register(name string, handler HealthCheckHandler) {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	for i := 0; i < len(h.checks); i++ {
+		if h.checks[i].name == name {
+			h.checks[i].handler = handler
+			return
+		}
+	}
+	h.checks = append(h.checks, healthCheck{
+		name:    name,
+		handler: handler,
+	})
+}
+
+func (h *HealthChecker) all(state *ServerState) []healthCheck {
+	var checks []healthCheck
+	cnf := state.ApplicationConfig()
+	for _, db := range cnf.Backend.Db {
+		name := db.Name
+		checks = append(checks, healthCheck{
+			name: fmt.Sprintf("db:%s", name),
+			handler: func(ctx context.Context, state *ServerState) error {
+				sqlDb, err := state.Store().GetDbStoreByName(name).DB()
+				if err != nil {
+					return err
+				}
+				return sqlDb.PingContext(ctx)
+			},
+		})
+	}
+	for _, cache := range cnf.Backend.Cache {
+		name := cache.Name
+		checks = append(checks, healthCheck{
+			name: fmt.Sprintf("cache:%s", name),
+			handler: func(ctx context.Context, state *ServerState) error {
+				return state.Store().GetCacheStoreByName(name).Ping(ctx).Err()
+			},
+		})
+	}
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	checks = append(checks, h.checks...)
+	return checks
+}
+
+// Check run all of the readiness checks concurrently, returns the results and a flag that all checks are passed.
+func (h *HealthChecker) Check(state *ServerState, timeout time.Duration) ([]HealthCheckResult, bool) {
+	checks := h.all(state)
+	results := make([]HealthCheckResult, len(checks))
+	wg := sync.WaitGroup{}
+	wg.Add(len(checks))
+	for i := 0; i < len(checks); i++ {
+		go func(idx int, check healthCheck) {
+			defer wg.Done()
+			results[idx] = runHealthCheck(state, check, timeout)
+		}(i, checks[i])
+	}
+	wg.Wait()
+	var healthy = true
+	for _, r := range results {
+		if r.Status != HealthStatusUp {
+			healthy = false
+		}
+	}
+	return results, healthy
+}
+
+func runHealthCheck(state *ServerState, check healthCheck, timeout time.Duration) (result HealthCheckResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	result.Name = check.name
+	result.Status = HealthStatusUp
+	defer func() {
+		if err := recover(); err != nil {
+			result.Status = HealthStatusDown
+			result.Error = fmt.Sprintf("%v", err)
+		}
+		result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	}()
+	if err := check.handler(ctx, state); err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// gwLiveness is the liveness probe API, It's only tells the server process are alive.
+func gwLiveness(c *gin.Context) {
+	s := getHostServer(c)
+	requestId := getRequestId(s, c)
+	renderResp(c, http.StatusOK, s.RespBodyBuildFunc(0, requestId, nil, gin.H{
+		"Status": HealthStatusUp,
+	}))
+}
+
+// gwReadiness is the readiness probe API, It's checks backend stores and app registered checks.
+// It's always failed when the server not compiled or graceful shutdown is in progress.
+func gwReadiness(c *gin.Context) {
+	s := getHostServer(c)
+	requestId := getRequestId(s, c)
+	var err error
+	if atomic.LoadInt32(&s.isShuttingDown) == 1 {
+		err = ErrServerShuttingDown
+	} else if atomic.LoadInt32(&s.isReady) == 0 {
+		err = ErrServerNotReady
+	}
+	if err != nil {
+		renderResp(c, http.StatusServiceUnavailable, s.RespBodyBuildFunc(http.StatusServiceUnavailable, requestId, errorOf(http.StatusServiceUnavailable, err.Error()), gin.H{
+			"Status": HealthStatusDown,
+		}))
+		return
+	}
+	timeout := healthDefaultTimeout
+	if t := s.Config().Service.Health.Timeout; t > 0 {
+		timeout = time.Duration(t) * time.Millisecond
+	}
+	results, healthy := s.HealthChecker.Check(s.State(), timeout)
+	if !healthy {
+		renderResp(c, http.StatusServiceUnavailable, s.RespBodyBuildFunc(http.StatusServiceUnavailable, requestId, errorOf(http.StatusServiceUnavailable, "unhealthy"), gin.H{
+			"Status": HealthStatusDown,
+			"Checks": results,
+		}))
+		return
+	}
+	renderResp(c, http.StatusOK, s.RespBodyBuildFunc(0, requestId, nil, gin.H{
+		"Status": HealthStatusUp,
+		"Checks": results,
+	}))
+}
@@ -0,0 +1,81 @@
+package gw_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func newHealthServer(t *testing.T, check gw.HealthCheckHandler) *gwtest.Server {
+	return gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Service.Health.Enabled = true
+			cnf.Service.Health.Liveness = "/healthz"
+			cnf.Service.Health.Readiness = "/readyz"
+			return cnf
+		}
+	}, &gwtest.App{
+		OnStartFunc: func(state *gw.ServerState) {
+			state.HealthChecker().Register("app", check)
+		},
+	})
+}
+
+func TestServer_Health(t *testing.T) {
+	var appErr error
+	server := newHealthServer(t, func(ctx context.Context, state *gw.ServerState) error {
+		return appErr
+	})
+	client := server.Client()
+
+	// the server that served by Handler(...) are ready after compiled.
+	client.Get("/healthz").AssertOK()
+	var out struct {
+		Status string
+		Checks []gw.HealthCheckResult
+	}
+	client.Get("/readyz").AssertOK().DecodePayload(&out)
+	assert.Equal(t, gw.HealthStatusUp, out.Status)
+	assert.NotEmpty(t, out.Checks)
+
+	appErr = fmt.Errorf("app down")
+	resp := client.Get("/readyz").AssertError(http.StatusServiceUnavailable, "unhealthy")
+	assert.Equal(t, gw.ErrCodeServiceUnavailable, resp.Envelope().Code)
+	resp.DecodePayload(&out)
+	assert.Equal(t, gw.HealthStatusDown, out.Status)
+	var failed []string
+	for _, c := range out.Checks {
+		if c.Status == gw.HealthStatusDown {
+			failed = append(failed, c.Name)
+		}
+	}
+	assert.Equal(t, []string{"app"}, failed)
+	// the liveness are not affected by the checks.
+	client.Get("/healthz").AssertOK()
+}
+
+func TestServer_Health_ShuttingDown(t *testing.T) {
+	server := newHealthServer(t, func(ctx context.Context, state *gw.ServerState) error {
+		return nil
+	})
+	client := server.Client()
+	client.Get("/readyz").AssertOK()
+
+	done := make(chan struct{})
+	server.RegisterShutDownHandler(func(s *gw.HostServer) error {
+		defer close(done)
+		// the readiness are failed while the graceful shutdown is in progress, the liveness are not.
+		resp := client.Get("/readyz").AssertError(http.StatusServiceUnavailable, gw.ErrServerShuttingDown.Error())
+		assert.Equal(t, gw.ErrCodeServiceUnavailable, resp.Envelope().Code)
+		client.Get("/healthz").AssertOK()
+		return nil
+	})
+	server.ShutDown()
+	<-done
+}
@@ -2,6 +2,18 @@ package gw
 
 import (
 	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// The names of the built-in hooks, they are configured by the app.yaml,
+// and can be replaced/deleted by HostServer.ReplaceHook(...)/HostServer.DeleteHook(...).
+const (
+	HookCors            = "gw-cors"
+	HookSecurityHeaders = "gw-security-headers"
+	HookBodyLimit       = "gw-body-limit"
+	HookCompression     = "gw-compression"
+	HookMetrics         = "gw-metrics"
+	HookTracing         = "gw-tracing"
 )
 
 // Hook represents a global gin engine http Middleware.
@@ -26,3 +38,24 @@ func NewHook(name string, before gin.HandlerFunc, after gin.HandlerFunc) *Hook {
 		OnAfter:  after,
 	}
 }
+
+// newBuiltinHooks returns the built-in hooks of the server, the hooks read the server config per request,
+// so the disabled hooks are no-op, and the reloaded config are take effect at once.
+func newBuiltinHooks(s *HostServer) []*Hook {
+	return []*Hook{
+		newMetricsHook(s),
+		newTracingHook(s),
+		newCompressionHook(s),
+		newSecurityHeadersHook(s),
+		newCorsHook(s),
+		newBodyLimitHook(s),
+	}
+}
+
+// matchPath returns true if the path matches the pattern, the pattern can be has * suffix(prefix match).
+func matchPath(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == path
+}
@@ -0,0 +1,64 @@
+package gw
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrRequestEntityTooLarge = NewError(http.StatusRequestEntityTooLarge, ErrCodeRequestEntityTooLarge, "request entity too large")
+)
+
+// newBodyLimitHook returns the built-in request body size limit hook, it's configured by security.limit.body of the app.yaml.
+//
+// The first rule that matches the request path are used, security.limit.body.maxSize are used if no rule matched, 0 means no limit.
+// The requests that Content-Length greater than the limit are responded by 413,
+// the reads of the body are failed with ErrRequestEntityTooLarge if the body(such as chunked) exceeds the limit.
+func newBodyLimitHook(s *HostServer) *Hook {
+	return NewBeforeHook(HookBodyLimit, func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			return
+		}
+		cnf := s.Config().Security.Limit.Body
+		maxSize := cnf.MaxSize
+		for _, r := range cnf.Rules {
+			if matchPath(r.Path, c.Request.URL.Path) {
+				maxSize = r.MaxSize
+				break
+			}
+		}
+		if maxSize <= 0 {
+			return
+		}
+		if c.Request.ContentLength > maxSize {
+			respErr(c, getRequestId(s, c), 0, ErrRequestEntityTooLarge, nil)
+			c.Abort()
+			return
+		}
+		c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, remaining: maxSize}
+	})
+}
+
+// limitedBody represents a request body that fails with ErrRequestEntityTooLarge if it's read more than remaining bytes.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrRequestEntityTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = -1
+		return n, ErrRequestEntityTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
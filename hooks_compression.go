@@ -0,0 +1,272 @@
+package gw
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/logger"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	compressWriterKey = "gw-compress-writer"
+)
+
+var (
+	defaultCompressionEncodings = []string{"gzip"}
+	defaultCompressionMimeTypes = []string{
+		"application/json", "application/problem+json", "application/xml", "application/x-yaml",
+		"application/javascript", "text/html", "text/plain", "text/css", "text/xml",
+	}
+	compressorsLocker sync.RWMutex
+	compressors       = map[string]CompressorFunc{
+		"gzip": gzipCompressor,
+	}
+	gzipWriterPools [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+)
+
+// CompressorFunc returns a encoder that compresses the data into w,
+// level is the settings.compression.level of the app.yaml, 0 means the default level of the encoding.
+type CompressorFunc func(w io.Writer, level int) (io.WriteCloser, error)
+
+// RegisterCompressor registers the encoder of a content encoding(such as br), the registered encoder are replaced.
+// The gzip encoder are built-in, the other encodings of settings.compression.encodings should be registered in the init stage.
+//
+// Usage(brotli):
+//
+//	gw.RegisterCompressor("br", func(w io.Writer, level int) (io.WriteCloser, error) {
+//		return brotli.NewWriterLevel(w, level), nil
+//	})
+func RegisterCompressor(encoding string, fn CompressorFunc) {
+	compressorsLocker.Lock()
+	defer compressorsLocker.Unlock()
+	compressors[strings.ToLower(encoding)] = fn
+}
+
+func compressorOf(encoding string) (CompressorFunc, bool) {
+	compressorsLocker.RLock()
+	defer compressorsLocker.RUnlock()
+	fn, ok := compressors[strings.ToLower(encoding)]
+	return fn, ok
+}
+
+// pooledGzipWriter represents a gzip.Writer that put back to the pool after closed.
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (w *pooledGzipWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	return err
+}
+
+func gzipCompressor(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return gzip.NewWriterLevel(w, level)
+	}
+	pool := &gzipWriterPools[level-gzip.HuffmanOnly]
+	if gw, ok := pool.Get().(*gzip.Writer); ok {
+		gw.Reset(w)
+		return &pooledGzipWriter{Writer: gw, pool: pool}, nil
+	}
+	gw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledGzipWriter{Writer: gw, pool: pool}, nil
+}
+
+// newCompressionHook returns the built-in response compression hook, it's configured by settings.compression of the app.yaml.
+//
+// The encoding are picked by the settings.compression.encodings order that the request accepted(Accept-Encoding),
+// the responses that smaller than minSize, has a Content-Encoding or the media type not in mimeTypes are not compressed.
+func newCompressionHook(s *HostServer) *Hook {
+	return NewHook(HookCompression, func(c *gin.Context) {
+		cnf := s.Config().Settings.Compression
+		if !cnf.Enabled || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encodings := cnf.Encodings
+		if len(encodings) == 0 {
+			encodings = defaultCompressionEncodings
+		}
+		encoding, fn := negotiateEncoding(encodings, c.GetHeader("Accept-Encoding"))
+		if fn == nil {
+			return
+		}
+		mimeTypes := cnf.MimeTypes
+		if len(mimeTypes) == 0 {
+			mimeTypes = defaultCompressionMimeTypes
+		}
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			compressor:     fn,
+			level:          cnf.Level,
+			minSize:        cnf.MinSize,
+			mimeTypes:      mimeTypes,
+		}
+		c.Writer = w
+		c.Set(compressWriterKey, w)
+	}, func(c *gin.Context) {
+		// the c.Writer may be wrapped by the decorators.
+		if w, ok := c.Get(compressWriterKey); ok {
+			w.(*compressWriter).close()
+		}
+	})
+}
+
+// negotiateEncoding returns the first encoding of the encodings that registered and accepted by the Accept-Encoding.
+func negotiateEncoding(encodings []string, acceptEncoding string) (string, CompressorFunc) {
+	ranges := parseAccept(acceptEncoding)
+	for _, encoding := range encodings {
+		fn, ok := compressorOf(encoding)
+		if !ok {
+			continue
+		}
+		for _, r := range ranges {
+			if r.mimeType == "*" || r.mimeType == strings.ToLower(encoding) {
+				return encoding, fn
+			}
+		}
+	}
+	return "", nil
+}
+
+// compressWriter represents a gin.ResponseWriter that compresses the response body,
+// the body are buffered until the size reached minSize(or flushed), then it's decided to compress or not.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding      string
+	compressor    CompressorFunc
+	level         int
+	minSize       int
+	mimeTypes     []string
+	buf           bytes.Buffer
+	encoder       io.WriteCloser
+	decided       bool
+	headerWritten bool
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.headerWritten = true
+}
+
+// Size returns the number of the body bytes that written to the client(the compressed size if it's compressed),
+// the buffered body that not decided are counted by it's uncompressed size.
+func (w *compressWriter) Size() int {
+	if !w.decided && w.buf.Len() > 0 {
+		if size := w.ResponseWriter.Size(); size > 0 {
+			return size + w.buf.Len()
+		}
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.headerWritten || w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	status := w.Status()
+	if w.buf.Len() == 0 || header.Get("Content-Encoding") != "" ||
+		status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(header.Get("Content-Type"), ";")[0]))
+	for _, pattern := range w.mimeTypes {
+		if matchPath(strings.ToLower(pattern), mimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// decide decides to compress the response or not, and writes the buffered body.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	if w.buf.Len() >= w.minSize && w.compressible() {
+		encoder, err := w.compressor(w.ResponseWriter, w.level)
+		if err != nil {
+			logger.Error("create %s encoder fail, the response are not compressed, err: %v", w.encoding, err)
+		} else {
+			header := w.Header()
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			w.encoder = encoder
+		}
+	}
+	if w.buf.Len() == 0 {
+		if w.headerWritten {
+			w.ResponseWriter.WriteHeaderNow()
+		}
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// close writes the buffered body, and closes the encoder.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			logger.Error("write compressed response fail, err: %v", err)
+		}
+	}
+	if w.encoder != nil {
+		if err := w.encoder.Close(); err != nil {
+			logger.Error("close %s encoder fail, err: %v", w.encoding, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package gw
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/conf"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var defaultCorsAllowMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// newCorsHook returns the built-in CORS hook, it's configured by security.cors of the app.yaml.
+//
+// The first policy that matches the request path are used, the preflight requests are responded by 204(allowed) or 403,
+// the actual requests of the not allowed origins are processed without the CORS headers(the browsers block it).
+func newCorsHook(s *HostServer) *Hook {
+	return NewBeforeHook(HookCors, func(c *gin.Context) {
+		cnf := s.Config().Security.Cors
+		origin := c.GetHeader("Origin")
+		if !cnf.Enabled || origin == "" {
+			return
+		}
+		policy, ok := corsPolicyOf(cnf.Policies, c.Request.URL.Path)
+		if !ok {
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		allowed := corsAllowOrigin(policy, origin)
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !preflight {
+			if allowed {
+				setCorsOriginHeaders(c, policy, origin)
+				if len(policy.ExposeHeaders) > 0 {
+					c.Header("Access-Control-Expose-Headers", strings.Join(policy.ExposeHeaders, ", "))
+				}
+			}
+			return
+		}
+		c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+		c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		methods := policy.AllowMethods
+		if len(methods) == 0 {
+			methods = defaultCorsAllowMethods
+		}
+		method := c.GetHeader("Access-Control-Request-Method")
+		if !allowed || !corsContains(methods, method) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		setCorsOriginHeaders(c, policy, origin)
+		c.Header("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(policy.AllowHeaders) > 0 && !corsContains(policy.AllowHeaders, "*") {
+			c.Header("Access-Control-Allow-Headers", strings.Join(policy.AllowHeaders, ", "))
+		} else if headers := c.GetHeader("Access-Control-Request-Headers"); headers != "" {
+			c.Header("Access-Control-Allow-Headers", headers)
+		}
+		if policy.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+}
+
+func corsPolicyOf(policies []conf.CorsPolicy, path string) (conf.CorsPolicy, bool) {
+	for _, p := range policies {
+		if len(p.Paths) == 0 {
+			return p, true
+		}
+		for _, pattern := range p.Paths {
+			if matchPath(pattern, path) {
+				return p, true
+			}
+		}
+	}
+	return conf.CorsPolicy{}, false
+}
+
+// corsAllowOrigin returns true if the origin matches "*", the origin or a wildcard subdomain origin(https://*.gw.com).
+func corsAllowOrigin(policy conf.CorsPolicy, origin string) bool {
+	for _, o := range policy.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+		if idx := strings.Index(o, "://*."); idx > 0 {
+			scheme, domain := o[:idx+3], o[idx+4:]
+			if len(origin) > len(scheme)+len(domain) && strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)) &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func setCorsOriginHeaders(c *gin.Context, policy conf.CorsPolicy, origin string) {
+	if !policy.AllowCredentials && corsContains(policy.AllowOrigins, "*") {
+		c.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+	}
+	if policy.AllowCredentials {
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func corsContains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package gw
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// newSecurityHeadersHook returns the built-in security headers hook, it's configured by security.headers of the app.yaml.
+//
+// The empty items are not responded, the Strict-Transport-Security are responded for the https requests only
+// (TLS, or X-Forwarded-Proto: https of the reverse proxy).
+func newSecurityHeadersHook(s *HostServer) *Hook {
+	return NewBeforeHook(HookSecurityHeaders, func(c *gin.Context) {
+		cnf := s.Config().Security.Headers
+		if !cnf.Enabled {
+			return
+		}
+		if cnf.HSTS != "" && (c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")) {
+			c.Header("Strict-Transport-Security", cnf.HSTS)
+		}
+		if cnf.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", cnf.ContentSecurityPolicy)
+		}
+		if cnf.FrameOptions != "" {
+			c.Header("X-Frame-Options", cnf.FrameOptions)
+		}
+		if cnf.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", cnf.ReferrerPolicy)
+		}
+		if cnf.ContentTypeOptions != "" {
+			c.Header("X-Content-Type-Options", cnf.ContentTypeOptions)
+		}
+	})
+}
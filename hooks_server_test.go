@@ -0,0 +1,115 @@
+package gw_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func hooksHello(c *gw.Context) {
+	c.JSON200("hello")
+}
+
+func hooksEcho(c *gw.Context) {
+	var in struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if c.Bind(&in) != nil {
+		return
+	}
+	c.JSON200(in.Name)
+}
+
+func TestServer_Hooks(t *testing.T) {
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Security.Cors.Enabled = true
+			cnf.Security.Cors.Policies = []conf.CorsPolicy{{
+				AllowOrigins:     []string{"https://*.gw-framework.com"},
+				AllowHeaders:     []string{"Authorization", "Content-Type"},
+				ExposeHeaders:    []string{"X-Request-Id"},
+				AllowCredentials: true,
+				MaxAge:           600,
+			}}
+			cnf.Security.Headers.Enabled = true
+			cnf.Security.Headers.HSTS = "max-age=31536000"
+			cnf.Security.Headers.FrameOptions = "DENY"
+			cnf.Security.Headers.ContentTypeOptions = "nosniff"
+			cnf.Security.Limit.Body.MaxSize = 4096
+			cnf.Settings.Compression.Enabled = true
+			cnf.Settings.Compression.MinSize = 1024
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("hello", hooksHello)
+			router.POST("echo", hooksEcho)
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	hello := client.Router("hooksHello").UrlPath
+
+	// CORS
+	resp := client.WithHeader("Origin", "https://app.gw-framework.com").
+		WithHeader("Access-Control-Request-Method", http.MethodGet).
+		WithHeader("Access-Control-Request-Headers", "Authorization").
+		Do(http.MethodOptions, hello, nil).AssertStatus(http.StatusNoContent)
+	assert.Equal(t, "https://app.gw-framework.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Authorization, Content-Type", resp.Header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	client.WithHeader("Origin", "https://evil.com").
+		WithHeader("Access-Control-Request-Method", http.MethodGet).
+		Do(http.MethodOptions, hello, nil).AssertStatus(http.StatusForbidden)
+	resp = client.WithHeader("Origin", "https://app.gw-framework.com").Call("hooksHello", nil, nil).AssertOK()
+	assert.Equal(t, "https://app.gw-framework.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id", resp.Header.Get("Access-Control-Expose-Headers"))
+	assert.Contains(t, resp.Header.Values("Vary"), "Origin")
+	resp = client.WithHeader("Origin", "https://evil.com").Call("hooksHello", nil, nil).AssertOK()
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// security headers
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Empty(t, resp.Header.Get("Strict-Transport-Security"))
+	resp = client.WithHeader("X-Forwarded-Proto", "https").Call("hooksHello", nil, nil).AssertOK()
+	assert.Equal(t, "max-age=31536000", resp.Header.Get("Strict-Transport-Security"))
+
+	// body limit
+	large := strings.Repeat("x", 8192)
+	resp = client.Call("hooksEcho", nil, gin.H{"name": large}).
+		AssertError(http.StatusRequestEntityTooLarge, gw.ErrRequestEntityTooLarge.Error())
+	assert.Equal(t, gw.ErrCodeRequestEntityTooLarge, resp.Envelope().Code)
+	// the chunked body(without Content-Length).
+	body, _ := json.Marshal(gin.H{"name": large})
+	client.WithHeader("Content-Type", "application/json").Call("hooksEcho", nil, bufio.NewReader(bytes.NewReader(body))).
+		AssertError(http.StatusRequestEntityTooLarge, gw.ErrRequestEntityTooLarge.Error())
+
+	// compression, the http.Client decompress the response transparently if the Accept-Encoding are not specified.
+	name := strings.Repeat("gw", 1024)
+	resp = client.Call("hooksEcho", nil, gin.H{"name": name}).AssertOK()
+	assert.True(t, resp.Uncompressed)
+	var out string
+	resp.DecodePayload(&out)
+	assert.Equal(t, name, out)
+	resp = client.WithHeader("Accept-Encoding", "gzip").Call("hooksHello", nil, nil).AssertOK()
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Contains(t, resp.Header.Values("Vary"), "Accept-Encoding")
+	resp = client.WithHeader("Accept-Encoding", "gzip").Call("hooksEcho", nil, gin.H{"name": name + "x"})
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	r, err := gzip.NewReader(bytes.NewReader(resp.Body))
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(r)
+	assert.Contains(t, string(b), name+"x")
+}
@@ -0,0 +1,144 @@
+package gw
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw/conf"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHostServer_AddHook(t *testing.T) {
+	s := &HostServer{}
+	s.AddHook(NewBeforeHook("a", func(c *gin.Context) {}), NewBeforeHook("b", func(c *gin.Context) {}))
+	assert.Len(t, s.hooks, 2)
+	after := NewAfterHook("a", func(c *gin.Context) {})
+	s.AddHook(after)
+	assert.Len(t, s.hooks, 2)
+	assert.Equal(t, after, s.hooks[0])
+	s.DeleteHook("b")
+	assert.Len(t, s.hooks, 1)
+	s.ReplaceHook("a", NewBeforeHook("a2", func(c *gin.Context) {}))
+	assert.Equal(t, "a2", s.hooks[0].Name)
+	prepareHooks(s)
+	assert.Len(t, s.beforeHooks, 1)
+	assert.Equal(t, -1, s.afterHookMaxIdx)
+}
+
+func TestMatchPath(t *testing.T) {
+	assert.True(t, matchPath("/api/v1/*", "/api/v1/users"))
+	assert.True(t, matchPath("/api/v1/users", "/api/v1/users"))
+	assert.False(t, matchPath("/api/v1/users", "/api/v1/users/1"))
+	assert.False(t, matchPath("/api/v2/*", "/api/v1/users"))
+}
+
+func TestCorsAllowOrigin(t *testing.T) {
+	policy := conf.CorsPolicy{AllowOrigins: []string{"https://gw.com", "https://*.gw-framework.com"}}
+	assert.True(t, corsAllowOrigin(policy, "https://gw.com"))
+	assert.True(t, corsAllowOrigin(policy, "HTTPS://GW.COM"))
+	assert.True(t, corsAllowOrigin(policy, "https://app.gw-framework.com"))
+	assert.False(t, corsAllowOrigin(policy, "https://.gw-framework.com"))
+	assert.False(t, corsAllowOrigin(policy, "http://app.gw-framework.com"))
+	assert.False(t, corsAllowOrigin(policy, "https://evilgw-framework.com"))
+	assert.False(t, corsAllowOrigin(policy, "https://gw.com.evil.com"))
+	assert.True(t, corsAllowOrigin(conf.CorsPolicy{AllowOrigins: []string{"*"}}, "https://any.com"))
+
+	policies := []conf.CorsPolicy{{Paths: []string{"/api/v1/public/*"}, MaxAge: 1}, {MaxAge: 2}}
+	p, ok := corsPolicyOf(policies, "/api/v1/public/x")
+	assert.True(t, ok)
+	assert.Equal(t, 1, p.MaxAge)
+	p, _ = corsPolicyOf(policies, "/api/v1/users")
+	assert.Equal(t, 2, p.MaxAge)
+	_, ok = corsPolicyOf(policies[:1], "/api/v1/users")
+	assert.False(t, ok)
+}
+
+func TestLimitedBody(t *testing.T) {
+	body := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("12345")), remaining: 5}
+	b, err := ioutil.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "12345", string(b))
+
+	body = &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("123456")), remaining: 5}
+	b, err = ioutil.ReadAll(body)
+	assert.Equal(t, ErrRequestEntityTooLarge, err)
+	assert.Equal(t, "12345", string(b))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	RegisterCompressor("test-br", gzipCompressor)
+	defer func() {
+		compressorsLocker.Lock()
+		delete(compressors, "test-br")
+		compressorsLocker.Unlock()
+	}()
+	encoding, fn := negotiateEncoding([]string{"test-br", "gzip"}, "gzip, deflate, test-br")
+	assert.Equal(t, "test-br", encoding)
+	assert.NotNil(t, fn)
+	encoding, _ = negotiateEncoding([]string{"br", "gzip"}, "gzip;q=0.5, br")
+	assert.Equal(t, "gzip", encoding)
+	encoding, _ = negotiateEncoding([]string{"gzip"}, "*")
+	assert.Equal(t, "gzip", encoding)
+	_, fn = negotiateEncoding([]string{"gzip"}, "gzip;q=0, deflate")
+	assert.Nil(t, fn)
+	_, fn = negotiateEncoding([]string{"gzip"}, "")
+	assert.Nil(t, fn)
+}
+
+func TestCompressWriter(t *testing.T) {
+	var size int
+	write := func(contentType, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       "gzip",
+			compressor:     gzipCompressor,
+			minSize:        16,
+			mimeTypes:      []string{"application/json", "text/*"},
+		}
+		c.Writer = w
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Length", "0")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString(body)
+		w.close()
+		size = c.Writer.Size()
+		return rec
+	}
+	large := strings.Repeat(`{"name":"gw"}`, 10)
+	rec := write("application/json; charset=utf-8", large)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+	r, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, large, string(b))
+	// the size are the compressed size.
+	assert.Equal(t, rec.Body.Len(), size)
+	assert.Less(t, size, len(large))
+
+	rec = write("application/json", `{"name":"gw"}`)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"name":"gw"}`, rec.Body.String())
+	assert.Equal(t, rec.Body.Len(), size)
+
+	rec = write("image/png", large)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, rec.Body.String())
+
+	rec = write("text/plain", large)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	// the buffered body are counted before it's decided.
+	rec = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	w := &compressWriter{ResponseWriter: c.Writer, encoding: "gzip", compressor: gzipCompressor, minSize: 1024}
+	_, _ = w.WriteString(`{"name":"gw"}`)
+	assert.Equal(t, len(`{"name":"gw"}`), w.Size())
+}
@@ -0,0 +1,350 @@
+package gw
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// SourceLocale is the locale of the message keys, the keys are used as messages if the locale has no catalog.
+	SourceLocale = "en"
+	gwLocaleKey  = "gw-locale"
+)
+
+// builtinCatalogs are the translations of gw framework messages(the default English messages are the keys).
+var builtinCatalogs = map[string]map[string]string{
+	"zh-CN": {
+		// http status messages.
+		"Bad Request":           "请求参数错误",
+		"Unauthorized":          "未授权",
+		"Access Denied":         "拒绝访问",
+		"Not Found":             "资源不存在",
+		"Not Acceptable":        "不支持的响应格式",
+		"Internal Server Error": "服务器内部错误",
+		"Service Unavailable":   "服务不可用",
+		"Gateway Timeout":       "网关超时",
+		// gw errors.
+		"has no credentitals":          "缺少身份凭证",
+		"bad request":                  "请求参数错误",
+		"not found":                    "资源不存在",
+		"not acceptable":               "不支持的响应格式",
+		"permission denied":            "没有权限",
+		"server internal error":        "服务器内部错误",
+		"request canceled":             "请求已取消",
+		"request timeout":              "请求超时",
+		"validation failed":            "参数校验失败",
+		"too many requests":            "请求过于频繁，请稍后再试",
+		"precondition failed":          "资源已被修改，请刷新后重试",
+		"request entity too large":     "请求体过大",
+		"Permission Denied, need:(%s)": "没有权限，需要:(%s)",
+		"Create session ID fail.":      "创建会话ID失败",
+		"Save session fail.":           "保存会话失败",
+		// idempotency.
+		"invalid idempotency key":                                  "无效的幂等键",
+		"the idempotency key has been used by a different request": "幂等键已被其他请求使用",
+		"a request with the same idempotency key is in progress":   "相同幂等键的请求正在处理中",
+		// validation rules.
+		"is required":                         "不能为空",
+		"must be a valid email address":       "必须是有效的邮箱地址",
+		"must be a valid URL":                 "必须是有效的URL",
+		"must be at least %s":                 "不能小于%s",
+		"must be at most %s":                  "不能大于%s",
+		"length must be %s":                   "长度必须为%s",
+		"must be greater than %s":             "必须大于%s",
+		"must be greater than or equal to %s": "必须大于或等于%s",
+		"must be less than %s":                "必须小于%s",
+		"must be less than or equal to %s":    "必须小于或等于%s",
+		"must be one of [%s]":                 "必须是[%s]中的一个",
+		"must be equal to %s":                 "必须等于%s",
+		"must not be equal to %s":             "不能等于%s",
+		"already exists":                      "已存在",
+		"does not exist":                      "不存在",
+		"failed on the '%s' rule":             "未通过'%s'规则校验",
+	},
+}
+
+// Translator represents the message catalogs of locales.
+//
+// The message keys are the source(English) messages or the app defined keys, such as users.not_found,
+// the message can be has fmt verbs of the args.
+type Translator struct {
+	locker        sync.RWMutex
+	defaultLocale string
+	locales       []string
+	catalogs      map[string]map[string]string
+}
+
+// NewTranslator returns a Translator that has the gw framework catalogs, the default locale is SourceLocale.
+func NewTranslator() *Translator {
+	t := &Translator{
+		defaultLocale: SourceLocale,
+		locales:       []string{SourceLocale},
+		catalogs:      make(map[string]map[string]string),
+	}
+	// the builtin catalogs are added in sorted order, so the fallback of catalogOf are deterministic.
+	locales := make([]string, 0, len(builtinCatalogs))
+	for locale := range builtinCatalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	for _, locale := range locales {
+		t.Add(locale, builtinCatalogs[locale])
+	}
+	return t
+}
+
+// Add adds the messages into the catalog of locale, the exists messages are overrides.
+func (t *Translator) Add(locale string, messages map[string]string) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	name := strings.ToLower(locale)
+	catalog, ok := t.catalogs[name]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		t.catalogs[name] = catalog
+		if t.indexOf(locale) < 0 {
+			t.locales = append(t.locales, locale)
+		}
+	}
+	for k, v := range messages {
+		catalog[k] = v
+	}
+}
+
+// Load loads the catalog files(<locale>.yaml, <locale>.yml or <locale>.json) of the dir,
+// the nested keys are joined by ".". It's returns nil if the dir not exists.
+func (t *Translator) Load(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return err
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(b, &values); err != nil {
+			return fmt.Errorf("parse catalog file: %s, err: %v", f.Name(), err)
+		}
+		var messages = make(map[string]string)
+		flattenMessages("", values, messages)
+		t.Add(strings.TrimSuffix(f.Name(), ext), messages)
+	}
+	return nil
+}
+
+func flattenMessages(prefix string, values map[string]interface{}, messages map[string]string) {
+	for k, v := range values {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			flattenMessages(k, m, messages)
+			continue
+		}
+		messages[k] = fmt.Sprintf("%v", v)
+	}
+}
+
+// SetDefaultLocale sets the default locale, it's used if the request locale can not be resolved.
+func (t *Translator) SetDefaultLocale(locale string) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	t.defaultLocale = locale
+	if t.indexOf(locale) < 0 {
+		t.locales = append(t.locales, locale)
+	}
+}
+
+// DefaultLocale returns the default locale.
+func (t *Translator) DefaultLocale() string {
+	t.locker.RLock()
+	defer t.locker.RUnlock()
+	return t.defaultLocale
+}
+
+// Locales returns the supported locales.
+func (t *Translator) Locales() []string {
+	t.locker.RLock()
+	defer t.locker.RUnlock()
+	locales := make([]string, len(t.locales))
+	copy(locales, t.locales)
+	sort.Strings(locales)
+	return locales
+}
+
+// Match returns the best supported locale of the language ranges(such as Accept-Language: zh-CN,zh;q=0.9,en;q=0.8),
+// a language matches the locales that has same language(zh matches zh-CN). It's returns "" if no locale matched.
+func (t *Translator) Match(languages string) string {
+	t.locker.RLock()
+	defer t.locker.RUnlock()
+	for _, r := range parseAccept(strings.ReplaceAll(languages, "_", "-")) {
+		if r.mimeType == "*" {
+			return t.defaultLocale
+		}
+		if idx := t.indexOf(r.mimeType); idx >= 0 {
+			return t.locales[idx]
+		}
+		lang := baseLanguage(r.mimeType)
+		for _, locale := range t.locales {
+			if baseLanguage(strings.ToLower(locale)) == lang {
+				return locale
+			}
+		}
+	}
+	return ""
+}
+
+// T returns the message of the key that translated into locale, the key are returned if it's has no translation.
+func (t *Translator) T(locale, key string, args ...interface{}) string {
+	msg, ok := t.Lookup(locale, key)
+	if !ok {
+		msg = key
+	}
+	return formatMessage(msg, args)
+}
+
+// formatMessage formats the message by args, the args are not a variadic param,
+// so the message keys(such as users.not_found) are not checked as printf format by go vet.
+func formatMessage(msg string, args []interface{}) string {
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lookup returns the message of the key in the catalog of locale(or it's language),
+// the default locale's catalog are used if the language has no catalog.
+func (t *Translator) Lookup(locale, key string) (string, bool) {
+	t.locker.RLock()
+	defer t.locker.RUnlock()
+	catalog, ok := t.catalogOf(locale)
+	if !ok {
+		if baseLanguage(strings.ToLower(locale)) == SourceLocale {
+			return "", false
+		}
+		catalog, ok = t.catalogOf(t.defaultLocale)
+	}
+	if !ok {
+		return "", false
+	}
+	msg, ok := catalog[key]
+	return msg, ok
+}
+
+func (t *Translator) catalogOf(locale string) (map[string]string, bool) {
+	name := strings.ToLower(locale)
+	if catalog, ok := t.catalogs[name]; ok {
+		return catalog, true
+	}
+	lang := baseLanguage(name)
+	if catalog, ok := t.catalogs[lang]; ok {
+		return catalog, true
+	}
+	// the first added locale of the language are used, such as zh-CN for zh-HK if zh-CN added before zh-TW.
+	for _, l := range t.locales {
+		n := strings.ToLower(l)
+		if baseLanguage(n) != lang {
+			continue
+		}
+		if catalog, ok := t.catalogs[n]; ok {
+			return catalog, true
+		}
+	}
+	return nil, false
+}
+
+func (t *Translator) indexOf(locale string) int {
+	for i, l := range t.locales {
+		if strings.EqualFold(l, locale) {
+			return i
+		}
+	}
+	return -1
+}
+
+func baseLanguage(locale string) string {
+	if idx := strings.IndexAny(locale, "-_"); idx > 0 {
+		return locale[:idx]
+	}
+	return locale
+}
+
+// loadCatalogs loads the catalog files of Settings.I18n.Dir, and the apps catalog files of {Dir}/{App.Name()}.
+func loadCatalogs(s *HostServer) {
+	dir := s.Config().Settings.I18n.Dir
+	if dir == "" {
+		return
+	}
+	if err := s.Translator.Load(dir); err != nil {
+		panic(fmt.Sprintf("load i18n catalogs: %s fail, err: %v", dir, err))
+	}
+	for _, app := range s.sortedApps {
+		appDir := filepath.Join(dir, app.instance.Name())
+		if err := s.Translator.Load(appDir); err != nil {
+			panic(fmt.Sprintf("load app: %s i18n catalogs: %s fail, err: %v", app.instance.Name(), appDir, err))
+		}
+	}
+}
+
+// resolveLocale returns the locale of the request, it's picked by order
+//
+// 1. The user profile(User.Locale).
+//
+// 2. The Accept-Language header.
+//
+// 3. The tenant default(ServerOption.TenantLocaleHandler).
+//
+// 4. The default locale of the Translator.
+func resolveLocale(s *HostServer, c *gin.Context) string {
+	if locale := c.GetString(gwLocaleKey); locale != "" {
+		return locale
+	}
+	var locale string
+	user := getUser(c)
+	if user.Locale != "" {
+		locale = s.Translator.Match(user.Locale)
+	}
+	if locale == "" {
+		locale = s.Translator.Match(c.GetHeader("Accept-Language"))
+	}
+	if locale == "" && user.TenantId > 0 && s.options.TenantLocaleHandler != nil {
+		locale = s.Translator.Match(s.options.TenantLocaleHandler(s.State(), user.TenantId))
+	}
+	if locale == "" {
+		locale = s.Translator.DefaultLocale()
+	}
+	c.Set(gwLocaleKey, locale)
+	return locale
+}
+
+// Locale returns the locale of the request.
+func (c *Context) Locale() string {
+	if c.server == nil {
+		return SourceLocale
+	}
+	return resolveLocale(c.server, c.Context)
+}
+
+// T returns the message of the key that translated into the request locale, args are formatted by fmt.Sprintf.
+func (c *Context) T(key string, args ...interface{}) string {
+	if c == nil || c.server == nil {
+		return formatMessage(key, args)
+	}
+	return c.server.Translator.T(c.Locale(), key, args...)
+}
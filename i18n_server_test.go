@@ -0,0 +1,77 @@
+package gw_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func i18nHello(c *gw.Context) {
+	c.JSON200(c.T("tester.hello", c.User().Passport))
+}
+
+func i18nForbidden(c *gw.Context) {
+	c.JSON500Msg(0, gw.ErrPermissionDenied)
+}
+
+func i18nRegister(c *gw.Context) {
+	var in struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if c.Bind(&in) != nil {
+		return
+	}
+	c.JSON200(in.Name)
+}
+
+func TestServer_I18n(t *testing.T) {
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.TenantLocaleHandler = func(state *gw.ServerState, tenantId uint64) string {
+			if tenantId == 20 {
+				return "zh-CN"
+			}
+			return ""
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("hello", i18nHello)
+			router.GET("forbidden", i18nForbidden)
+			router.POST("register", i18nRegister)
+		},
+		UseFunc: func(option *gw.ServerOption) {
+			option.Translator.Add("en", map[string]string{"tester.hello": "Hello, %s"})
+			option.Translator.Add("zh-CN", map[string]string{"tester.hello": "你好，%s"})
+		},
+	})
+	var msg string
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	client.Call("i18nHello", nil, nil).AssertOK().DecodePayload(&msg)
+	assert.Equal(t, "Hello, gw", msg)
+	client.WithHeader("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8").Call("i18nHello", nil, nil).AssertOK().DecodePayload(&msg)
+	assert.Equal(t, "你好，gw", msg)
+	client.WithHeader("Accept-Language", "zh-TW").Call("i18nHello", nil, nil).AssertOK().DecodePayload(&msg)
+	assert.Equal(t, "你好，gw", msg)
+
+	// user profile > Accept-Language > tenant default.
+	client = server.Client().LoginAs(gw.User{ID: 2, TenantId: 20, Passport: "tenant"})
+	client.Call("i18nHello", nil, nil).AssertOK().DecodePayload(&msg)
+	assert.Equal(t, "你好，tenant", msg)
+	client.WithHeader("Accept-Language", "en-US").Call("i18nHello", nil, nil).AssertOK().DecodePayload(&msg)
+	assert.Equal(t, "Hello, tenant", msg)
+	client = server.Client().LoginAs(gw.User{ID: 3, TenantId: 10, Passport: "user", Locale: "zh-CN"})
+	client.WithHeader("Accept-Language", "en").Call("i18nHello", nil, nil).AssertOK().DecodePayload(&msg)
+	assert.Equal(t, "你好，user", msg)
+
+	// framework messages and validation errors.
+	resp := client.Call("i18nForbidden", nil, nil).AssertError(http.StatusForbidden, "没有权限")
+	assert.Equal(t, "zh-CN", resp.Header.Get("Content-Language"))
+	resp = client.Call("i18nRegister", nil, gin.H{}).AssertStatus(http.StatusBadRequest)
+	var payload struct {
+		Errors []gw.FieldError
+	}
+	resp.DecodePayload(&payload)
+	assert.Equal(t, []gw.FieldError{{Field: "name", Rule: "required", Message: "name 不能为空"}}, payload.Errors)
+}
@@ -0,0 +1,65 @@
+package gw
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslator(t *testing.T) {
+	tr := NewTranslator()
+	tr.Add("zh-CN", map[string]string{"users.not_found": "用户%s不存在"})
+	assert.Equal(t, "zh-CN", tr.Match("zh-CN,zh;q=0.9,en;q=0.8"))
+	assert.Equal(t, "zh-CN", tr.Match("zh"))
+	assert.Equal(t, "en", tr.Match("fr;q=0.9,en-US;q=0.8"))
+	assert.Equal(t, "en", tr.Match("*"))
+	assert.Equal(t, "", tr.Match("fr"))
+	assert.Equal(t, "", tr.Match(""))
+
+	assert.Equal(t, "用户gw不存在", tr.T("zh-CN", "users.not_found", "gw"))
+	assert.Equal(t, "没有权限", tr.T("zh", "permission denied"))
+	assert.Equal(t, "permission denied", tr.T("en-US", "permission denied"))
+	assert.Equal(t, "permission denied", tr.T("fr", "permission denied"))
+	tr.SetDefaultLocale("zh-CN")
+	assert.Equal(t, "没有权限", tr.T("fr", "permission denied"))
+	assert.Equal(t, "permission denied", tr.T("en", "permission denied"))
+	assert.Equal(t, "unknown.key", tr.T("zh-CN", "unknown.key"))
+
+	// the first added locale of the language are used.
+	for i := 0; i < 20; i++ {
+		tr = NewTranslator()
+		tr.Add("zh-TW", map[string]string{"permission denied": "沒有權限"})
+		tr.Add("pt-PT", map[string]string{"hello": "olá"})
+		tr.Add("pt-BR", map[string]string{"hello": "oi"})
+		assert.Equal(t, "没有权限", tr.T("zh-HK", "permission denied"))
+		assert.Equal(t, "olá", tr.T("pt", "hello"))
+	}
+}
+
+func TestTranslator_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gw-i18n")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "zh-CN.yaml"), []byte("users:\n  not_found: 用户不存在\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "ja.json"), []byte(`{"users": {"not_found": "ユーザーが存在しません"}}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# catalogs"), 0644))
+
+	tr := NewTranslator()
+	assert.Nil(t, tr.Load(dir))
+	assert.Nil(t, tr.Load(filepath.Join(dir, "not-exists")))
+	assert.Equal(t, []string{"en", "ja", "zh-CN"}, tr.Locales())
+	assert.Equal(t, "用户不存在", tr.T("zh-CN", "users.not_found"))
+	assert.Equal(t, "ユーザーが存在しません", tr.T("ja-JP", "users.not_found"))
+}
+
+func TestError_localize(t *testing.T) {
+	tr := NewTranslator()
+	tr.Add("zh-CN", map[string]string{"user %s not found": "用户%s不存在"})
+	assert.Equal(t, "没有权限", ErrPermissionDenied.localize(tr, "zh-CN").Error())
+	assert.Equal(t, "permission denied", ErrPermissionDenied.Error())
+	assert.Equal(t, "用户gw不存在", ErrNotFoundRequest.WithMessage("user %s not found", "gw").localize(tr, "zh-CN").Error())
+	assert.Equal(t, "user gw not found", ErrNotFoundRequest.WithMessage("user %s not found", "gw").localize(tr, "en").Error())
+	assert.Equal(t, "custom", ErrBadRequest.WithMessage("custom").localize(tr, "zh-CN").Error())
+}
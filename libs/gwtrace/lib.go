@@ -0,0 +1,167 @@
+// Package gwtrace implements the W3C trace context(https://www.w3.org/TR/trace-context/) propagation,
+// it's used by the gw tracing and the clients(such as sdk/confsvr) that propagate the trace context to the upstream services.
+package gwtrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+	FlagsSampled      = byte(0x01)
+	maxTraceStateLen  = 512
+)
+
+// TraceID represents a 16 bytes trace ID.
+type TraceID [16]byte
+
+// IsValid returns false if the trace ID is all zeros.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID represents a 8 bytes span(parent) ID.
+type SpanID [8]byte
+
+// IsValid returns false if the span ID is all zeros.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// NewTraceID returns a random trace ID.
+func NewTraceID() TraceID {
+	var t TraceID
+	for !t.IsValid() {
+		_, _ = rand.Read(t[:])
+	}
+	return t
+}
+
+// NewSpanID returns a random span ID.
+func NewSpanID() SpanID {
+	var s SpanID
+	for !s.IsValid() {
+		_, _ = rand.Read(s[:])
+	}
+	return s
+}
+
+// SpanContext represents the propagated trace context of a span.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+// IsValid returns true if the trace ID and span ID are valid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled returns true if the sampled flag are set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagsSampled == FlagsSampled
+}
+
+// TraceParent returns the traceparent header value(version 00) of the span context.
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceParent parses a traceparent header value, returns false if it's invalid.
+//
+// The future versions are parsed as version 00(the extra fields are ignored), version ff are invalid.
+func ParseTraceParent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	version, ok := decodeHex(value[0:2])
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(value) != 55) || (len(value) > 55 && value[55] != '-') {
+		return sc, false
+	}
+	traceID, ok := decodeHex(value[3:35])
+	if !ok {
+		return sc, false
+	}
+	spanID, ok := decodeHex(value[36:52])
+	if !ok {
+		return sc, false
+	}
+	flags, ok := decodeHex(value[53:55])
+	if !ok {
+		return sc, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// decodeHex decodes the lowercase hex string.
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// Extract returns the span context of the traceparent/tracestate headers, returns false if the traceparent are invalid.
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, ok := ParseTraceParent(header.Get(TraceParentHeader))
+	if !ok {
+		return sc, false
+	}
+	state := strings.Join(header.Values(TraceStateHeader), ",")
+	if len(state) <= maxTraceStateLen {
+		sc.TraceState = state
+	}
+	return sc, true
+}
+
+// Inject sets the traceparent/tracestate headers by the span context of ctx, nothing to do if ctx has no span context.
+func Inject(ctx context.Context, header http.Header) {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return
+	}
+	header.Set(TraceParentHeader, sc.TraceParent())
+	if sc.TraceState != "" {
+		header.Set(TraceStateHeader, sc.TraceState)
+	} else {
+		header.Del(TraceStateHeader)
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx that carries the span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of ctx, returns false if ctx has no valid span context.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
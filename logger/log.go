@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,6 +21,8 @@ var logPrefix = "GW"
 var logLevel = INFO
 var logDefFormatter = "\n[$prefix-$level] - $time - $msg\n"
 var logFormatter = logDefFormatter
+var contextFieldsLocker sync.RWMutex
+var contextFieldsFuncs []func(ctx context.Context) string
 
 func SetLogPrefix(prefix string) {
 	logPrefix = prefix
@@ -46,6 +50,29 @@ func formatLog(level, format string, a ...interface{}) string {
 	return strings.Replace(s, "$msg", msg, 1)
 }
 
+// RegisterContextFieldsFunc registers a func that returns the fields(such as "traceId=xxx") of a context,
+// the fields are logged by the InfoCtx/ErrorCtx/WarnCtx/DebugCtx APIs.
+func RegisterContextFieldsFunc(fn func(ctx context.Context) string) {
+	contextFieldsLocker.Lock()
+	defer contextFieldsLocker.Unlock()
+	contextFieldsFuncs = append(contextFieldsFuncs, fn)
+}
+
+func formatLogCtx(ctx context.Context, level, format string, a ...interface{}) string {
+	var fields []string
+	contextFieldsLocker.RLock()
+	for _, fn := range contextFieldsFuncs {
+		if f := fn(ctx); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	contextFieldsLocker.RUnlock()
+	if len(fields) == 0 {
+		return formatLog(level, format, a...)
+	}
+	return formatLog(level, "[%s] %s", strings.Join(fields, ", "), fmt.Sprintf(format, a...))
+}
+
 func NewLine(n int) {
 	fmt.Printf(strings.Repeat("\n", n))
 }
@@ -73,3 +100,27 @@ func Debug(format string, a ...interface{}) {
 		fmt.Printf(formatLog("DEBUG", format, a...))
 	}
 }
+
+func InfoCtx(ctx context.Context, format string, a ...interface{}) {
+	if logLevel >= INFO {
+		fmt.Printf(formatLogCtx(ctx, "INFO", format, a...))
+	}
+}
+
+func ErrorCtx(ctx context.Context, format string, a ...interface{}) {
+	if logLevel >= ERROR {
+		fmt.Printf(formatLogCtx(ctx, "ERROR", format, a...))
+	}
+}
+
+func WarnCtx(ctx context.Context, format string, a ...interface{}) {
+	if logLevel >= WARN {
+		fmt.Printf(formatLogCtx(ctx, "WARN", format, a...))
+	}
+}
+
+func DebugCtx(ctx context.Context, format string, a ...interface{}) {
+	if logLevel >= DEBUG {
+		fmt.Printf(formatLogCtx(ctx, "DEBUG", format, a...))
+	}
+}
@@ -1,6 +1,9 @@
 package logger
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestLogAll(t *testing.T) {
 	Info("Info tester")
@@ -26,3 +29,23 @@ func TestLogAll(t *testing.T) {
 	Warn("Warn tester")   // should be not output.
 	Error("Error tester\n")
 }
+
+func TestFormatLogCtx(t *testing.T) {
+	type key struct{}
+	RegisterContextFieldsFunc(func(ctx context.Context) string {
+		if v, ok := ctx.Value(key{}).(string); ok {
+			return "traceId=" + v
+		}
+		return ""
+	})
+	SetLogFormatter("$level $msg")
+	defer ResetLogFormatter()
+	if s := formatLogCtx(context.Background(), "INFO", "hello %s", "gw"); s != "INFO hello gw" {
+		t.Errorf("unexpected log: %s", s)
+	}
+	ctx := context.WithValue(context.Background(), key{}, "abc")
+	if s := formatLogCtx(ctx, "ERROR", "hello %s", "gw"); s != "ERROR [traceId=abc] hello gw" {
+		t.Errorf("unexpected log: %s", s)
+	}
+	InfoCtx(ctx, "Info tester")
+}
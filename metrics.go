@@ -0,0 +1,413 @@
+package gw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	MIMEPrometheusText = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// DefaultMetricsBuckets is the default buckets(seconds) of the latency histograms.
+	DefaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	metricNamePattern     = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	metricLabelPattern    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+const (
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+)
+
+// MetricsRegistry represents a registry of the metrics, it's exposed by the metrics API(service.metrics of the app.yaml)
+// in Prometheus text format.
+//
+// The gw built-in metrics(requests, panics, db, redis and events) are registered by default,
+// apps can be register custom metrics by ServerState.Metrics().
+//
+// Usage:
+//
+//	orders := state.Metrics().NewCounter("myapp_orders_total", "The created orders.", "channel")
+//	orders.Inc("web")
+type MetricsRegistry struct {
+	locker   sync.RWMutex
+	families map[string]metricFamily
+}
+
+type metricFamily interface {
+	kind() string
+	labels() []string
+	write(w *bufio.Writer)
+}
+
+func newMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{
+		families: make(map[string]metricFamily),
+	}
+}
+
+// register returns the registered family that has same name, kind and labels, or registers a new family by create.
+// It's panics if the name are registered as a different metric.
+func (r *MetricsRegistry) register(name, kind string, labelNames []string, create func() metricFamily) metricFamily {
+	if !metricNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("invalid metric name: %s", name))
+	}
+	for _, l := range labelNames {
+		if !metricLabelPattern.MatchString(l) || strings.HasPrefix(l, "__") || l == "le" {
+			panic(fmt.Sprintf("invalid label name: %s of metric: %s", l, name))
+		}
+	}
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.kind() != kind || strings.Join(f.labels(), ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metric: %s are registered as a %s%v", name, f.kind(), f.labels()))
+		}
+		return f
+	}
+	f := create()
+	r.families[name] = f
+	return f
+}
+
+// NewCounter registers a counter, the registered counter are returned if it's registered before.
+func (r *MetricsRegistry) NewCounter(name, help string, labelNames ...string) *Counter {
+	return r.register(name, metricTypeCounter, labelNames, func() metricFamily {
+		return &Counter{vec: newMetricVec(name, help, metricTypeCounter, labelNames, nil)}
+	}).(*Counter)
+}
+
+// NewGauge registers a gauge, the registered gauge are returned if it's registered before.
+func (r *MetricsRegistry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	return r.register(name, metricTypeGauge, labelNames, func() metricFamily {
+		return &Gauge{vec: newMetricVec(name, help, metricTypeGauge, labelNames, nil)}
+	}).(*Gauge)
+}
+
+// NewGaugeFunc registers a gauge that the value are got by fn when it's collected(such as a queue depth).
+func (r *MetricsRegistry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, metricTypeGauge, nil, func() metricFamily {
+		return &gaugeFunc{name: name, help: help, fn: fn}
+	})
+}
+
+// NewHistogram registers a histogram, nil buckets means DefaultMetricsBuckets,
+// the registered histogram are returned if it's registered before.
+func (r *MetricsRegistry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			panic(fmt.Sprintf("the buckets of histogram: %s should be in increasing order", name))
+		}
+	}
+	return r.register(name, metricTypeHistogram, labelNames, func() metricFamily {
+		return &Histogram{vec: newMetricVec(name, help, metricTypeHistogram, labelNames, buckets)}
+	}).(*Histogram)
+}
+
+// WriteTo writes all of the metrics into w in Prometheus text format(version 0.0.4).
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.locker.RLock()
+	var names = make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var families = make([]metricFamily, 0, len(names))
+	for _, name := range names {
+		families = append(families, r.families[name])
+	}
+	r.locker.RUnlock()
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, f := range families {
+		f.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Counter represents a monotonically increasing metric that partitioned by the labels.
+type Counter struct {
+	vec *metricVec
+}
+
+// Inc increases the counter of the label values by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter of the label values by v, it's panics if v is negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("counter: %s can not be decreased", c.vec.name))
+	}
+	c.vec.update(labelValues, func(s *metricSeries) {
+		s.value += v
+	})
+}
+
+// Value returns the counter of the label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.vec.value(labelValues)
+}
+
+func (c *Counter) kind() string          { return metricTypeCounter }
+func (c *Counter) labels() []string      { return c.vec.labelNames }
+func (c *Counter) write(w *bufio.Writer) { c.vec.write(w) }
+
+// Gauge represents a metric that can be go up and down, it's partitioned by the labels.
+type Gauge struct {
+	vec *metricVec
+}
+
+// Set sets the gauge of the label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.update(labelValues, func(s *metricSeries) {
+		s.value = v
+	})
+}
+
+// Add adds v(can be negative) to the gauge of the label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.vec.update(labelValues, func(s *metricSeries) {
+		s.value += v
+	})
+}
+
+// Inc increases the gauge of the label values by 1.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decreases the gauge of the label values by 1.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the gauge of the label values.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.vec.value(labelValues)
+}
+
+func (g *Gauge) kind() string          { return metricTypeGauge }
+func (g *Gauge) labels() []string      { return g.vec.labelNames }
+func (g *Gauge) write(w *bufio.Writer) { g.vec.write(w) }
+
+// Histogram represents a metric that samples the observations(such as latencies) into the buckets.
+type Histogram struct {
+	vec *metricVec
+}
+
+// Observe adds a observation of the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	buckets := h.vec.buckets
+	h.vec.update(labelValues, func(s *metricSeries) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(buckets))
+		}
+		// the counts are not cumulative, it's accumulated when written.
+		if idx := sort.SearchFloat64s(buckets, v); idx < len(buckets) {
+			s.counts[idx]++
+		}
+		s.count++
+		s.value += v
+	})
+}
+
+// Count returns the count of the observations of the label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	var count uint64
+	h.vec.read(labelValues, func(s *metricSeries) {
+		count = s.count
+	})
+	return count
+}
+
+func (h *Histogram) kind() string          { return metricTypeHistogram }
+func (h *Histogram) labels() []string      { return h.vec.labelNames }
+func (h *Histogram) write(w *bufio.Writer) { h.vec.write(w) }
+
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+func (g *gaugeFunc) kind() string     { return metricTypeGauge }
+func (g *gaugeFunc) labels() []string { return nil }
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeMetricHeader(w, g.name, g.help, metricTypeGauge)
+	writeMetricSample(w, g.name, "", g.fn())
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	count       uint64
+	counts      []uint64
+}
+
+type metricVec struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+	buckets    []float64
+	locker     sync.Mutex
+	series     map[string]*metricSeries
+}
+
+func newMetricVec(name, help, typ string, labelNames []string, buckets []float64) *metricVec {
+	return &metricVec{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*metricSeries),
+	}
+}
+
+func (v *metricVec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric: %s expects %d label values(%s), but got %d",
+			v.name, len(v.labelNames), strings.Join(v.labelNames, ", "), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (v *metricVec) update(labelValues []string, fn func(s *metricSeries)) {
+	key := v.key(labelValues)
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	fn(s)
+}
+
+func (v *metricVec) read(labelValues []string, fn func(s *metricSeries)) {
+	key := v.key(labelValues)
+	v.locker.Lock()
+	defer v.locker.Unlock()
+	if s, ok := v.series[key]; ok {
+		fn(s)
+	}
+}
+
+func (v *metricVec) value(labelValues []string) float64 {
+	var value float64
+	v.read(labelValues, func(s *metricSeries) {
+		value = s.value
+	})
+	return value
+}
+
+func (v *metricVec) write(w *bufio.Writer) {
+	v.locker.Lock()
+	var keys = make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var series = make([]metricSeries, 0, len(keys))
+	for _, k := range keys {
+		s := *v.series[k]
+		s.counts = append([]uint64(nil), s.counts...)
+		series = append(series, s)
+	}
+	v.locker.Unlock()
+
+	writeMetricHeader(w, v.name, v.help, v.typ)
+	for _, s := range series {
+		labels := formatMetricLabels(v.labelNames, s.labelValues)
+		if v.typ != metricTypeHistogram {
+			writeMetricSample(w, v.name, labels, s.value)
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			if i < len(s.counts) {
+				cumulative += s.counts[i]
+			}
+			writeMetricSample(w, v.name+"_bucket", appendMetricLabel(labels, "le", formatMetricValue(upper)), float64(cumulative))
+		}
+		writeMetricSample(w, v.name+"_bucket", appendMetricLabel(labels, "le", "+Inf"), float64(s.count))
+		writeMetricSample(w, v.name+"_sum", labels, s.value)
+		writeMetricSample(w, v.name+"_count", labels, float64(s.count))
+	}
+}
+
+var (
+	metricHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	metricLabelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeMetricHeader(w *bufio.Writer, name, help, typ string) {
+	if help != "" {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", name, metricHelpEscaper.Replace(help))
+	}
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func writeMetricSample(w *bufio.Writer, name, labels string, value float64) {
+	_, _ = w.WriteString(name)
+	if labels != "" {
+		_, _ = w.WriteString("{" + labels + "}")
+	}
+	_, _ = w.WriteString(" " + formatMetricValue(value) + "\n")
+}
+
+func formatMetricLabels(names, values []string) string {
+	var labels = make([]string, 0, len(names))
+	for i, name := range names {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, name, metricLabelEscaper.Replace(values[i])))
+	}
+	return strings.Join(labels, ",")
+}
+
+func appendMetricLabel(labels, name, value string) string {
+	label := fmt.Sprintf(`%s="%s"`, name, value)
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,162 @@
+package gw
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/oceanho/gw/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	metricsStartKey     = "gw-metrics-start"
+	gwDbMetricsStartKey = "gw:metrics_start"
+	unmatchedHandler    = "unmatched"
+)
+
+// builtinMetrics represents the gw built-in metrics of a server.
+type builtinMetrics struct {
+	requests        *Counter
+	requestDuration *Histogram
+	panics          *Counter
+	dbDuration      *Histogram
+	redisDuration   *Histogram
+}
+
+func newBuiltinMetrics(r *MetricsRegistry) *builtinMetrics {
+	return &builtinMetrics{
+		requests: r.NewCounter("gw_http_requests_total",
+			"The total number of the http requests.", "app", "handler", "method", "status"),
+		requestDuration: r.NewHistogram("gw_http_request_duration_seconds",
+			"The latencies of the http requests.", nil, "app", "handler", "method", "status"),
+		panics: r.NewCounter("gw_panics_recovered_total",
+			"The total number of the recovered panics of the http requests.", "app", "handler"),
+		dbDuration: r.NewHistogram("gw_db_query_duration_seconds",
+			"The latencies of the db operations.", nil, "operation", "table"),
+		redisDuration: r.NewHistogram("gw_redis_command_duration_seconds",
+			"The latencies of the redis commands.", nil, "store", "command"),
+	}
+}
+
+func (s *HostServer) metricsEnabled() bool {
+	return s.Config().Service.Metrics.Enabled
+}
+
+// routerLabels returns the app and handler name of the request, the handler is the path of the not gw routers(such as /healthz).
+func routerLabels(c *gin.Context) (app, handler string) {
+	if v, ok := c.Get(gwRouterInfoKey); ok {
+		if router, ok := v.(RouterInfo); ok {
+			return router.AppName, router.Name()
+		}
+	}
+	if path := c.FullPath(); path != "" {
+		return "", path
+	}
+	return "", unmatchedHandler
+}
+
+// newMetricsHook returns the built-in metrics hook, it's records the count and latency of the requests.
+func newMetricsHook(s *HostServer) *Hook {
+	return NewHook(HookMetrics, func(c *gin.Context) {
+		c.Set(metricsStartKey, time.Now())
+	}, func(c *gin.Context) {
+		v, ok := c.Get(metricsStartKey)
+		if !ok || !s.metricsEnabled() {
+			return
+		}
+		app, handler := routerLabels(c)
+		method, status := c.Request.Method, strconv.Itoa(c.Writer.Status())
+		s.metrics.requests.Inc(app, handler, method, status)
+		s.metrics.requestDuration.Observe(time.Since(v.(time.Time)).Seconds(), app, handler, method, status)
+	})
+}
+
+// recordPanic increases the recovered panics counter of the request.
+func recordPanic(s *HostServer, c *gin.Context) {
+	app, handler := routerLabels(c)
+	s.metrics.panics.Inc(app, handler)
+}
+
+// setupMetrics registers the metrics of the backend stores and event manager, it's called after the server initialed.
+func setupMetrics(s *HostServer) {
+	if !s.metricsEnabled() {
+		return
+	}
+	for _, cache := range s.Config().Backend.Cache {
+		client, err := cacheStoreByName(s, cache.Name)
+		if err != nil {
+			logger.Error("setup metrics of cache store: %s fail, err: %v", cache.Name, err)
+			continue
+		}
+		client.AddHook(redisMetricsHook{store: cache.Name, duration: s.metrics.redisDuration})
+	}
+	if q, ok := s.EventManager.(interface{ QueueDepth() int }); ok {
+		s.Metrics.NewGaugeFunc("gw_event_queue_depth", "The number of the published events that waiting to be dispatched.", func() float64 {
+			return float64(q.QueueDepth())
+		})
+	}
+}
+
+type redisMetricsStartKey struct{}
+
+// redisMetricsHook represents a redis.Hook that records the latencies of the commands.
+type redisMetricsHook struct {
+	store    string
+	duration *Histogram
+}
+
+func (h redisMetricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisMetricsStartKey{}, time.Now()), nil
+}
+
+func (h redisMetricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if start, ok := ctx.Value(redisMetricsStartKey{}).(time.Time); ok {
+		h.duration.Observe(time.Since(start).Seconds(), h.store, cmd.Name())
+	}
+	return nil
+}
+
+func (h redisMetricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisMetricsStartKey{}, time.Now()), nil
+}
+
+func (h redisMetricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if start, ok := ctx.Value(redisMetricsStartKey{}).(time.Time); ok {
+		h.duration.Observe(time.Since(start).Seconds(), h.store, "pipeline")
+	}
+	return nil
+}
+
+// startDbMetrics records the start time of the db operation.
+func startDbMetrics(db *gorm.DB) {
+	db.InstanceSet(gwDbMetricsStartKey, time.Now())
+}
+
+// observeDbMetrics records the latency of the db operation,
+// only the operations of the requests(the db are got by the Context.Store()) are recorded.
+func observeDbMetrics(db *gorm.DB, operation string) {
+	start, ok := db.InstanceGet(gwDbMetricsStartKey)
+	if !ok {
+		return
+	}
+	obj, ok := db.Get(gwDbContextKey)
+	if !ok {
+		return
+	}
+	if ctx, ok := obj.(*Context); ok && ctx.server.metricsEnabled() {
+		ctx.server.metrics.dbDuration.Observe(time.Since(start.(time.Time)).Seconds(), operation, db.Statement.Table)
+	}
+}
+
+// gwMetrics is the metrics API, It's responds the metrics in Prometheus text format.
+func gwMetrics(c *gin.Context) {
+	s := getHostServer(c)
+	c.Header("Content-Type", MIMEPrometheusText)
+	c.Status(http.StatusOK)
+	if _, err := s.Metrics.WriteTo(c.Writer); err != nil {
+		logger.Error("write metrics fail, err: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package gw_test
+
+import (
+	"fmt"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type metricsUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+}
+
+// metricsHelloCounter returns the app custom counter, it's registered on the app started.
+func metricsHelloCounter(metrics *gw.MetricsRegistry) *gw.Counter {
+	return metrics.NewCounter("gwtest_hello_total", "The total number of the hello calls.", "passport")
+}
+
+func metricsHello(c *gw.Context) {
+	metricsHelloCounter(c.HostServer().Metrics).Inc(c.User().Passport)
+	c.JSON200("hello")
+}
+
+func metricsListUsers(c *gw.Context) {
+	var users []metricsUser
+	if err := c.Store().GetDbStore().Where("tenant_id = ?", c.User().TenantId).Find(&users).Error; err != nil {
+		c.JSON500Msg(0, err)
+		return
+	}
+	c.JSON200(users)
+}
+
+func metricsCrash(c *gw.Context) {
+	panic("crash")
+}
+
+func TestServer_Metrics(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		AppName: "gwtest.metrics",
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("hello", metricsHello)
+			router.GET("users", metricsListUsers)
+			router.GET("crash", metricsCrash)
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			_ = state.Store().GetDbStore().AutoMigrate(&metricsUser{})
+		},
+		OnStartFunc: func(state *gw.ServerState) {
+			metricsHelloCounter(state.Metrics())
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	client.Call("metricsHello", nil, nil).AssertOK()
+	client.Call("metricsHello", nil, nil).AssertOK()
+	client.Call("metricsListUsers", nil, nil).AssertOK()
+	client.Call("metricsCrash", nil, nil).AssertStatus(http.StatusInternalServerError)
+
+	resp := server.Client().Get("/metrics").AssertStatus(http.StatusOK)
+	assert.Equal(t, gw.MIMEPrometheusText, resp.Header.Get("Content-Type"))
+	body := string(resp.Body)
+	helloRouter, crashRouter := client.Router("metricsHello"), client.Router("metricsCrash")
+	hello, crash := helloRouter.Name(), crashRouter.Name()
+	for _, line := range []string{
+		"# TYPE gw_http_requests_total counter",
+		fmt.Sprintf(`gw_http_requests_total{app="gwtest.metrics",handler="%s",method="GET",status="200"} 2`, hello),
+		fmt.Sprintf(`gw_http_requests_total{app="gwtest.metrics",handler="%s",method="GET",status="500"} 1`, crash),
+		fmt.Sprintf(`gw_http_request_duration_seconds_count{app="gwtest.metrics",handler="%s",method="GET",status="200"} 2`, hello),
+		fmt.Sprintf(`gw_http_request_duration_seconds_bucket{app="gwtest.metrics",handler="%s",method="GET",status="200",le="+Inf"} 2`, hello),
+		fmt.Sprintf(`gw_panics_recovered_total{app="gwtest.metrics",handler="%s"} 1`, crash),
+		`gw_db_query_duration_seconds_count{operation="query",table="metrics_users"} 1`,
+		`gw_event_queue_depth 0`,
+		`gwtest_hello_total{passport="gw"} 2`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.Contains(t, body, `gw_redis_command_duration_seconds_count{store="primary",command="get"}`)
+
+	// the not gw routers are labelled by the path.
+	body = string(server.Client().Get("/metrics").Body)
+	assert.Contains(t, body, `gw_http_requests_total{app="",handler="/metrics",method="GET",status="200"} 1`+"\n")
+}
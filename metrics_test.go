@@ -0,0 +1,65 @@
+package gw
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	r := newMetricsRegistry()
+	requests := r.NewCounter("requests_total", "The requests.\nmultiline", "method", "path")
+	requests.Inc("GET", "/a")
+	requests.Add(2, "GET", "/a")
+	requests.Inc("POST", `/b"\`)
+	assert.Equal(t, float64(3), requests.Value("GET", "/a"))
+	assert.Equal(t, requests, r.NewCounter("requests_total", "", "method", "path"))
+
+	inflight := r.NewGauge("inflight", "")
+	inflight.Inc()
+	inflight.Inc()
+	inflight.Dec()
+	assert.Equal(t, float64(1), inflight.Value())
+
+	latency := r.NewHistogram("latency_seconds", "The latencies.", []float64{0.1, 1}, "method")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.1, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(2, "GET")
+	assert.Equal(t, uint64(4), latency.Count("GET"))
+	r.NewGaugeFunc("queue_depth", "The queue depth.", func() float64 { return 7 })
+
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, `# TYPE inflight gauge
+inflight 1
+# HELP latency_seconds The latencies.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 2
+latency_seconds_bucket{method="GET",le="1"} 3
+latency_seconds_bucket{method="GET",le="+Inf"} 4
+latency_seconds_sum{method="GET"} 2.65
+latency_seconds_count{method="GET"} 4
+# HELP queue_depth The queue depth.
+# TYPE queue_depth gauge
+queue_depth 7
+# HELP requests_total The requests.\nmultiline
+# TYPE requests_total counter
+requests_total{method="GET",path="/a"} 3
+requests_total{method="POST",path="/b\"\\"} 1
+`, buf.String())
+}
+
+func TestMetricsRegistry_Panics(t *testing.T) {
+	r := newMetricsRegistry()
+	counter := r.NewCounter("requests_total", "", "method")
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { counter.Add(-1, "GET") })
+	assert.Panics(t, func() { r.NewGauge("requests_total", "", "method") })
+	assert.Panics(t, func() { r.NewCounter("requests_total", "", "path") })
+	assert.Panics(t, func() { r.NewCounter("requests-total", "") })
+	assert.Panics(t, func() { r.NewCounter("errors_total", "", "le") })
+	assert.Panics(t, func() { r.NewHistogram("latency_seconds", "", []float64{1, 0.5}) })
+}
@@ -0,0 +1,526 @@
+package gw
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	openAPIVersion            = "3.0.3"
+	apiSchemaDecoratorCatalog = "gw_framework_api_schema"
+	openAPIResponseSchemaName = "gw.Response"
+	openAPICookieSecurityName = "gwCookie"
+	openAPITokenSecurityName  = "gwToken"
+)
+
+var (
+	openAPIPathParamRegexp   = regexp.MustCompile(`[:*]([^/]+)`)
+	openAPIOperationIdRegexp = regexp.MustCompile(`[^A-Za-z0-9_.]+`)
+	timeTyper                = reflect.TypeOf(time.Time{})
+	rawMessageTyper          = reflect.TypeOf(json.RawMessage{})
+	// openAPIAnyMethods are the methods of the "any" routers, the CONNECT are not a OpenAPI operation.
+	openAPIAnyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodTrace}
+)
+
+// apiSchema represents the request models and response payload of a router.
+type apiSchema struct {
+	in  []reflect.Type
+	out reflect.Type
+}
+
+// NewApiSchemaDecorator returns a Decorator that declares the request model(in) and response payload(out) of a router,
+// It's used by the OpenAPI document. in/out can be a object or a reflect.Type, nil means none.
+//
+// The dynamic rest APIs are declared automatically by the handler's arguments and return values.
+func NewApiSchemaDecorator(in, out interface{}) Decorator {
+	var schema = &apiSchema{}
+	if typ := schemaTyperOf(in); typ != nil {
+		schema.in = append(schema.in, typ)
+	}
+	schema.out = schemaTyperOf(out)
+	return newApiSchemaDecorator(schema)
+}
+
+func newApiSchemaDecorator(schema *apiSchema) Decorator {
+	return Decorator{
+		Catalog:  apiSchemaDecoratorCatalog,
+		MetaData: schema,
+	}
+}
+
+func schemaTyperOf(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	if typ, ok := v.(reflect.Type); ok {
+		return typ
+	}
+	return reflect.TypeOf(v)
+}
+
+func routerApiSchema(decorators ...Decorator) *apiSchema {
+	var schema *apiSchema
+	for _, d := range decorators {
+		if d.Catalog != apiSchemaDecoratorCatalog {
+			continue
+		}
+		if s, ok := d.MetaData.(*apiSchema); ok {
+			schema = s
+		}
+	}
+	return schema
+}
+
+// OpenAPIDocument represents a OpenAPI 3.0 document.
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Tags       []OpenAPITag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type OpenAPITag struct {
+	Name string `json:"name"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	Tags        []string                    `json:"tags,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationId string                      `json:"operationId"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Permissions []string                    `json:"x-gw-permissions,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AllOf                []*OpenAPISchema          `json:"allOf,omitempty"`
+}
+
+// OpenAPI returns the OpenAPI 3.0 document of the registered routers.
+//
+// Request/Response schemas come from the routers' DTO types(see NewApiSchemaDecorator),
+// security requirements come from the permission decorators, tags come from the owning App.Name().
+func (s *HostServer) OpenAPI() *OpenAPIDocument {
+	var routers = s.GetRouters()
+	var cnf = s.Config()
+	var builder = newOpenAPISchemaBuilder(s)
+	var doc = &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       cnf.Service.Name,
+			Description: cnf.Service.Remarks,
+			Version:     cnf.Service.Version,
+		},
+		Paths: make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{
+			Schemas: builder.schemas,
+			SecuritySchemes: map[string]*OpenAPISecurityScheme{
+				openAPICookieSecurityName: {
+					Type: "apiKey",
+					In:   "cookie",
+					Name: cnf.Security.Auth.Cookie.Key,
+				},
+				openAPITokenSecurityName: {
+					Type: "apiKey",
+					In:   "header",
+					Name: "X-Auth-Token",
+				},
+			},
+		},
+	}
+	var allowUrls = newAllowUrlsState(cnf).allowUrls
+	var tags = make(map[string]bool)
+	builder.schemas[openAPIResponseSchemaName] = &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"Status":    {Type: "integer"},
+			"Code":      {Type: "string"},
+			"Error":     {Type: "string", Nullable: true},
+			"RequestId": {Type: "string"},
+			"Payload":   {},
+		},
+	}
+	for i := 0; i < len(routers); i++ {
+		r := routers[i]
+		urlPath := openAPIPathParamRegexp.ReplaceAllString(r.UrlPath, "{$1}")
+		if _, ok := doc.Paths[urlPath]; !ok {
+			doc.Paths[urlPath] = make(map[string]*OpenAPIOperation)
+		}
+		methods := []string{r.Method}
+		if r.Method == "any" {
+			methods = openAPIAnyMethods
+		}
+		for _, method := range methods {
+			r.Method = method
+			op := builder.operation(&r, allowUrls[fmt.Sprintf("%s:%s", method, r.UrlPath)])
+			if len(methods) > 1 {
+				// the operation ids must be unique.
+				op.OperationId += "_" + strings.ToLower(method)
+			}
+			doc.Paths[urlPath][strings.ToLower(method)] = op
+		}
+		if r.AppName != "" {
+			tags[r.AppName] = true
+		}
+	}
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, OpenAPITag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+	return doc
+}
+
+type openAPISchemaBuilder struct {
+	s       *HostServer
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func newOpenAPISchemaBuilder(s *HostServer) *openAPISchemaBuilder {
+	return &openAPISchemaBuilder{
+		s:       s,
+		schemas: make(map[string]*OpenAPISchema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (b *openAPISchemaBuilder) operation(r *RouterInfo, isPublic bool) *OpenAPIOperation {
+	var name = strings.TrimSuffix(r.Name(), "(ctx *Context)")
+	var op = &OpenAPIOperation{
+		Summary:     name[strings.LastIndex(name, "/")+1:],
+		OperationId: strings.Trim(openAPIOperationIdRegexp.ReplaceAllString(name, "_"), "_"),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	if r.AppName != "" {
+		op.Tags = []string{r.AppName}
+	}
+	for _, m := range openAPIPathParamRegexp.FindAllStringSubmatch(r.UrlPath, -1) {
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   &OpenAPISchema{Type: "string"},
+		})
+	}
+	var payload *OpenAPISchema
+	if r.schema != nil {
+		for _, in := range r.schema.in {
+			b.requestModel(op, r.Method, in)
+		}
+		if r.schema.out != nil {
+			payload = b.schemaOf(r.schema.out)
+		}
+	}
+	op.Responses["200"] = b.response("OK", payload)
+	if r.isWebSocket {
+		op.Responses = map[string]*OpenAPIResponse{
+			"101": {Description: "Switching Protocols"},
+		}
+	}
+	if r.isStream {
+		op.Responses["200"] = &OpenAPIResponse{
+			Description: "OK",
+			Content: map[string]OpenAPIMediaType{
+				MIMEEventStream: {Schema: &OpenAPISchema{Type: "string"}},
+			},
+		}
+	}
+	if op.RequestBody != nil || len(op.Parameters) > 0 {
+		op.Responses["400"] = b.response(errDefault400Msg, nil)
+	}
+	if !isPublic {
+		op.Security = []map[string][]string{
+			{openAPICookieSecurityName: {}},
+			{openAPITokenSecurityName: {}},
+		}
+		op.Responses["401"] = b.response(errDefault401Msg, nil)
+	}
+	if len(r.Permissions) > 0 {
+		for _, p := range r.Permissions {
+			op.Permissions = append(op.Permissions, p.IdStr())
+		}
+		op.Description = fmt.Sprintf("Requires one of permissions: %s", strings.Join(op.Permissions, ", "))
+		op.Responses["403"] = b.response(errDefault403Msg, nil)
+	}
+	return op
+}
+
+func (b *openAPISchemaBuilder) response(description string, payload *OpenAPISchema) *OpenAPIResponse {
+	var schema = &OpenAPISchema{Ref: "#/components/schemas/" + openAPIResponseSchemaName}
+	if payload != nil {
+		schema = &OpenAPISchema{
+			AllOf: []*OpenAPISchema{
+				schema,
+				{
+					Type:       "object",
+					Properties: map[string]*OpenAPISchema{"Payload": payload},
+				},
+			},
+		}
+	}
+	return &OpenAPIResponse{
+		Description: description,
+		Content: map[string]OpenAPIMediaType{
+			gin.MIMEJSON: {Schema: schema},
+		},
+	}
+}
+
+// requestModel declares the path/query parameters and the request body of the request model.
+func (b *openAPISchemaBuilder) requestModel(op *OpenAPIOperation, method string, typ reflect.Type) {
+	if di, ok := b.s.DIProvider.(diTyperRegistry); ok && di.IsRegistered(typ) {
+		return
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+	var hasBody = method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+	var hasJsonField bool
+	eachSchemaField(typ, func(field reflect.StructField) {
+		if name := tagName(field, "uri"); name != "" {
+			for _, p := range op.Parameters {
+				if p.In == "path" && p.Name == name {
+					p.Schema = b.schemaOf(field.Type)
+				}
+			}
+			return
+		}
+		if name := tagName(field, "form"); name != "" && !hasBody {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name:     name,
+				In:       "query",
+				Required: isRequiredField(field),
+				Schema:   b.schemaOf(field.Type),
+			})
+			return
+		}
+		if tagName(field, "json") != "" || field.Tag.Get("json") == "" {
+			hasJsonField = true
+		}
+	})
+	if hasBody && hasJsonField {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				gin.MIMEJSON: {Schema: b.schemaOf(typ)},
+			},
+		}
+	}
+}
+
+// schemaOf returns the schema of the typer, structs are declared in components and referenced.
+func (b *openAPISchemaBuilder) schemaOf(typ reflect.Type) *OpenAPISchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ {
+	case timeTyper:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case rawMessageTyper:
+		return &OpenAPISchema{}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var min float64
+		return &OpenAPISchema{Type: "integer", Format: "int64", Minimum: &min}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: b.schemaOf(typ.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: b.schemaOf(typ.Elem())}
+	case reflect.Struct:
+		return b.structSchemaOf(typ)
+	}
+	return &OpenAPISchema{}
+}
+
+func (b *openAPISchemaBuilder) structSchemaOf(typ reflect.Type) *OpenAPISchema {
+	if typ.Name() == "" {
+		return b.objectSchemaOf(typ)
+	}
+	name, ok := b.names[typ]
+	if !ok {
+		name = b.schemaName(typ)
+		b.names[typ] = name
+		// placeholder for recursive types.
+		b.schemas[name] = &OpenAPISchema{Type: "object"}
+		b.schemas[name] = b.objectSchemaOf(typ)
+	}
+	return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func (b *openAPISchemaBuilder) objectSchemaOf(typ reflect.Type) *OpenAPISchema {
+	var schema = &OpenAPISchema{
+		Type:       "object",
+		Properties: make(map[string]*OpenAPISchema),
+	}
+	eachSchemaField(typ, func(field reflect.StructField) {
+		name := field.Name
+		tag := field.Tag.Get("json")
+		if tag != "" {
+			name = tagName(field, "json")
+		}
+		if name == "" {
+			return
+		}
+		var prop *OpenAPISchema
+		if strings.Contains(tag, ",string") {
+			prop = &OpenAPISchema{Type: "string"}
+		} else {
+			prop = b.schemaOf(field.Type)
+		}
+		schema.Properties[name] = prop
+		if isRequiredField(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	})
+	return schema
+}
+
+// schemaName returns a unique components schema name, such as dto.User
+func (b *openAPISchemaBuilder) schemaName(typ reflect.Type) string {
+	pkg := typ.PkgPath()
+	name := fmt.Sprintf("%s.%s", pkg[strings.LastIndex(pkg, "/")+1:], typ.Name())
+	if _, ok := b.schemas[name]; !ok {
+		return name
+	}
+	for i := 2; ; i++ {
+		n := fmt.Sprintf("%s%d", name, i)
+		if _, ok := b.schemas[n]; !ok {
+			return n
+		}
+	}
+}
+
+// eachSchemaField iterate the exported fields of struct, the embedded struct fields are flatted.
+func eachSchemaField(typ reflect.Type, fn func(field reflect.StructField)) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				eachSchemaField(ft, fn)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		fn(field)
+	}
+}
+
+func tagName(field reflect.StructField, key string) string {
+	tag := field.Tag.Get(key)
+	if tag == "" {
+		return ""
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" && key == "json" {
+		return field.Name
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func isRequiredField(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// gwOpenAPI is the OpenAPI document API.
+func gwOpenAPI(c *gin.Context) {
+	s := getHostServer(c)
+	c.JSON(http.StatusOK, s.OpenAPI())
+}
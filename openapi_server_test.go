@@ -0,0 +1,79 @@
+package gw_test
+
+import (
+	"encoding/json"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type openapiUser struct {
+	ID   uint64
+	Name string
+}
+
+type openapiUserQuery struct {
+	ID uint64 `uri:"id" binding:"required"`
+}
+
+type openapiCreateUser struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type openapiUserAPI struct {
+}
+
+func (u *openapiUserAPI) Name() string {
+	return "users"
+}
+
+func (u *openapiUserAPI) Detail(in *openapiUserQuery) (*openapiUser, error) {
+	return &openapiUser{ID: in.ID}, nil
+}
+
+func (u *openapiUserAPI) Post(in openapiCreateUser) (openapiUser, error) {
+	return openapiUser{Name: in.Name}, nil
+}
+
+func openapiGetUser(c *gw.Context) {
+	c.JSON200(openapiUser{})
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		AppName: "gwtest.openapi",
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("user/:id", openapiGetUser)
+			router.Any("ping", openapiGetUser)
+			router.RegisterRestAPIs(&openapiUserAPI{})
+		},
+	})
+	var doc gw.OpenAPIDocument
+	resp := server.Client().Get("/openapi.json").AssertStatus(http.StatusOK)
+	assert.Nil(t, json.Unmarshal(resp.Body, &doc))
+
+	detail := doc.Paths["/api/v1/tester/users/detail/{id}"]["get"]
+	if assert.NotNil(t, detail) {
+		assert.Equal(t, []string{"gwtest.openapi"}, detail.Tags)
+		assert.Equal(t, "path", detail.Parameters[0].In)
+		assert.Equal(t, "integer", detail.Parameters[0].Schema.Type)
+		assert.NotEmpty(t, detail.Security)
+		assert.Equal(t, "#/components/schemas/gw_test.openapiUser", detail.Responses["200"].Content["application/json"].Schema.AllOf[1].Properties["Payload"].Ref)
+	}
+	post := doc.Paths["/api/v1/tester/users"]["post"]
+	if assert.NotNil(t, post) && assert.NotNil(t, post.RequestBody) {
+		assert.Equal(t, "#/components/schemas/gw_test.openapiCreateUser", post.RequestBody.Content["application/json"].Schema.Ref)
+	}
+	assert.Equal(t, []string{"name"}, doc.Components.Schemas["gw_test.openapiCreateUser"].Required)
+	assert.NotNil(t, doc.Paths["/api/v1/tester/user/{id}"]["get"])
+
+	// the "any" routers are expanded into the http methods.
+	ping := doc.Paths["/api/v1/tester/ping"]
+	assert.Len(t, ping, 8)
+	assert.Nil(t, ping["any"])
+	if assert.NotNil(t, ping["get"]) && assert.NotNil(t, ping["post"]) {
+		assert.NotEqual(t, ping["get"].OperationId, ping["post"].OperationId)
+	}
+}
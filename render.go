@@ -0,0 +1,432 @@
+package gw
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	gwRendererKey            = "gw-renderer"
+	rendererDecoratorCatalog = "gw_framework_renderer"
+	MIMEYAML                 = "application/x-yaml"
+	MIMEMsgPack              = "application/msgpack"
+	errDefault406Msg         = "Not Acceptable"
+)
+
+var (
+	ErrNotAcceptable = NewError(http.StatusNotAcceptable, ErrCodeNotAcceptable, "not acceptable")
+)
+
+// RendererFunc returns a gin render.Render that renders the response body.
+type RendererFunc func(body interface{}) render.Render
+
+// Renderers represents the response renderer registry of media types,
+// the renderer are chosen by the request Accept header or the router's renderer decorator.
+type Renderers struct {
+	locker    sync.RWMutex
+	mimeTypes []string
+	renderers map[string]RendererFunc
+}
+
+// NewRenderers returns a Renderers that has JSON(the default), XML, YAML and MessagePack renderers.
+func NewRenderers() *Renderers {
+	r := &Renderers{
+		renderers: make(map[string]RendererFunc),
+	}
+	r.Register(func(body interface{}) render.Render {
+		return render.JSON{Data: body}
+	}, gin.MIMEJSON)
+	r.Register(func(body interface{}) render.Render {
+		return render.XML{Data: xmlBodyOf(body)}
+	}, gin.MIMEXML, gin.MIMEXML2)
+	r.Register(func(body interface{}) render.Render {
+		return render.YAML{Data: body}
+	}, MIMEYAML, "application/yaml", "text/yaml")
+	r.Register(func(body interface{}) render.Render {
+		return render.MsgPack{Data: body}
+	}, MIMEMsgPack, binding.MIMEMSGPACK)
+	r.Register(func(body interface{}) render.Render {
+		return render.JSON{Data: body}
+	}, MIMEProblemJSON)
+	return r
+}
+
+// Register registers(or replace) the renderer of the media types, the first registered media type is the default.
+func (r *Renderers) Register(renderer RendererFunc, mimeTypes ...string) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	for _, m := range mimeTypes {
+		m = strings.ToLower(m)
+		if _, ok := r.renderers[m]; !ok {
+			r.mimeTypes = append(r.mimeTypes, m)
+		}
+		r.renderers[m] = renderer
+	}
+}
+
+// MimeTypes returns the registered media types.
+func (r *Renderers) MimeTypes() []string {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+	return append([]string{}, r.mimeTypes...)
+}
+
+// Default returns the default media type and it's renderer.
+func (r *Renderers) Default() (string, RendererFunc) {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+	if len(r.mimeTypes) == 0 {
+		return gin.MIMEJSON, func(body interface{}) render.Render {
+			return render.JSON{Data: body}
+		}
+	}
+	return r.mimeTypes[0], r.renderers[r.mimeTypes[0]]
+}
+
+// Negotiate returns the renderer that best matches the accept header,
+// offers limits the candidate media types(in order of preference), empty means all registered media types.
+func (r *Renderers) Negotiate(accept string, offers ...string) (string, RendererFunc, bool) {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+	if len(offers) == 0 {
+		offers = r.mimeTypes
+	}
+	var candidates = make([]string, 0, len(offers))
+	for _, o := range offers {
+		o = strings.ToLower(o)
+		if _, ok := r.renderers[o]; ok {
+			candidates = append(candidates, o)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil, false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return candidates[0], r.renderers[candidates[0]], true
+	}
+	ranges := parseAcceptRanges(accept)
+	if c, ok := bestOffer(ranges, candidates, func(mimeType string) bool {
+		_, ok := r.renderers[mimeType]
+		return ok
+	}); ok {
+		return c, r.renderers[c], true
+	}
+	return "", nil, false
+}
+
+// bestOffer returns the offer that explicitly listed by the ranges of the highest quality, by the order of the offers.
+// Otherwise(the wildcards, or the types that has no renderer such as text/html of the browsers),
+// It's fall back to the first offer that not refused(q=0), so the clients get the route's first renderer as before.
+// The clients that prefers a registered type that not offered by the route are not acceptable.
+func bestOffer(ranges []acceptRange, offers []string, isRegistered func(mimeType string) bool) (string, bool) {
+	var topQ float64
+	for _, a := range ranges {
+		if a.q > topQ {
+			topQ = a.q
+		}
+	}
+	if topQ > 0 {
+		for _, o := range offers {
+			for _, a := range ranges {
+				if a.q == topQ && a.mimeType == o {
+					return o, true
+				}
+			}
+		}
+		for _, a := range ranges {
+			if a.q == topQ && isRegistered(a.mimeType) {
+				return "", false
+			}
+		}
+	}
+	for _, o := range offers {
+		if !isRefused(ranges, o) {
+			return o, true
+		}
+	}
+	return "", false
+}
+
+// isRefused returns true if the most specific range that matched the offer is not acceptable(q=0).
+func isRefused(ranges []acceptRange, offer string) bool {
+	var matched = -1
+	for i, a := range ranges {
+		if a.match(offer) && (matched < 0 || a.specificity() > ranges[matched].specificity()) {
+			matched = i
+		}
+	}
+	return matched >= 0 && ranges[matched].q <= 0
+}
+
+type acceptRange struct {
+	mimeType string
+	q        float64
+}
+
+// specificity returns the specificity of the range, type/subtype(2) > type/*(1) > */*(0).
+func (a acceptRange) specificity() int {
+	switch {
+	case a.mimeType == "*/*" || a.mimeType == "*":
+		return 0
+	case strings.HasSuffix(a.mimeType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func (a acceptRange) match(mimeType string) bool {
+	if a.mimeType == "*/*" || a.mimeType == mimeType {
+		return true
+	}
+	if strings.HasSuffix(a.mimeType, "/*") {
+		return strings.HasPrefix(mimeType, strings.TrimSuffix(a.mimeType, "*"))
+	}
+	return false
+}
+
+// parseAccept parse the Accept header, returns the acceptable media ranges that order by the quality(desc),
+// the more specific ranges are ordered first at the equal quality.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, ar := range parseAcceptRanges(accept) {
+		if ar.q > 0 {
+			ranges = append(ranges, ar)
+		}
+	}
+	return ranges
+}
+
+// parseAcceptRanges parse the Accept header like parseAccept, the not acceptable(q=0) ranges are kept.
+func parseAcceptRanges(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		segments := strings.Split(part, ";")
+		mimeType := strings.ToLower(strings.TrimSpace(segments[0]))
+		if mimeType == "" {
+			continue
+		}
+		ar := acceptRange{mimeType: mimeType, q: 1}
+		for _, p := range segments[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) == 2 && kv[0] == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					ar.q = q
+				}
+			}
+		}
+		ranges = append(ranges, ar)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// NewRendererDecorator returns a Decorator that overrides the response media types of the router,
+// the first media type is the default if the request has no Accept header.
+func NewRendererDecorator(mimeTypes ...string) Decorator {
+	return Decorator{
+		Catalog:  rendererDecoratorCatalog,
+		MetaData: mimeTypes,
+	}
+}
+
+func routerRenderers(decorators ...Decorator) []string {
+	var mimeTypes []string
+	for _, d := range decorators {
+		if d.Catalog != rendererDecoratorCatalog {
+			continue
+		}
+		if m, ok := d.MetaData.([]string); ok {
+			mimeTypes = m
+		}
+	}
+	return mimeTypes
+}
+
+// negotiateRenderer negotiate the renderer of the request by Accept header,
+// a 406 response will be sent(by the default renderer) if the Accept header refused all the offers(by q=0).
+func negotiateRenderer(s *HostServer, c *gin.Context, requestId string, offers ...string) bool {
+	_, renderer, ok := s.Renderers.Negotiate(c.GetHeader("Accept"), offers...)
+	if !ok {
+		c.Abort()
+		respErr(c, requestId, 0, ErrNotAcceptable.WithDetails(gin.H{
+			"Supported": supportedMimeTypes(s, offers...),
+		}), nil)
+		return false
+	}
+	c.Set(gwRendererKey, renderer)
+	return true
+}
+
+func supportedMimeTypes(s *HostServer, offers ...string) []string {
+	if len(offers) > 0 {
+		return offers
+	}
+	return s.Renderers.MimeTypes()
+}
+
+// renderResp renders the response body by the negotiated renderer of the request.
+//
+// The routers that not registered by gw(such as gw builtin APIs) negotiate by Accept header,
+// the default renderer will be used if the Accept header can not be satisfied.
+//
+// The body are captured(rendered on flushed) if the response are buffered for the After decorators, see ResponseBuffer.
+func renderResp(c *gin.Context, code int, body interface{}) {
+	if w, ok := c.Writer.(*ResponseBuffer); ok {
+		w.capture(code, body)
+		return
+	}
+	c.Render(code, rendererOf(c)(body))
+}
+
+// rendererOf returns the negotiated renderer of the request.
+func rendererOf(c *gin.Context) RendererFunc {
+	if v, ok := c.Get(gwRendererKey); ok {
+		if renderer, ok := v.(RendererFunc); ok {
+			return renderer
+		}
+	}
+	s := getHostServer(c)
+	_, renderer, ok := s.Renderers.Negotiate(c.GetHeader("Accept"))
+	if !ok {
+		_, renderer = s.Renderers.Default()
+	}
+	return renderer
+}
+
+// Response represents the response body(envelope) of gw APIs, it's same in every media type.
+// The DefaultRespBodyBuildFunc returns it as a gin.H, it's encoded as Response by the XML renderer.
+type Response struct {
+	Status    int         `json:"Status" yaml:"Status" codec:"Status"`
+	Code      string      `json:"Code,omitempty" yaml:"Code,omitempty" codec:"Code,omitempty"`
+	Error     interface{} `json:"Error" yaml:"Error" codec:"Error"`
+	RequestId string      `json:"RequestId" yaml:"RequestId" codec:"RequestId"`
+	Payload   interface{} `json:"Payload" yaml:"Payload" codec:"Payload"`
+}
+
+// responseOf returns the Response of a gin.H envelope(the DefaultRespBodyBuildFunc result),
+// ok is false if the h has not the envelope keys.
+func responseOf(h gin.H) (Response, bool) {
+	status, ok := h["Status"].(int)
+	if !ok {
+		return Response{}, false
+	}
+	requestId, ok := h["RequestId"].(string)
+	if !ok {
+		return Response{}, false
+	}
+	if _, ok = h["Payload"]; !ok {
+		return Response{}, false
+	}
+	code, _ := h["Code"].(string)
+	return Response{
+		Status:    status,
+		Code:      code,
+		Error:     h["Error"],
+		RequestId: requestId,
+		Payload:   h["Payload"],
+	}, true
+}
+
+// xmlBodyOf returns the Response of the gin.H envelope, so the XML body are <Response>(gin.H are encoded as <map>).
+func xmlBodyOf(body interface{}) interface{} {
+	if h, ok := body.(gin.H); ok {
+		if resp, ok := responseOf(h); ok {
+			return resp
+		}
+	}
+	return body
+}
+
+// MarshalXML encodes the Response as <Response>, the maps(such as gin.H) of Payload are encoded as elements.
+func (r Response) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "Response"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"Status", r.Status},
+		{"Code", r.Code},
+		{"Error", r.Error},
+		{"RequestId", r.RequestId},
+		{"Payload", r.Payload},
+	} {
+		if f.name == "Code" && r.Code == "" {
+			continue
+		}
+		if err := encodeXMLElement(e, f.name, reflect.ValueOf(f.value)); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeXMLElement(e *xml.Encoder, name string, val reflect.Value) error {
+	for val.IsValid() && (val.Kind() == reflect.Interface || val.Kind() == reflect.Ptr) {
+		if val.IsNil() {
+			return nil
+		}
+		if _, ok := val.Interface().(xml.Marshaler); ok && val.Kind() == reflect.Ptr {
+			break
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch val.Kind() {
+	case reflect.Map:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			if err := encodeXMLElement(e, fmt.Sprint(k.Interface()), val.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return e.EncodeElement(val.Interface(), start)
+		}
+		for i := 0; i < val.Len(); i++ {
+			if err := encodeXMLElement(e, name, val.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return e.EncodeElement(val.Interface(), start)
+}
+
+// bindingOf returns the binding of request body by the request Content-Type, supports JSON, XML, YAML and MessagePack.
+func bindingOf(method, contentType string) binding.Binding {
+	switch contentType {
+	case MIMEYAML, "application/yaml", "text/yaml":
+		return binding.YAML
+	case MIMEMsgPack, binding.MIMEMSGPACK:
+		return binding.MsgPack
+	}
+	return binding.Default(method, contentType)
+}
@@ -0,0 +1,63 @@
+package gw_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type renderUser struct {
+	ID   uint64
+	Name string
+}
+
+func renderGetUser(c *gw.Context) {
+	c.JSON200(renderUser{ID: 1, Name: "gw"})
+}
+
+func renderPing(c *gw.Context) {
+	c.JSON200("pong")
+}
+
+func TestServer_Renderers(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("user", renderGetUser)
+			router.GET("ping", renderPing, gw.NewRendererDecorator(gw.MIMEYAML, gin.MIMEJSON))
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+
+	resp := client.WithHeader("Accept", "application/xml").Call("renderGetUser", nil, nil).AssertStatus(http.StatusOK)
+	assert.Equal(t, "application/xml; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(resp.Body), "<Payload><ID>1</ID><Name>gw</Name></Payload>")
+
+	resp = client.WithHeader("Accept", "application/msgpack").Call("renderGetUser", nil, nil).AssertStatus(http.StatusOK)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/msgpack")
+
+	// the types that has no renderer, and the browsers get the route's first renderer.
+	resp = client.WithHeader("Accept", "text/html").Call("renderGetUser", nil, nil).AssertOK()
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	resp = client.WithHeader("Accept", "text/plain").Call("renderGetUser", nil, nil).AssertOK()
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	browser := client.WithHeader("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	resp = browser.Call("renderGetUser", nil, nil).AssertOK()
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	resp = browser.Call("renderPing", nil, nil).AssertStatus(http.StatusOK)
+	assert.Equal(t, "application/x-yaml; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	resp = client.WithHeader("Accept", "application/json;q=0").Call("renderGetUser", nil, nil).AssertStatus(http.StatusOK)
+	assert.NotContains(t, resp.Header.Get("Content-Type"), "json")
+	client.WithHeader("Accept", "*/*;q=0").Call("renderGetUser", nil, nil).
+		AssertError(http.StatusNotAcceptable, gw.ErrNotAcceptable.Error())
+
+	// per-route override.
+	resp = client.Call("renderPing", nil, nil).AssertStatus(http.StatusOK)
+	assert.Equal(t, "application/x-yaml; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(resp.Body), "Payload: pong")
+	client.WithHeader("Accept", "application/json").Call("renderPing", nil, nil).AssertOK()
+	client.WithHeader("Accept", "application/xml").Call("renderPing", nil, nil).AssertStatus(http.StatusNotAcceptable)
+}
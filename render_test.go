@@ -0,0 +1,79 @@
+package gw
+
+import (
+	"encoding/xml"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRenderers_Negotiate(t *testing.T) {
+	r := NewRenderers()
+	mime, _, ok := r.Negotiate("")
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEJSON, mime)
+
+	// the browsers(prefers text/html) get the route's first renderer.
+	browser := "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	mime, _, ok = r.Negotiate(browser)
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEJSON, mime)
+	mime, _, ok = r.Negotiate(browser, MIMEYAML, gin.MIMEJSON)
+	assert.True(t, ok)
+	assert.Equal(t, MIMEYAML, mime)
+	mime, _, ok = r.Negotiate("text/html")
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEJSON, mime)
+	mime, _, ok = r.Negotiate("text/plain", gin.MIMEXML, gin.MIMEJSON)
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEXML, mime)
+
+	// the explicitly listed types of the highest quality are preferred.
+	mime, _, ok = r.Negotiate("application/xml, */*;q=0.1")
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEXML, mime)
+	mime, _, ok = r.Negotiate("text/html, application/xml")
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEXML, mime)
+	mime, _, ok = r.Negotiate("application/*, application/json", gin.MIMEXML, gin.MIMEJSON)
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEJSON, mime)
+	mime, _, ok = r.Negotiate("application/*;q=0.5, application/x-yaml")
+	assert.True(t, ok)
+	assert.Equal(t, MIMEYAML, mime)
+
+	// the wildcards get the first offer.
+	mime, _, ok = r.Negotiate("application/json;q=0.5, */*", gin.MIMEXML, gin.MIMEJSON)
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEXML, mime)
+	mime, _, ok = r.Negotiate("*/*", MIMEMsgPack, gin.MIMEJSON)
+	assert.True(t, ok)
+	assert.Equal(t, MIMEMsgPack, mime)
+
+	// the refused(q=0) offers, and the registered types that not offered are not acceptable.
+	_, _, ok = r.Negotiate("application/xml", MIMEYAML, gin.MIMEJSON)
+	assert.False(t, ok)
+	mime, _, ok = r.Negotiate("application/json;q=0", gin.MIMEJSON, gin.MIMEXML)
+	assert.True(t, ok)
+	assert.Equal(t, gin.MIMEXML, mime)
+	_, _, ok = r.Negotiate("application/json;q=0", gin.MIMEJSON)
+	assert.False(t, ok)
+	_, _, ok = r.Negotiate("application/json;q=0, */*", gin.MIMEJSON)
+	assert.False(t, ok)
+	_, _, ok = r.Negotiate("*/*;q=0")
+	assert.False(t, ok)
+}
+
+func TestResponse_MarshalXML(t *testing.T) {
+	body := DefaultRespBodyBuildFunc(0, "req-1", nil, gin.H{
+		"Names": []string{"a", "b"},
+		"User":  gin.H{"ID": 1},
+	})
+	// the envelope are gin.H, it's encoded as <Response> by the XML renderer.
+	_, ok := body.(gin.H)
+	assert.True(t, ok)
+	b, err := xml.Marshal(xmlBodyOf(body))
+	assert.Nil(t, err)
+	assert.Equal(t, "<Response><Status>0</Status><RequestId>req-1</RequestId>"+
+		"<Payload><Names>a</Names><Names>b</Names><User><ID>1</ID></User></Payload></Response>", string(b))
+}
@@ -8,7 +8,7 @@ var requestIdStateKey = "gw-req-id"
 
 func getRequestId(s *HostServer, c *gin.Context) string {
 	shouldSave := true
-	requestID := c.GetHeader(s.conf.Settings.HeaderKey.RequestIDKey)
+	requestID := c.GetHeader(s.Config().Settings.HeaderKey.RequestIDKey)
 	if requestID == "" {
 		requestID = c.GetString(requestIdStateKey)
 		if requestID == "" {
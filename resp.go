@@ -153,22 +153,34 @@ func (c *Context) JSON500PayloadMsg(status int, errMsg interface{}, payload inte
 	c.StatusJSON(http.StatusInternalServerError, status, errMsg, payload)
 }
 
-// StatusJSON response a JSON formatter to client.
+// StatusJSON response a formatter to client, the formatter(JSON, XML, YAML, MessagePack...) are negotiated
+// by the request Accept header, see Renderers.
+// The errMsg are converted to *Error if code >= 400, a *Error errMsg's http status overrides the code.
 // Auto call c.Abort() when code < 200 || code > 202.
 func (c *Context) StatusJSON(code int, status int, errMsg interface{}, payload interface{}) {
+	if code >= http.StatusBadRequest && errMsg != nil {
+		respErr(c.Context, c.RequestId(), status, errorOf(code, errMsg), payload)
+		return
+	}
 	s := c.HostServer()
-	c.Context.JSON(code, s.RespBodyBuildFunc(status, c.RequestId(), errMsg, payload))
+	renderResp(c.Context, code, s.RespBodyBuildFunc(status, c.RequestId(), errMsg, payload))
 }
 
+// DefaultRespBodyBuildFunc returns the gin.H envelope(Status, Error, RequestId, Payload) of gw APIs,
+// the stable machine code are set as Code if the errMsg is a *Error.
 func DefaultRespBodyBuildFunc(status int, requestID string, errMsg interface{}, payload interface{}) interface{} {
 	var errMsgStr interface{} = nil
 	if errMsg != nil {
 		errMsgStr = fmt.Sprintf("%s", errMsg)
 	}
-	return gin.H{
+	body := gin.H{
 		"Status":    status,
 		"Error":     errMsgStr,
 		"RequestId": requestID,
 		"Payload":   payload,
 	}
+	if e, ok := errMsg.(*Error); ok {
+		body["Code"] = e.Code
+	}
+	return body
 }
@@ -0,0 +1,297 @@
+package gw
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+)
+
+const (
+	responseBufferKey = "gw-response-buffer"
+)
+
+// ResponseBuffer represents a gin.ResponseWriter that buffers the response of the handler,
+// it's used by the routers that has After decorators, the buffered response are sent after the After decorators called,
+// so the After decorators can inspect and rewrite(or replace) the status, headers and body of the response.
+//
+// The gw responses(such as c.JSON200(...), c.JSON400Msg(...)) are captured as the body object(RespBodyBuildFunc result),
+// it's rendered by the negotiated renderer when the response are sent, the others(such as c.String(...)) are buffered as bytes.
+//
+// Usage(response masking):
+//
+//	func MaskDecorator() gw.Decorator {
+//		return gw.Decorator{
+//			Catalog: "mask",
+//			After: func(c *gw.Context) (int, error, interface{}) {
+//				if buf := c.ResponseBuffer(); buf != nil {
+//					if user, ok := buf.Payload().(*User); ok {
+//						buf.SetPayload(user.Masked())
+//					}
+//				}
+//				return 0, nil, nil
+//			},
+//		}
+//	}
+type ResponseBuffer struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	header   http.Header
+	snapshot http.Header
+	status   int
+	written  bool
+	raw      bytes.Buffer
+	body     interface{}
+	captured bool
+	rendered bool
+}
+
+func newResponseBuffer(c *gin.Context) *ResponseBuffer {
+	snapshot := c.Writer.Header().Clone()
+	w := &ResponseBuffer{
+		ResponseWriter: c.Writer,
+		c:              c,
+		header:         snapshot.Clone(),
+		snapshot:       snapshot,
+	}
+	c.Writer = w
+	c.Set(responseBufferKey, w)
+	return w
+}
+
+// ResponseBuffer returns the buffered response of the request, it's used by the After decorators.
+// nil if the response are not buffered(the router has no After decorators, or it's a stream/WebSocket router).
+func (c *Context) ResponseBuffer() *ResponseBuffer {
+	if w, ok := c.Get(responseBufferKey); ok {
+		return w.(*ResponseBuffer)
+	}
+	return nil
+}
+
+func (w *ResponseBuffer) Header() http.Header {
+	return w.header
+}
+
+func (w *ResponseBuffer) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *ResponseBuffer) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *ResponseBuffer) Write(b []byte) (int, error) {
+	w.written = true
+	w.captured = false
+	return w.raw.Write(b)
+}
+
+func (w *ResponseBuffer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush does nothing, the response are sent after the After decorators called.
+func (w *ResponseBuffer) Flush() {
+}
+
+func (w *ResponseBuffer) Written() bool {
+	return w.written
+}
+
+func (w *ResponseBuffer) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.raw.Len()
+}
+
+// Status returns the http status of the response, 200 if it's not set.
+func (w *ResponseBuffer) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// SetStatus overrides the http status of the response.
+func (w *ResponseBuffer) SetStatus(code int) {
+	w.status = code
+	w.written = true
+}
+
+// Body returns the captured body object of the gw responses, nil if the response are written as bytes.
+func (w *ResponseBuffer) Body() interface{} {
+	if !w.captured {
+		return nil
+	}
+	return w.body
+}
+
+// SetBody replaces the response body by a body object, it's rendered by the negotiated renderer.
+func (w *ResponseBuffer) SetBody(body interface{}) {
+	w.raw.Reset()
+	w.body = body
+	w.captured = true
+	w.rendered = false
+	w.written = true
+}
+
+// Payload returns the Payload of the captured envelope(the default RespBodyBuildFunc result or a Response),
+// nil if it's not a envelope.
+func (w *ResponseBuffer) Payload() interface{} {
+	switch resp := w.Body().(type) {
+	case gin.H:
+		if r, ok := responseOf(resp); ok {
+			return r.Payload
+		}
+	case Response:
+		return resp.Payload
+	case *Response:
+		return resp.Payload
+	}
+	return nil
+}
+
+// SetPayload replaces the Payload of the captured envelope, returns false if the body is not a envelope.
+func (w *ResponseBuffer) SetPayload(payload interface{}) bool {
+	switch resp := w.Body().(type) {
+	case gin.H:
+		if _, ok := responseOf(resp); !ok {
+			return false
+		}
+		body := make(gin.H, len(resp))
+		for k, v := range resp {
+			body[k] = v
+		}
+		body["Payload"] = payload
+		w.SetBody(body)
+	case Response:
+		resp.Payload = payload
+		w.SetBody(resp)
+	case *Response:
+		resp.Payload = payload
+		w.SetBody(resp)
+	default:
+		return false
+	}
+	return true
+}
+
+// Bytes returns the response body bytes, the captured body object are rendered by the negotiated renderer.
+func (w *ResponseBuffer) Bytes() []byte {
+	if w.captured && !w.rendered {
+		w.raw.Reset()
+		w.rendered = true
+		r := rendererOf(w.c)(w.body)
+		if bodyAllowedForStatus(w.Status()) {
+			if err := r.Render(bufferRenderWriter{w}); err != nil {
+				_ = w.c.Error(err)
+			}
+		} else {
+			r.WriteContentType(w)
+		}
+	}
+	return w.raw.Bytes()
+}
+
+// SetBytes replaces the response body by b.
+func (w *ResponseBuffer) SetBytes(b []byte) {
+	w.raw.Reset()
+	w.raw.Write(b)
+	w.body = nil
+	w.captured = false
+	w.written = true
+}
+
+// Reset discards the buffered response(status, headers and body), the headers that set before buffered are kept.
+func (w *ResponseBuffer) Reset() {
+	w.header = w.snapshot.Clone()
+	w.status = 0
+	w.written = false
+	w.raw.Reset()
+	w.body = nil
+	w.captured = false
+	w.rendered = false
+}
+
+// changedHeader returns the headers that added or changed after the response buffered(by the handler and decorators),
+// the per-request headers that set by the hooks before(such as X-Trace-Id, RateLimit-*, CORS) are excluded.
+func (w *ResponseBuffer) changedHeader() http.Header {
+	header := make(http.Header)
+	for k, v := range w.header {
+		if !reflect.DeepEqual(v, w.snapshot[k]) {
+			header[k] = append([]string(nil), v...)
+		}
+	}
+	return header
+}
+
+// replayHeader sets the replayed headers into dst, the headers that dst already has are not overwritten.
+func replayHeader(dst, header http.Header) {
+	for k, v := range header {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
+
+func (w *ResponseBuffer) capture(code int, body interface{}) {
+	w.SetBody(body)
+	w.status = code
+}
+
+// flush sends the buffered response by the underlying writer, the c.Writer are restored.
+func (w *ResponseBuffer) flush() {
+	w.c.Writer = w.ResponseWriter
+	header := w.ResponseWriter.Header()
+	for k := range header {
+		if _, ok := w.header[k]; !ok {
+			header.Del(k)
+		}
+	}
+	for k, v := range w.header {
+		header[k] = v
+	}
+	if !w.written {
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		return
+	}
+	if w.captured && !w.rendered {
+		w.c.Render(w.Status(), rendererOf(w.c)(w.body))
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.Status())
+	if w.raw.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if _, err := w.ResponseWriter.Write(w.raw.Bytes()); err != nil {
+		_ = w.c.Error(err)
+	}
+}
+
+// bufferRenderWriter represents a http.ResponseWriter that renders the captured body into the buffer.
+type bufferRenderWriter struct {
+	*ResponseBuffer
+}
+
+func (w bufferRenderWriter) Write(b []byte) (int, error) {
+	return w.raw.Write(b)
+}
+
+// bodyAllowedForStatus is a copy of gin's bodyAllowedForStatus.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package gw_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type respBufferUser struct {
+	Name  string
+	Phone string
+}
+
+func respBufferProfile(c *gw.Context) {
+	c.Header("ETag", `"profile"`)
+	c.JSON200(respBufferUser{Name: "gw", Phone: "13800001234"})
+}
+
+func newRespBufferMaskDecorator() gw.Decorator {
+	return gw.Decorator{
+		Catalog: "gw_test_mask_phone",
+		After: func(c *gw.Context) (int, error, interface{}) {
+			buf := c.ResponseBuffer()
+			if profile, ok := buf.Payload().(respBufferUser); ok {
+				profile.Phone = profile.Phone[:3] + "****" + profile.Phone[7:]
+				buf.SetPayload(profile)
+			}
+			return 0, nil, nil
+		},
+	}
+}
+
+// newRespBufferOrderDecorator appends the name to X-Order header of the response, it's used to check the order of the decorators.
+func newRespBufferOrderDecorator(name string, priority int) gw.Decorator {
+	return gw.Decorator{
+		Catalog:  "gw_test_order",
+		Priority: priority,
+		After: func(c *gw.Context) (int, error, interface{}) {
+			c.ResponseBuffer().Header().Add("X-Order", name)
+			return 0, nil, nil
+		},
+	}
+}
+
+func newRespBufferDenyDecorator() gw.Decorator {
+	return gw.Decorator{
+		Catalog: "gw_test_deny",
+		After: func(c *gw.Context) (int, error, interface{}) {
+			if c.Query("deny") != "" {
+				return http.StatusForbidden, gw.ErrPermissionDenied, nil
+			}
+			return 0, nil, nil
+		},
+	}
+}
+
+func TestServer_AfterDecorators(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("profile", respBufferProfile, newRespBufferOrderDecorator("inner", 1), newRespBufferMaskDecorator(),
+				newRespBufferOrderDecorator("outer", -1), newRespBufferDenyDecorator())
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	var profile respBufferUser
+	resp := client.Call("respBufferProfile", nil, nil).AssertOK().DecodePayload(&profile)
+	assert.Equal(t, respBufferUser{Name: "gw", Phone: "138****1234"}, profile)
+	assert.Equal(t, `"profile"`, resp.Header.Get("ETag"))
+	// the After of the lower priority decorators are called last.
+	assert.Equal(t, []string{"inner", "outer"}, resp.Header.Values("X-Order"))
+
+	// the response of the handler are replaced by the error of the After decorators.
+	resp = client.Call("respBufferProfile", gwtest.Params{"deny": "1"}, nil).AssertError(http.StatusForbidden, gw.ErrPermissionDenied.Error())
+	assert.Equal(t, gw.ErrCodePermissionDenied, resp.Envelope().Code)
+	assert.Empty(t, resp.Header.Get("ETag"))
+	assert.NotContains(t, string(resp.Body), "13800001234")
+	assert.Nil(t, json.NewDecoder(bytes.NewReader(resp.Body)).Decode(&map[string]interface{}{}))
+	assert.Equal(t, 1, strings.Count(string(resp.Body), "RequestId"))
+}
@@ -0,0 +1,96 @@
+package gw
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newResponseBufferTestContext() (*gin.Context, *httptest.ResponseRecorder, *ResponseBuffer) {
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Set(gwRendererKey, RendererFunc(func(body interface{}) render.Render {
+		return render.JSON{Data: body}
+	}))
+	c.Header("X-Before", "1")
+	return c, rec, newResponseBuffer(c)
+}
+
+func TestResponseBuffer_Capture(t *testing.T) {
+	c, rec, buf := newResponseBufferTestContext()
+	renderResp(c, http.StatusOK, DefaultRespBodyBuildFunc(0, "r1", nil, "secret"))
+	assert.True(t, c.Writer.Written())
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, "secret", buf.Payload())
+	assert.True(t, buf.SetPayload("***"))
+	buf.Header().Set("X-After", "1")
+	assert.Equal(t, `{"Error":null,"Payload":"***","RequestId":"r1","Status":0}`, string(buf.Bytes()))
+	buf.flush()
+	assert.Equal(t, c.Writer, buf.ResponseWriter)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-Before"))
+	assert.Equal(t, "1", rec.Header().Get("X-After"))
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"Error":null,"Payload":"***","RequestId":"r1","Status":0}`, rec.Body.String())
+}
+
+func TestResponseBuffer_Raw(t *testing.T) {
+	c, rec, buf := newResponseBufferTestContext()
+	c.String(http.StatusCreated, "hello")
+	assert.Nil(t, buf.Body())
+	assert.Nil(t, buf.Payload())
+	assert.False(t, buf.SetPayload("x"))
+	assert.Equal(t, http.StatusCreated, buf.Status())
+	assert.Equal(t, "hello", string(buf.Bytes()))
+	buf.SetBytes([]byte("world"))
+	buf.flush()
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "world", rec.Body.String())
+}
+
+func TestResponseBuffer_Reset(t *testing.T) {
+	c, rec, buf := newResponseBufferTestContext()
+	c.Header("ETag", `"v1"`)
+	renderResp(c, http.StatusOK, Response{Payload: "ok"})
+	buf.Reset()
+	assert.False(t, buf.Written())
+	assert.Empty(t, buf.Header().Get("ETag"))
+	renderResp(c, http.StatusForbidden, Response{Status: http.StatusForbidden, Error: "denied"})
+	buf.flush()
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, "1", rec.Header().Get("X-Before"))
+	assert.Equal(t, `{"Status":403,"Error":"denied","RequestId":"","Payload":null}`, rec.Body.String())
+
+	c, rec, buf = newResponseBufferTestContext()
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	assert.Empty(t, buf.Bytes())
+	buf.flush()
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestSplitDecorators(t *testing.T) {
+	handler := func(c *Context) (int, error, interface{}) { return 0, nil, nil }
+	before, after := splitDecorators(
+		Decorator{Catalog: "a", Before: handler, After: handler},
+		Decorator{Catalog: "b", Priority: -1, After: handler},
+		Decorator{Catalog: "c", Before: handler},
+		Decorator{Catalog: "d", Priority: -1, Before: handler},
+	)
+	var catalogs []string
+	for _, d := range before {
+		catalogs = append(catalogs, d.Catalog)
+	}
+	assert.Equal(t, []string{"d", "a", "c"}, catalogs)
+	catalogs = nil
+	for _, d := range after {
+		catalogs = append(catalogs, d.Catalog)
+	}
+	assert.Equal(t, []string{"b", "a"}, catalogs)
+}
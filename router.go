@@ -1,16 +1,20 @@
 package gw
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/oceanho/gw/conf"
 	"github.com/oceanho/gw/logger"
 	"net/http"
 	"path"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +33,7 @@ type Handler func(ctx *Context)
 // Context represents a gw Context object, it's extension from gin.Context.
 type Context struct {
 	*gin.Context
+	ctx        context.Context
 	requestId  string
 	user       User
 	store      IStore
@@ -38,6 +43,11 @@ type Context struct {
 	params     map[string]interface{}
 	bindModels map[string]interface{}
 	server     *HostServer
+	stream     *EventStream
+	webSocket  *WebSocketConn
+	spanScope  atomic.Value
+	// validateErr is the error(such as db errors) of the validators, see Validate.
+	validateErr error
 }
 
 // ServerState represents a Server state context object.
@@ -59,6 +69,29 @@ func (c *Context) Store() IStore {
 	return c.store
 }
 
+// Deadline returns the deadline of the request, It's implementation of context.Context.
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.ctx.Deadline()
+}
+
+// Done returns a channel that's closed when the request timeout or canceled, It's implementation of context.Context.
+func (c *Context) Done() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+// Err returns the error of the request context, It's implementation of context.Context.
+func (c *Context) Err() error {
+	return c.ctx.Err()
+}
+
+// Value returns the value of gin.Context's keys, or the value of the request context(carries the current span).
+func (c *Context) Value(key interface{}) interface{} {
+	if val := c.Context.Value(key); val != nil {
+		return val
+	}
+	return c.spanContext().Value(key)
+}
+
 func (c *Context) ResolveByTyper(typer reflect.Type) interface{} {
 	return c.server.DIProvider.ResolveByTyperWithState(c.store, typer)
 }
@@ -71,6 +104,7 @@ type Router struct {
 	router        *gin.RouterGroup
 	currentRouter *gin.RouterGroup
 	routerInfos   []RouterInfo
+	appName       string
 }
 
 // RouterGroup represents a gw's Group Router info.
@@ -85,7 +119,13 @@ type RouterInfo struct {
 	Handler           Handler
 	Decorators        []Decorator
 	Permissions       []Permission
+	Timeout           time.Duration // 0 means uses Settings.TimeoutControl.HTTP, negative means no timeout.
+	AppName           string
 	handlerActionName string
+	renderers         []string
+	isStream          bool
+	isWebSocket       bool
+	schema            *apiSchema
 	beforeDecorators  []Decorator
 	afterDecorators   []Decorator
 }
@@ -98,6 +138,11 @@ func (r *RouterInfo) String() string {
 		"[decorators(%d before, %d after)]", r.Method, r.UrlPath, r.handlerActionName, len(before), len(after))
 }
 
+// Name returns the handler action name of the router.
+func (r *RouterInfo) Name() string {
+	return r.handlerActionName
+}
+
 func (router *Router) createRouter(method, relativePath string, handler Handler, handlerActionName string, decorators ...Decorator) {
 	urlPath := path.Join(router.currentRouter.BasePath(), relativePath)
 	//var _decorators []Decorator
@@ -125,16 +170,15 @@ func (router *Router) createRouter(method, relativePath string, handler Handler,
 		handlerActionName = getHandlerFullName(handler)
 	}
 	routerInfo.Permissions = perms
+	routerInfo.Timeout = routerTimeout(decorators...)
+	routerInfo.AppName = router.appName
+	routerInfo.schema = routerApiSchema(decorators...)
+	routerInfo.renderers = routerRenderers(decorators...)
+	routerInfo.isStream = isEventStreamRouter(decorators...)
+	routerInfo.isWebSocket = isWebSocketRouter(decorators...)
 	routerInfo.afterDecorators = afterDecorators
 	routerInfo.beforeDecorators = beforeDecorators
 	routerInfo.handlerActionName = handlerActionName
-	if method == "any" {
-		router.currentRouter.Any(relativePath, func(c *gin.Context) {
-			c.Set(gwRouterInfoKey, routerInfo)
-			handle(c)
-		})
-		return
-	}
 	if method == "group" {
 		router.currentRouter.Group(relativePath, func(c *gin.Context) {
 			c.Set(gwRouterInfoKey, routerInfo)
@@ -143,10 +187,15 @@ func (router *Router) createRouter(method, relativePath string, handler Handler,
 		return
 	}
 	// gin router
-	router.currentRouter.Handle(method, relativePath, func(c *gin.Context) {
+	ginHandler := func(c *gin.Context) {
 		c.Set(gwRouterInfoKey, routerInfo)
 		handle(c)
-	})
+	}
+	if method == "any" {
+		router.currentRouter.Any(relativePath, ginHandler)
+	} else {
+		router.currentRouter.Handle(method, relativePath, ginHandler)
+	}
 	router.locker.Lock()
 	defer router.locker.Unlock()
 	router.routerInfos = append(router.routerInfos, routerInfo)
@@ -294,19 +343,26 @@ func (c *Context) MustParam(key string, out *string) error {
 // It's auto response 400, invalid request parameter to client if bind fail.
 // returns a error message for c.Bind(...).
 func (c *Context) Bind(out interface{}) error {
-	if err := c.Context.Bind(out); err != nil {
-		c.JSON400Msg(400, fmt.Sprintf("invalid request parameters, details: \n%v", err))
-		return err
-	}
-	return nil
+	b := bindingOf(c.Request.Method, c.ContentType())
+	return c.bindWith(out, b)
 }
 
 // BindQuery define a Api that can be bind data to out object by gin.Context's Bind(...) APIs.
 // It's auto response 400, invalid request parameter to client if bind fail.
 // returns a error message for c.BindQuery(...).
 func (c *Context) BindQuery(out interface{}) error {
-	if err := c.Context.BindQuery(out); err != nil {
-		c.JSON400Msg(400, fmt.Sprintf("invalid request parameters, details: \n%v", err))
+	return c.bindWith(out, binding.Query)
+}
+
+// bindWith binds the out object by binding, then validate it by c.Validate(...).
+// The structured field errors(ValidationErrors) are responded if the out object are invalid.
+func (c *Context) bindWith(out interface{}, b binding.Binding) error {
+	err := skipValidationErr(c.ShouldBindWith(out, b))
+	if err == nil {
+		err = c.Validate(out)
+	}
+	if err != nil {
+		c.respBindErr(err)
 		return err
 	}
 	return nil
@@ -317,7 +373,7 @@ func (c *Context) HostServer() *HostServer {
 }
 
 func (c *Context) AppConfig() *conf.ApplicationConfig {
-	return c.server.conf
+	return c.server.Config()
 }
 
 // handle code APIs.
@@ -334,25 +390,57 @@ func handle(c *gin.Context) {
 	// action before Decorators
 	var s = getHostServer(c)
 	var requestID = getRequestId(s, c)
+	if !router.isStream && !router.isWebSocket && !negotiateRenderer(s, c, requestID, router.renderers...) {
+		return
+	}
 	var ctx = makeCtx(c, requestID)
+	var cancel = newRequestContext(s, ctx, router.Timeout)
+	defer cancel()
+	// the response are buffered for the After decorators, so they can rewrite it.
+	var buf *ResponseBuffer
+	if len(router.afterDecorators) > 0 && !router.isStream && !router.isWebSocket {
+		buf = newResponseBuffer(c)
+		defer func() {
+			if r := recover(); r != nil {
+				c.Writer = buf.ResponseWriter
+				panic(r)
+			}
+			buf.flush()
+		}()
+	}
 	for _, d := range router.beforeDecorators {
+		end := ctx.startSpan(decoratorSpanName("before", d))
 		status, err, payload = d.Before(ctx)
+		end(err)
 		if err != nil || status != 0 {
 			shouldStop = true
 			break
 		}
 	}
 	if shouldStop {
+		c.Abort()
+		// the decorator has responded, such as replays the stored response.
+		if c.Writer.Written() {
+			return
+		}
 		if payload == "" {
 			payload = "caller decorator fail."
 		}
-		status, body := parseErrToRespBody(s, requestID, payload, err)
-		c.JSON(status, body)
+		respErr(c, requestID, 0, decoratorErr(status, err), payload)
+		return
+	}
+
+	if abortIfDone(s, ctx) {
 		return
 	}
 
 	// process Action handler.
+	end := ctx.startSpan("handler " + router.Name())
 	router.Handler(ctx)
+	end(nil)
+	if abortIfDone(s, ctx) {
+		return
+	}
 
 	// action after Decorators
 	l := len(router.afterDecorators)
@@ -361,7 +449,10 @@ func handle(c *gin.Context) {
 	}
 	shouldStop = false
 	for i := l - 1; i >= 0; i-- {
-		status, err, payload = router.afterDecorators[i].After(ctx)
+		d := router.afterDecorators[i]
+		end := ctx.startSpan(decoratorSpanName("after", d))
+		status, err, payload = d.After(ctx)
+		end(err)
 		if err != nil || status != 0 {
 			shouldStop = true
 			break
@@ -371,21 +462,32 @@ func handle(c *gin.Context) {
 		if payload == "" {
 			payload = "caller decorator fail."
 		}
-		status, body := parseErrToRespBody(s, requestID, payload, err)
-		c.JSON(status, body)
+		// the response of the handler are replaced by the error.
+		if buf != nil {
+			buf.Reset()
+		}
+		respErr(c, requestID, 0, decoratorErr(status, err), payload)
 	}
 }
 
-func parseErrToRespBody(s *HostServer, requestID string, msgBody interface{}, err error) (int, interface{}) {
-	var status = http.StatusBadRequest
-	if err == ErrPermissionDenied {
-		status = http.StatusForbidden
-	} else if err == ErrInternalServerError {
-		status = http.StatusInternalServerError
-	} else if err == ErrUnauthorized {
-		status = http.StatusUnauthorized
+// decoratorSpanName returns the span name of the decorator, such as "decorator.before permission".
+func decoratorSpanName(stage string, d Decorator) string {
+	if d.Catalog == "" {
+		return "decorator." + stage
 	}
-	return status, s.RespBodyBuildFunc(status, requestID, err.Error(), msgBody)
+	return "decorator." + stage + " " + d.Catalog
+}
+
+// decoratorErr returns the *Error of the decorator result, the *Error(such as ErrPermissionDenied) are returned as it is,
+// others are converted by the http status(400 if the status are not a http error status).
+func decoratorErr(status int, err error) *Error {
+	if status < http.StatusBadRequest || status > 599 {
+		status = http.StatusBadRequest
+	}
+	if err == nil {
+		return NewError(status, errCodeOf(status), http.StatusText(status))
+	}
+	return errorOf(status, err)
 }
 
 func makeCtx(c *gin.Context, requestID string) *Context {
@@ -404,9 +506,9 @@ func makeCtx(c *gin.Context, requestID string) *Context {
 		bindModels: make(map[string]interface{}),
 	}
 	var dbSetups []StoreDbSetupHandler
-	dbSetups = append(dbSetups, s.storeDbSetupHandler)
+	dbSetups = append(dbSetups, storeDbContextSetup, s.storeDbSetupHandler)
 	var cacheSetups []StoreCacheSetupHandler
-	cacheSetups = append(cacheSetups, s.storeCacheSetupHandler)
+	cacheSetups = append(cacheSetups, storeCacheContextSetup, s.storeCacheSetupHandler)
 	store := &backendWrapper{
 		user:                    user,
 		ctx:                     ctx,
@@ -418,8 +520,14 @@ func makeCtx(c *gin.Context, requestID string) *Context {
 	return ctx
 }
 
+// splitDecorators returns the Before and After decorators that ordered by the Priority.
 func splitDecorators(decorators ...Decorator) (before, after []Decorator) {
-	for _, d := range decorators {
+	var sorted = make([]Decorator, len(decorators))
+	copy(sorted, decorators)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	for _, d := range sorted {
 		if d.After != nil {
 			after = append(after, d)
 		}
@@ -0,0 +1,244 @@
+package gw
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	eventStreamDecoratorCatalog = "gw_framework_event_stream"
+	MIMEEventStream             = "text/event-stream"
+	DefaultEventStreamHeartbeat = 15 * time.Second
+)
+
+var (
+	ErrEventStreamClosed = fmt.Errorf("event stream closed")
+)
+
+// SSEvent represents a Server-Sent Event.
+type SSEvent struct {
+	Id    string        // empty means uses the auto-increment id of the EventStream.
+	Event string        // empty means the "message" event.
+	Data  interface{}   // string, []byte are sent as it is, others are encoded as JSON.
+	Retry time.Duration // the reconnection time hint of the client, 0 means not sent.
+}
+
+// EventStream represents a Server-Sent Events stream writer of the SSE router.
+//
+// The stream are closed when the client disconnected, the server shutting down or the handler returned.
+type EventStream struct {
+	c           *Context
+	locker      sync.Mutex
+	seq         uint64
+	lastEventId string
+	closed      bool
+	done        chan struct{}
+	closeOnce   sync.Once
+	closeCh     chan struct{}
+	heartbeat   chan time.Duration
+	wg          sync.WaitGroup
+}
+
+// SSE register a Server-Sent Events(http GET) router of handler, the handler can be got the stream by ctx.EventStream().
+//
+// The router has no timeout(can be override by NewTimeoutDecorator), decorators are applied as normal routers.
+func (router *Router) SSE(relativePath string, handler Handler, decorators ...Decorator) {
+	var ds = []Decorator{NewTimeoutDecorator(0), {Catalog: eventStreamDecoratorCatalog, MetaData: true}}
+	ds = append(ds, decorators...)
+	router.createRouter(http.MethodGet, relativePath, func(ctx *Context) {
+		ctx.stream = newEventStream(ctx, DefaultEventStreamHeartbeat)
+		defer ctx.stream.Close()
+		handler(ctx)
+	}, getHandlerFullName(handler), ds...)
+}
+
+func isEventStreamRouter(decorators ...Decorator) bool {
+	for _, d := range decorators {
+		if d.Catalog == eventStreamDecoratorCatalog {
+			return true
+		}
+	}
+	return false
+}
+
+// EventStream returns the Server-Sent Events stream writer of the request, nil if the router are not registered by SSE(...).
+func (c *Context) EventStream() *EventStream {
+	return c.stream
+}
+
+func newEventStream(c *Context, heartbeat time.Duration) *EventStream {
+	es := &EventStream{
+		c:         c,
+		done:      make(chan struct{}),
+		closeCh:   make(chan struct{}),
+		heartbeat: make(chan time.Duration),
+	}
+	es.lastEventId = c.GetHeader("Last-Event-ID")
+	if es.lastEventId == "" {
+		es.lastEventId = c.Query("lastEventId")
+	}
+	if seq, err := strconv.ParseUint(es.lastEventId, 10, 64); err == nil {
+		es.seq = seq
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", MIMEEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+	es.wg.Add(1)
+	go es.watch(heartbeat)
+	return es
+}
+
+// watch sends heartbeats, and close the stream when the client disconnected or the server shutting down.
+func (es *EventStream) watch(interval time.Duration) {
+	defer es.wg.Done()
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	var reset = func(d time.Duration) {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		if d > 0 {
+			ticker = time.NewTicker(d)
+			tick = ticker.C
+		}
+	}
+	reset(interval)
+	defer reset(0)
+	for {
+		select {
+		case <-tick:
+			_ = es.write(":heartbeat\n\n")
+		case d := <-es.heartbeat:
+			reset(d)
+		case <-es.c.Request.Context().Done():
+			es.markClosed()
+			return
+		case <-es.c.server.closing:
+			es.markClosed()
+			return
+		case <-es.closeCh:
+			es.markClosed()
+			return
+		}
+	}
+}
+
+func (es *EventStream) markClosed() {
+	es.locker.Lock()
+	defer es.locker.Unlock()
+	if !es.closed {
+		es.closed = true
+		close(es.done)
+	}
+}
+
+// LastEventId returns the Last-Event-ID header(or lastEventId query) of the reconnected client, used for resume.
+func (es *EventStream) LastEventId() string {
+	return es.lastEventId
+}
+
+// Done returns a channel that's closed when the stream closed.
+func (es *EventStream) Done() <-chan struct{} {
+	return es.done
+}
+
+// SetHeartbeat changes the heartbeat(a comment line) interval, d <= 0 means disable heartbeat.
+func (es *EventStream) SetHeartbeat(d time.Duration) {
+	select {
+	case es.heartbeat <- d:
+	case <-es.done:
+	}
+}
+
+// Send sends a event with the auto-increment id.
+func (es *EventStream) Send(event string, data interface{}) error {
+	return es.SendEvent(SSEvent{Event: event, Data: data})
+}
+
+// Retry sends the reconnection time hint to client.
+func (es *EventStream) Retry(d time.Duration) error {
+	return es.write(fmt.Sprintf("retry: %d\n\n", d.Milliseconds()))
+}
+
+// SendEvent sends the event to client.
+func (es *EventStream) SendEvent(e SSEvent) error {
+	var data string
+	switch v := e.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	es.locker.Lock()
+	defer es.locker.Unlock()
+	if es.closed {
+		return ErrEventStreamClosed
+	}
+	id := e.Id
+	if id == "" {
+		es.seq++
+		id = strconv.FormatUint(es.seq, 10)
+	}
+	var sb strings.Builder
+	sb.WriteString("id: ")
+	sb.WriteString(id)
+	sb.WriteString("\n")
+	if e.Event != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(e.Event)
+		sb.WriteString("\n")
+	}
+	if e.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", e.Retry.Milliseconds()))
+	}
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(strings.TrimSuffix(line, "\r"))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return es.writeLocked(sb.String())
+}
+
+func (es *EventStream) write(s string) error {
+	es.locker.Lock()
+	defer es.locker.Unlock()
+	if es.closed {
+		return ErrEventStreamClosed
+	}
+	return es.writeLocked(s)
+}
+
+func (es *EventStream) writeLocked(s string) error {
+	if _, err := es.c.Writer.WriteString(s); err != nil {
+		return err
+	}
+	es.c.Writer.Flush()
+	return nil
+}
+
+// Close closes the stream, it's called automatically when the SSE handler returned.
+func (es *EventStream) Close() {
+	es.closeOnce.Do(func() {
+		close(es.closeCh)
+	})
+	es.wg.Wait()
+}
@@ -0,0 +1,66 @@
+package gw_test
+
+import (
+	"bufio"
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func sseProgress(c *gw.Context) {
+	stream := c.EventStream()
+	start, _ := strconv.Atoi(stream.LastEventId())
+	for i := start + 1; i <= 3; i++ {
+		_ = stream.Send("progress", gin.H{"Percent": i * 100 / 3})
+	}
+	if c.Query("wait") != "" {
+		<-stream.Done()
+	}
+}
+
+func TestServer_SSE(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.SSE("progress", sseProgress)
+		},
+	})
+	server.Client().Call("sseProgress", nil, nil).AssertStatus(http.StatusUnauthorized)
+
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	resp := client.Call("sseProgress", nil, nil).AssertStatus(http.StatusOK)
+	assert.Equal(t, gw.MIMEEventStream, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "id: 1\nevent: progress\ndata: {\"Percent\":33}\n\n"+
+		"id: 2\nevent: progress\ndata: {\"Percent\":66}\n\n"+
+		"id: 3\nevent: progress\ndata: {\"Percent\":100}\n\n", string(resp.Body))
+
+	// resume by Last-Event-ID.
+	resp = client.WithHeader("Last-Event-ID", "2").Call("sseProgress", nil, nil).AssertStatus(http.StatusOK)
+	assert.Equal(t, "id: 3\nevent: progress\ndata: {\"Percent\":100}\n\n", string(resp.Body))
+
+	// the stream closed on server shutdown.
+	req, _ := http.NewRequest(http.MethodGet, server.HTTP.URL+client.Router("sseProgress").UrlPath+"?wait=1", nil)
+	req.Header = client.Header()
+	stream, err := server.HTTP.Client().Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer stream.Body.Close()
+	reader := bufio.NewReader(stream.Body)
+	line, _ := reader.ReadString('\n')
+	assert.Equal(t, "id: 1\n", line)
+	server.HostServer.ShutDown()
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		lines = append(lines, line)
+	}
+	assert.True(t, strings.HasPrefix(lines[len(lines)-2], "data: {\"Percent\":100}"))
+}
@@ -0,0 +1,482 @@
+package gw
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/oceanho/gw/logger"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	webSocketDecoratorCatalog = "gw_framework_websocket"
+	webSocketLimitCatalog     = "gw_framework_websocket_limit"
+	defaultWebSocketMaxSize   = 64 << 10
+	defaultWebSocketPing      = 30 * time.Second
+	defaultWebSocketWrite     = 10 * time.Second
+	defaultWebSocketBuffer    = 64
+)
+
+var (
+	ErrWebSocketClosed       = fmt.Errorf("websocket connection closed")
+	ErrWebSocketRateLimited  = fmt.Errorf("websocket rate limit exceeded")
+	ErrWebSocketSlowConsumer = fmt.Errorf("websocket send buffer full")
+	webSocketConnSeq         uint64
+)
+
+// WebSocket register a WebSocket(http GET) router of handler, the handler can be got the connection by ctx.WebSocket().
+//
+// The connection are upgraded after the decorators(such as permission decorators) passed,
+// and registered on the server's WebSocketRegistry until the handler returned.
+func (router *Router) WebSocket(relativePath string, handler Handler, decorators ...Decorator) {
+	var ds = []Decorator{NewTimeoutDecorator(0), {Catalog: webSocketDecoratorCatalog, MetaData: true}}
+	ds = append(ds, decorators...)
+	var limit = routerWebSocketLimit(decorators...)
+	router.createRouter(http.MethodGet, relativePath, func(ctx *Context) {
+		wc, err := upgradeWebSocket(ctx, limit)
+		if err != nil {
+			logger.Error("upgrade websocket fail, err: %v", err)
+			return
+		}
+		ctx.webSocket = wc
+		defer wc.release()
+		handler(ctx)
+	}, getHandlerFullName(handler), ds...)
+}
+
+func isWebSocketRouter(decorators ...Decorator) bool {
+	for _, d := range decorators {
+		if d.Catalog == webSocketDecoratorCatalog {
+			return true
+		}
+	}
+	return false
+}
+
+type webSocketLimit struct {
+	messagesPerSecond float64
+	burst             int
+}
+
+// NewWebSocketLimitDecorator returns a Decorator that overrides the inbound messages rate limit of per connection
+// (Settings.WebSocket.MessagesPerSecond, Burst) of the WebSocket router, messagesPerSecond <= 0 means no limit.
+func NewWebSocketLimitDecorator(messagesPerSecond float64, burst int) Decorator {
+	return Decorator{
+		Catalog: webSocketLimitCatalog,
+		MetaData: &webSocketLimit{
+			messagesPerSecond: messagesPerSecond,
+			burst:             burst,
+		},
+	}
+}
+
+func routerWebSocketLimit(decorators ...Decorator) *webSocketLimit {
+	var limit *webSocketLimit
+	for _, d := range decorators {
+		if d.Catalog != webSocketLimitCatalog {
+			continue
+		}
+		if l, ok := d.MetaData.(*webSocketLimit); ok {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// WebSocket returns the WebSocket connection of the request, nil if the router are not registered by WebSocket(...).
+func (c *Context) WebSocket() *WebSocketConn {
+	return c.webSocket
+}
+
+type webSocketFrame struct {
+	messageType int
+	data        []byte
+}
+
+// WebSocketConn represents a upgraded WebSocket connection of a User.
+//
+// Writes are queued and sent by the connection's writer, so it's safe to write from multiple goroutines.
+type WebSocketConn struct {
+	id           string
+	sid          string
+	user         User
+	conn         *websocket.Conn
+	server       *HostServer
+	limiter      *tokenBucket
+	locker       sync.RWMutex
+	topics       map[string]bool
+	send         chan webSocketFrame
+	done         chan struct{}
+	closeOnce    sync.Once
+	closeCode    int
+	closeReason  string
+	wg           sync.WaitGroup
+	pingInterval time.Duration
+	writeTimeout time.Duration
+}
+
+func upgradeWebSocket(c *Context, limit *webSocketLimit) (*WebSocketConn, error) {
+	s := c.server
+	cnf := s.Config().Settings.WebSocket
+	conn, err := s.webSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return nil, err
+	}
+	sid, _ := getSid(s, c.Context)
+	wc := &WebSocketConn{
+		id:           strconv.FormatUint(atomic.AddUint64(&webSocketConnSeq, 1), 10),
+		sid:          sid,
+		user:         c.User(),
+		conn:         conn,
+		server:       s,
+		topics:       make(map[string]bool),
+		done:         make(chan struct{}),
+		pingInterval: durationOrDefault(cnf.PingInterval, defaultWebSocketPing),
+		writeTimeout: durationOrDefault(cnf.WriteTimeout, defaultWebSocketWrite),
+	}
+	buffer := cnf.SendBuffer
+	if buffer <= 0 {
+		buffer = defaultWebSocketBuffer
+	}
+	wc.send = make(chan webSocketFrame, buffer)
+	maxSize := cnf.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultWebSocketMaxSize
+	}
+	conn.SetReadLimit(maxSize)
+	if limit == nil {
+		limit = &webSocketLimit{messagesPerSecond: cnf.MessagesPerSecond, burst: cnf.Burst}
+	}
+	if limit.messagesPerSecond > 0 {
+		wc.limiter = newTokenBucket(limit.messagesPerSecond, limit.burst)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(wc.pingInterval * 2))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wc.pingInterval * 2))
+	})
+	wc.wg.Add(1)
+	go wc.writeLoop()
+	s.WebSockets.add(wc)
+	return wc, nil
+}
+
+func durationOrDefault(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// writeLoop sends the queued messages and pings, and close the connection when the WebSocketConn closed.
+func (wc *WebSocketConn) writeLoop() {
+	defer wc.wg.Done()
+	ticker := time.NewTicker(wc.pingInterval)
+	defer ticker.Stop()
+	defer wc.conn.Close()
+	closing := wc.server.closing
+	for {
+		select {
+		case f := <-wc.send:
+			_ = wc.conn.SetWriteDeadline(time.Now().Add(wc.writeTimeout))
+			if err := wc.conn.WriteMessage(f.messageType, f.data); err != nil {
+				wc.CloseWithReason(websocket.CloseAbnormalClosure, err.Error())
+				return
+			}
+		case <-ticker.C:
+			if err := wc.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wc.writeTimeout)); err != nil {
+				wc.CloseWithReason(websocket.CloseAbnormalClosure, err.Error())
+				return
+			}
+		case <-closing:
+			closing = nil
+			wc.CloseWithReason(websocket.CloseGoingAway, "server shutting down")
+		case <-wc.done:
+			msg := websocket.FormatCloseMessage(wc.closeCode, wc.closeReason)
+			_ = wc.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wc.writeTimeout))
+			return
+		}
+	}
+}
+
+// release closes the connection and unregister it, it's called when the WebSocket handler returned.
+func (wc *WebSocketConn) release() {
+	wc.Close()
+	wc.wg.Wait()
+	wc.server.WebSockets.remove(wc)
+}
+
+// Id returns the unique id of the connection.
+func (wc *WebSocketConn) Id() string {
+	return wc.id
+}
+
+// User returns the User of the connection.
+func (wc *WebSocketConn) User() User {
+	return wc.user
+}
+
+// TenantId returns the tenant id of the connection's User.
+func (wc *WebSocketConn) TenantId() uint64 {
+	return wc.user.TenantId
+}
+
+// Done returns a channel that's closed when the connection closed.
+func (wc *WebSocketConn) Done() <-chan struct{} {
+	return wc.done
+}
+
+// Subscribe subscribes the topics, see WebSocketRegistry.Publish(...).
+func (wc *WebSocketConn) Subscribe(topics ...string) {
+	wc.locker.Lock()
+	defer wc.locker.Unlock()
+	for _, t := range topics {
+		wc.topics[t] = true
+	}
+}
+
+// Unsubscribe unsubscribes the topics.
+func (wc *WebSocketConn) Unsubscribe(topics ...string) {
+	wc.locker.Lock()
+	defer wc.locker.Unlock()
+	for _, t := range topics {
+		delete(wc.topics, t)
+	}
+}
+
+// Subscribed returns the connection has been subscribed the topic or not.
+func (wc *WebSocketConn) Subscribed(topic string) bool {
+	wc.locker.RLock()
+	defer wc.locker.RUnlock()
+	return wc.topics[topic]
+}
+
+// ReadMessage reads the next message of the connection,
+// the connection will be closed(1008, policy violation) if the inbound messages exceed the rate limit.
+func (wc *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType, data, err = wc.conn.ReadMessage()
+	if err != nil {
+		return
+	}
+	if wc.limiter != nil && !wc.limiter.allow() {
+		wc.CloseWithReason(websocket.ClosePolicyViolation, ErrWebSocketRateLimited.Error())
+		return messageType, nil, ErrWebSocketRateLimited
+	}
+	return
+}
+
+// ReadJSON reads the next message of the connection, and decode it into out.
+func (wc *WebSocketConn) ReadJSON(out interface{}) error {
+	_, data, err := wc.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// WriteMessage queues the message to send, the connection will be closed if the send buffer are full.
+func (wc *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	select {
+	case <-wc.done:
+		return ErrWebSocketClosed
+	default:
+	}
+	select {
+	case wc.send <- webSocketFrame{messageType: messageType, data: data}:
+		return nil
+	case <-wc.done:
+		return ErrWebSocketClosed
+	default:
+		wc.CloseWithReason(websocket.CloseTryAgainLater, ErrWebSocketSlowConsumer.Error())
+		return ErrWebSocketSlowConsumer
+	}
+}
+
+// Send queues the v to send, string are sent as text, []byte are sent as binary, others are sent as JSON text.
+func (wc *WebSocketConn) Send(v interface{}) error {
+	messageType, data, err := encodeWebSocketMessage(v)
+	if err != nil {
+		return err
+	}
+	return wc.WriteMessage(messageType, data)
+}
+
+// Close closes the connection normally.
+func (wc *WebSocketConn) Close() {
+	wc.CloseWithReason(websocket.CloseNormalClosure, "")
+}
+
+// CloseWithReason closes the connection with the close code and reason.
+func (wc *WebSocketConn) CloseWithReason(code int, reason string) {
+	wc.closeOnce.Do(func() {
+		wc.closeCode = code
+		wc.closeReason = reason
+		close(wc.done)
+	})
+}
+
+func encodeWebSocketMessage(v interface{}) (int, []byte, error) {
+	switch m := v.(type) {
+	case string:
+		return websocket.TextMessage, []byte(m), nil
+	case []byte:
+		return websocket.BinaryMessage, m, nil
+	}
+	b, err := json.Marshal(v)
+	return websocket.TextMessage, b, err
+}
+
+// WebSocketRegistry represents the server-wide WebSocket connections registry,
+// it's can be broadcast messages to users, tenants or topics.
+type WebSocketRegistry struct {
+	locker sync.RWMutex
+	conns  map[string]*WebSocketConn
+}
+
+func newWebSocketRegistry() *WebSocketRegistry {
+	return &WebSocketRegistry{
+		conns: make(map[string]*WebSocketConn),
+	}
+}
+
+func (r *WebSocketRegistry) add(wc *WebSocketConn) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.conns[wc.id] = wc
+}
+
+func (r *WebSocketRegistry) remove(wc *WebSocketConn) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	delete(r.conns, wc.id)
+}
+
+// Len returns the number of the connections.
+func (r *WebSocketRegistry) Len() int {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+	return len(r.conns)
+}
+
+// Conns returns the connections that matched the filter, nil filter means all connections.
+func (r *WebSocketRegistry) Conns(filter func(wc *WebSocketConn) bool) []*WebSocketConn {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+	var conns []*WebSocketConn
+	for _, wc := range r.conns {
+		if filter == nil || filter(wc) {
+			conns = append(conns, wc)
+		}
+	}
+	return conns
+}
+
+// SendToUser sends the v to the connections of the user, returns the number of the sent connections.
+func (r *WebSocketRegistry) SendToUser(userId uint64, v interface{}) (int, error) {
+	return r.send(v, func(wc *WebSocketConn) bool {
+		return wc.user.ID == userId
+	})
+}
+
+// SendToTenant sends the v to the connections of the tenant, returns the number of the sent connections.
+func (r *WebSocketRegistry) SendToTenant(tenantId uint64, v interface{}) (int, error) {
+	return r.send(v, func(wc *WebSocketConn) bool {
+		return wc.user.TenantId == tenantId
+	})
+}
+
+// Publish sends the v to the connections that subscribed the topic, returns the number of the sent connections.
+func (r *WebSocketRegistry) Publish(topic string, v interface{}) (int, error) {
+	return r.send(v, func(wc *WebSocketConn) bool {
+		return wc.Subscribed(topic)
+	})
+}
+
+// Broadcast sends the v to all connections, returns the number of the sent connections.
+func (r *WebSocketRegistry) Broadcast(v interface{}) (int, error) {
+	return r.send(v, nil)
+}
+
+func (r *WebSocketRegistry) send(v interface{}, filter func(wc *WebSocketConn) bool) (int, error) {
+	messageType, data, err := encodeWebSocketMessage(v)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, wc := range r.Conns(filter) {
+		if wc.WriteMessage(messageType, data) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CloseSession closes the connections of the session, returns the number of the closed connections.
+func (r *WebSocketRegistry) CloseSession(sid string) int {
+	conns := r.Conns(func(wc *WebSocketConn) bool {
+		return wc.sid == sid
+	})
+	for _, wc := range conns {
+		wc.CloseWithReason(websocket.ClosePolicyViolation, "session closed")
+	}
+	return len(conns)
+}
+
+// RemoveSession removes the session by SessionStateManager, and closes the WebSocket connections of the session.
+// It's called by the logout API and the ServerState.SessionStateManager().Remove(...).
+func (s *HostServer) RemoveSession(sid string) error {
+	err := s.SessionStateManager.Remove(sid)
+	s.WebSockets.CloseSession(sid)
+	return err
+}
+
+// webSocketSessionStateManager close the WebSocket connections of the session when the session removed.
+// It's returned by ServerState.SessionStateManager(), the HostServer.SessionStateManager are not replaced.
+type webSocketSessionStateManager struct {
+	ISessionStateManager
+	s *HostServer
+}
+
+func (m webSocketSessionStateManager) Remove(sid string) error {
+	return m.s.RemoveSession(sid)
+}
+
+// tokenBucket represents a token bucket rate limiter.
+type tokenBucket struct {
+	locker sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,100 @@
+package gw_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func wsDashboard(c *gw.Context) {
+	ws := c.WebSocket()
+	ws.Subscribe("jobs")
+	_ = ws.Send(gin.H{"Ready": ws.User().Passport})
+	for {
+		var msg map[string]string
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		_ = ws.Send(msg)
+	}
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.WebSocket("dashboard", wsDashboard, gw.NewWebSocketLimitDecorator(1, 2))
+		},
+	})
+	_, resp, err := server.Client().DialWebSocket("wsDashboard", nil)
+	assert.Equal(t, websocket.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	conn, _, err := client.DialWebSocket("wsDashboard", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	var msg map[string]interface{}
+	assert.Nil(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "gw", msg["Ready"])
+
+	registry := server.State().WebSockets()
+	assert.Equal(t, 1, registry.Len())
+	n, _ := registry.Publish("jobs", gin.H{"Job": "backup"})
+	assert.Equal(t, 1, n)
+	assert.Nil(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "backup", msg["Job"])
+	n, _ = registry.SendToUser(2, "other")
+	assert.Equal(t, 0, n)
+	n, _ = registry.SendToTenant(10, "tenant")
+	assert.Equal(t, 1, n)
+	_, b, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant", string(b))
+
+	// close on logout, the SessionStateManager of the server are not wrapped.
+	_, ok := server.SessionStateManager.(*gw.DefaultSessionStateManagerImpl)
+	assert.True(t, ok)
+	client.Logout()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+
+	// close on the session removed by the SessionStateManager of the state.
+	sessions := server.State().SessionStateManager()
+	assert.Nil(t, sessions.Save("ws-sid", gw.User{ID: 1, TenantId: 10, Passport: "gw"}))
+	credential, err := server.SessionCredential("ws-sid")
+	assert.Nil(t, err)
+	conn, _, err = server.Client().WithHeader("X-Auth-Token", credential).DialWebSocket("wsDashboard", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, conn.ReadJSON(&msg))
+	assert.Nil(t, sessions.Remove("ws-sid"))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+
+	// per-connection rate limit.
+	conn, _, err = client.LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"}).DialWebSocket("wsDashboard", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, conn.ReadJSON(&msg))
+	for i := 0; i < 3; i++ {
+		_ = conn.WriteJSON(map[string]string{"Ping": strconv.Itoa(i)})
+	}
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	closeErr, ok := err.(*websocket.CloseError)
+	if assert.True(t, ok) {
+		assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+		assert.Equal(t, gw.ErrWebSocketRateLimited.Error(), closeErr.Text)
+	}
+}
@@ -2,8 +2,10 @@ package confsvr
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	json "github.com/json-iterator/go"
+	"github.com/oceanho/gw/libs/gwtrace"
 	"github.com/oceanho/gw/sdk/confsvr/param"
 	"io"
 	"io/ioutil"
@@ -25,6 +27,11 @@ type Option struct {
 }
 
 func (c *Client) Do(req param.IRequest, output interface{}) (int, error) {
+	return c.DoContext(context.Background(), req, output)
+}
+
+// DoContext sends the request with ctx, the trace context(W3C traceparent/tracestate) of ctx are propagated to the confsvr.
+func (c *Client) DoContext(ctx context.Context, req param.IRequest, output interface{}) (int, error) {
 	var reader io.Reader
 	var buffer bufio.ReadWriter
 	body := req.Body()
@@ -38,15 +45,19 @@ func (c *Client) Do(req param.IRequest, output interface{}) (int, error) {
 	uri = strings.TrimRight(uri, "/")
 
 	url := fmt.Sprintf("%s/%s/%s/%s", c.options.Addr, c.options.Version, c.options.Service, uri)
-	oriReq, _ := http.NewRequest(req.Method(), url, reader)
+	oriReq, err := http.NewRequestWithContext(ctx, req.Method(), url, reader)
+	if err != nil {
+		return 0, fmt.Errorf("create http request: %v", err)
+	}
 	for k, header := range req.Headers() {
 		oriReq.Header.Set(k, header)
 	}
+	gwtrace.Inject(ctx, oriReq.Header)
 
 	client := http.DefaultClient
 	resp, err := client.Do(oriReq)
 	if err != nil {
-		return resp.StatusCode, fmt.Errorf("do http request: %v", err)
+		return 0, fmt.Errorf("do http request: %v", err)
 	}
 	defer resp.Body.Close()
 	b, err := ioutil.ReadAll(resp.Body)
@@ -1,7 +1,11 @@
 package confsvr
 
 import (
+	"context"
+	"github.com/oceanho/gw/libs/gwtrace"
 	"github.com/oceanho/gw/sdk/confsvr/param"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -20,3 +24,27 @@ func TestClient_Do(t *testing.T) {
 	}
 	t.Logf("result is: %v", resp)
 }
+
+func TestClient_DoContext(t *testing.T) {
+	var traceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get(gwtrace.TraceParentHeader)
+		_, _ = w.Write([]byte(`{"status":"succ","payload":{"token":"abc"}}`))
+	}))
+	defer server.Close()
+	opts := DefaultOptions()
+	opts.Addr = server.URL
+	client := NewClient(opts)
+	sc := gwtrace.SpanContext{TraceID: gwtrace.NewTraceID(), SpanID: gwtrace.NewSpanID(), Flags: gwtrace.FlagsSampled}
+	resp := &param.RspGetAuth{}
+	code, err := client.DoContext(gwtrace.ContextWithSpanContext(context.Background(), sc), param.ReqGetAuth{}, resp)
+	if err != nil {
+		t.Fatalf("client.DoContext(ctx, req, respobj), status code: %d, resp: %v", code, err)
+	}
+	if traceParent != sc.TraceParent() {
+		t.Fatalf("traceparent: %s, excepted: %s", traceParent, sc.TraceParent())
+	}
+	if resp.Payload.Token != "abc" {
+		t.Fatalf("token: %s, excepted: abc", resp.Payload.Token)
+	}
+}
@@ -1,22 +1,25 @@
 package gw
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
 	"github.com/oceanho/gw/conf"
 	"github.com/oceanho/gw/logger"
 	"github.com/oceanho/gw/utils/secure"
 	"gorm.io/gorm"
-	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"path"
-	"plugin"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -30,10 +33,13 @@ type ServerOption struct {
 	PluginDir                string
 	PluginSymbolName         string
 	PluginSymbolSuffix       string
+	PluginAllowUnversioned   bool
+	ShutDownTimeout          time.Duration
 	StartHandlers            []ServerHandler
 	ShutDownHandlers         []ServerHandler
 	BackendStoreHandler      func(cnf *conf.ApplicationConfig) IStore
 	AppConfigHandler         func(cnf *conf.BootConfig) *conf.ApplicationConfig
+	AppConfigValidators      []AppConfigValidator
 	Crypto                   func(conf *conf.ApplicationConfig) ICrypto
 	IDGeneratorHandler       func(conf *conf.ApplicationConfig) IdentifierGenerator
 	UserManagerHandler       func(state *ServerState) IUserManager
@@ -48,6 +54,9 @@ type ServerOption struct {
 	DbOpProcessor            *DbOpProcessor
 	EventManagerHandler      func(state *ServerState) IEventManager
 	RespBodyBuildFunc        RespBodyBuildFunc
+	Renderers                *Renderers
+	Translator               *Translator
+	TenantLocaleHandler      func(state *ServerState, tenantId uint64) string
 	isTester                 bool
 	cnf                      *conf.ApplicationConfig
 	bcs                      *conf.BootConfig
@@ -73,13 +82,26 @@ type HostServer struct {
 	DIProvider             IDIProvider
 	EventManager           IEventManager
 	DbOpProcessor          *DbOpProcessor
+	HealthChecker          *HealthChecker
+	Metrics                *MetricsRegistry
+	Tracer                 *Tracer
+	WebSockets             *WebSocketRegistry
 	RespBodyBuildFunc      RespBodyBuildFunc
+	Renderers              *Renderers
+	Translator             *Translator
 	state                  int
+	isReady                int32
+	plugins                *pluginLoader
+	webSocketUpgrader      *websocket.Upgrader
+	isShuttingDown         int32
+	metrics                *builtinMetrics
 	locker                 sync.Mutex
 	options                *ServerOption
 	router                 *Router
 	apps                   map[string]internalApp
-	conf                   *conf.ApplicationConfig
+	appNames               []string
+	sortedApps             []internalApp
+	config                 atomic.Value
 	httpErrHandlers        map[int][]ErrorHandler
 	hooks                  []*Hook
 	beforeHooks            []*Hook
@@ -88,13 +110,23 @@ type HostServer struct {
 	authParamValidators    map[string]*regexp.Regexp
 	storeDbSetupHandler    StoreDbSetupHandler
 	storeCacheSetupHandler StoreCacheSetupHandler
+	listener               net.Listener
+	addr                   atomic.Value
+	httpServer             *http.Server
+	shutDownOnce           sync.Once
 	quit                   chan bool
+	closing                chan struct{}
+	rateLimitStores        *rateLimitStores
+	rateLimitPolicies      atomic.Value
+	lockedStores           *lockedStores
 	serverExitSignal       chan struct{}
 	serverStartDone        chan struct{}
+	serverShutDownDone     chan struct{}
+	hostServer             *internalHostServer
 }
 
 func (s *HostServer) State() *ServerState {
-	return servers[s.options.Name].State
+	return s.hostServer.State
 }
 
 // ServerState represents a Server state context object.
@@ -149,8 +181,16 @@ func (ss *ServerState) PermissionChecker() IPermissionChecker {
 	return ss.s.PermissionManager.Checker()
 }
 
+// SessionStateManager returns the ISessionStateManager of the server,
+// the WebSocket connections of the session are closed when the session removed by it.
 func (ss *ServerState) SessionStateManager() ISessionStateManager {
-	return ss.s.SessionStateManager
+	if ss.s.SessionStateManager == nil {
+		return nil
+	}
+	return webSocketSessionStateManager{
+		ISessionStateManager: ss.s.SessionStateManager,
+		s:                    ss.s,
+	}
 }
 
 func (ss *ServerState) PermissionManager() IPermissionManager {
@@ -166,7 +206,7 @@ func (ss *ServerState) ServerOptions() *ServerOption {
 }
 
 func (ss *ServerState) ApplicationConfig() *conf.ApplicationConfig {
-	return ss.s.conf
+	return ss.s.Config()
 }
 
 func (ss *ServerState) IDGenerator() IdentifierGenerator {
@@ -177,10 +217,30 @@ func (ss *ServerState) DI() IDIProvider {
 	return ss.s.DIProvider
 }
 
+func (ss *ServerState) HealthChecker() *HealthChecker {
+	return ss.s.HealthChecker
+}
+
+func (ss *ServerState) Metrics() *MetricsRegistry {
+	return ss.s.Metrics
+}
+
+func (ss *ServerState) WebSockets() *WebSocketRegistry {
+	return ss.s.WebSockets
+}
+
 func (ss *ServerState) RespBodyBuildFunc() RespBodyBuildFunc {
 	return ss.s.RespBodyBuildFunc
 }
 
+func (ss *ServerState) Renderers() *Renderers {
+	return ss.s.Renderers
+}
+
+func (ss *ServerState) Translator() *Translator {
+	return ss.s.Translator
+}
+
 var (
 	appDefaultAddr               = ":8080"
 	appDefaultName               = "gw.app"
@@ -188,6 +248,7 @@ var (
 	appDefaultPrefix             = "/api/v1"
 	appDefaultPluginSymbolName   = "AppPlugin"
 	appDefaultPluginSymbolSuffix = ".so"
+	appDefaultShutDownTimeout    = time.Second * 30
 	appDefaultBackendHandler     = func(cnf *conf.ApplicationConfig) IStore {
 		return DefaultBackend(cnf)
 	}
@@ -205,7 +266,13 @@ var (
 )
 
 var (
-	servers map[string]*internalHostServer
+	servers       map[string]*internalHostServer
+	serversLocker sync.RWMutex
+)
+
+var (
+	ErrServerHasStarted    = fmt.Errorf("server has started")
+	ErrServerCanNotBeStart = fmt.Errorf("server can not be start, it's has no http router(tester server)")
 )
 
 type internalHostServer struct {
@@ -217,6 +284,15 @@ func (hss *internalHostServer) SetState(state *ServerState) {
 	hss.State = state
 }
 
+// unregisterServer removes the server from the registered servers, so the name can be used by a new server.
+func unregisterServer(s *HostServer) {
+	serversLocker.Lock()
+	defer serversLocker.Unlock()
+	if hostServer, ok := servers[s.options.Name]; ok && hostServer.Server == s {
+		delete(servers, s.options.Name)
+	}
+}
+
 func init() {
 	servers = make(map[string]*internalHostServer)
 	logger.SetLogFormatter(internLogFormatter)
@@ -230,8 +306,10 @@ func NewServerOption(bcs *conf.BootConfig) *ServerOption {
 		Restart:                appDefaultRestart,
 		Prefix:                 appDefaultPrefix,
 		AppConfigHandler:       appDefaultAppConfigHandler,
+		AppConfigValidators:    []AppConfigValidator{RestartRequiredConfigValidator},
 		PluginSymbolName:       appDefaultPluginSymbolName,
 		PluginSymbolSuffix:     appDefaultPluginSymbolSuffix,
+		ShutDownTimeout:        appDefaultShutDownTimeout,
 		StartHandlers:          make([]ServerHandler, 0, 4),
 		ShutDownHandlers:       make([]ServerHandler, 0, 4),
 		BackendStoreHandler:    appDefaultBackendHandler,
@@ -268,6 +346,8 @@ func NewServerOption(bcs *conf.BootConfig) *ServerOption {
 		},
 		DbOpProcessor:     NewDbOpProcessor(),
 		RespBodyBuildFunc: DefaultRespBodyBuildFunc,
+		Renderers:         NewRenderers(),
+		Translator:        NewTranslator(),
 		bcs:               bcs,
 		isTester:          false,
 	}
@@ -318,6 +398,8 @@ func at(addr string) *HostServer {
 
 // New return a  Server with ServerOptions.
 func NewServerWithOption(sopt *ServerOption) *HostServer {
+	serversLocker.Lock()
+	defer serversLocker.Unlock()
 	server, ok := servers[sopt.Name]
 	if ok {
 		logger.Warn("duplicated server, name: %s", sopt.Name)
@@ -328,21 +410,36 @@ func NewServerWithOption(sopt *ServerOption) *HostServer {
 		hooks:               make([]*Hook, 0),
 		beforeHooks:         make([]*Hook, 0),
 		afterHooks:          make([]*Hook, 0),
+		afterHookMaxIdx:     -1,
 		apps:                make(map[string]internalApp),
 		httpErrHandlers:     make(map[int][]ErrorHandler),
 		authParamValidators: make(map[string]*regexp.Regexp),
+		HealthChecker:       newHealthChecker(),
+		Metrics:             newMetricsRegistry(),
+		WebSockets:          newWebSocketRegistry(),
+		rateLimitStores:     newRateLimitStores(),
+		lockedStores:        newLockedStores(),
+		webSocketUpgrader:   &websocket.Upgrader{},
+		plugins:             newPluginLoader(),
 		serverExitSignal:    make(chan struct{}, 1),
 		serverStartDone:     make(chan struct{}, 1),
+		serverShutDownDone:  make(chan struct{}),
 		quit:                make(chan bool, 1),
+		closing:             make(chan struct{}),
 	}
-	servers[sopt.Name] = &internalHostServer{
+	// built-in hooks(cors, security headers etc.), they can be replaced or deleted by name.
+	serverInstance.hooks = newBuiltinHooks(serverInstance)
+	serverInstance.metrics = newBuiltinMetrics(serverInstance.Metrics)
+	serverInstance.Tracer = newTracer(serverInstance)
+	serverInstance.hostServer = &internalHostServer{
 		State:  nil,
 		Server: serverInstance,
 	}
+	servers[sopt.Name] = serverInstance.hostServer
 	return serverInstance
 }
 
-// AddHook register a global http handler API into the server.
+// AddHook register global http hooks into the server, the registered hook that has same name are replaced.
 func (s *HostServer) AddHook(handlers ...*Hook) {
 	if len(handlers) == 0 {
 		return
@@ -350,11 +447,15 @@ func (s *HostServer) AddHook(handlers ...*Hook) {
 	s.locker.Lock()
 	defer s.locker.Unlock()
 	for j := 0; j < len(handlers); j++ {
+		var replaced bool
 		for i := 0; i < len(s.hooks); i++ {
 			if s.hooks[i].Name == handlers[j].Name {
 				s.hooks[i] = handlers[j]
-				continue
+				replaced = true
+				break
 			}
+		}
+		if !replaced {
 			s.hooks = append(s.hooks, handlers[j])
 		}
 	}
@@ -415,6 +516,7 @@ func (s *HostServer) Register(apps ...App) {
 				instance:    app,
 				isPatchOnly: false,
 			}
+			s.appNames = append(s.appNames, appName)
 		}
 	}
 }
@@ -447,45 +549,7 @@ func (s *HostServer) Patch(apps ...App) {
 				instance:    app,
 				isPatchOnly: true,
 			}
-		}
-	}
-}
-
-// RegisterByPluginDir register app instances into the server with gw plugin mode.
-func (s *HostServer) RegisterByPluginDir(dirs ...string) {
-	for _, d := range dirs {
-		rd, err := ioutil.ReadDir(d)
-		if err != nil {
-			logger.Error("read dir: %s", d)
-			continue
-		}
-		for _, fi := range rd {
-			pn := path.Join(d, fi.Name())
-			if fi.IsDir() {
-				s.RegisterByPluginDir(pn)
-			} else {
-				if !strings.HasSuffix(fi.Name(), s.options.PluginSymbolSuffix) {
-					logger.Info("suffix not is %s, skipping file: %s", s.options.PluginSymbolSuffix, pn)
-					continue
-				}
-				p, err := plugin.Open(pn)
-				if err != nil {
-					logger.Error("load plugin file: %s, err: %v", pn, err)
-					continue
-				}
-				sym, err := p.Lookup(s.options.PluginSymbolName)
-				if err != nil {
-					logger.Error("file %s, err: %v", pn, err)
-					continue
-				}
-				// TODO(Ocean): If symbol are pointer, how to conversions ?
-				app, ok := sym.(App)
-				if !ok {
-					logger.Error("symbol %s in file %s did not is app.App interface.", s.options.PluginSymbolName, pn)
-					continue
-				}
-				s.Register(app)
-			}
+			s.appNames = append(s.appNames, appName)
 		}
 	}
 }
@@ -496,12 +560,20 @@ func initialConfig(s *HostServer) {
 	// There are options may be changed by Custom app instance's .Use(...) APIs.
 	cnf := s.options.AppConfigHandler(s.options.bcs)
 	cnf.Compile()
-	s.conf = cnf
+	if err := cnf.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid application config, err: %v", err))
+	}
+	policies, err := compileRateLimitPolicies(cnf)
+	if err != nil {
+		panic(fmt.Sprintf("invalid application config, err: %v", err))
+	}
+	s.config.Store(cnf)
+	s.rateLimitPolicies.Store(policies)
 	s.options.cnf = cnf
 }
 
 func initialServer(s *HostServer) *ServerState {
-	var cnf = s.conf
+	var cnf = s.Config()
 	crypto := s.options.Crypto(cnf)
 	s.Hash = crypto.Hash()
 	s.Name = s.options.Name
@@ -512,6 +584,22 @@ func initialServer(s *HostServer) *ServerState {
 	if s.RespBodyBuildFunc == nil {
 		s.RespBodyBuildFunc = s.options.RespBodyBuildFunc
 	}
+	if s.Renderers == nil {
+		s.Renderers = s.options.Renderers
+	}
+	if s.Renderers == nil {
+		s.Renderers = NewRenderers()
+	}
+	if s.Translator == nil {
+		s.Translator = s.options.Translator
+	}
+	if s.Translator == nil {
+		s.Translator = NewTranslator()
+	}
+	if locale := cnf.Settings.I18n.DefaultLocale; locale != "" {
+		s.Translator.SetDefaultLocale(locale)
+	}
+	loadCatalogs(s)
 
 	state := &ServerState{
 		s: s,
@@ -543,15 +631,15 @@ func initialServer(s *HostServer) *ServerState {
 	}
 	// SessionSidCreationFunc
 	if s.SessionSidCreationFunc == nil {
-		if s.conf.Security.Auth.Session.SidGenerator == "p" {
+		if s.Config().Security.Auth.Session.SidGenerator == "p" {
 			s.SessionSidCreationFunc = func(param AuthParameter) string {
 				return param.Passport
 			}
-		} else if s.conf.Security.Auth.Session.SidGenerator == "p,md5" {
+		} else if s.Config().Security.Auth.Session.SidGenerator == "p,md5" {
 			s.SessionSidCreationFunc = func(param AuthParameter) string {
 				return secure.Md5Str(param.Passport)
 			}
-		} else if s.conf.Security.Auth.Session.SidGenerator == "p,smd5" {
+		} else if s.Config().Security.Auth.Session.SidGenerator == "p,smd5" {
 			s.SessionSidCreationFunc = func(param AuthParameter) string {
 				return s.PasswordSigner.Sign(param.Passport)
 			}
@@ -585,13 +673,16 @@ func initialServer(s *HostServer) *ServerState {
 		// gin engine.
 		g := gin.New()
 		// g.Use(gin.Recovery())
-		g.Use(gwState(s.options.Name))
+		g.Use(gwState(s))
+
+		// global rate limit middleware.
+		g.Use(gwRateLimit())
 
 		// Auth(login/logout) API routers.
-		registerBuiltinRouter(s.conf, g)
+		registerBuiltinRouter(cnf, g)
 
 		// global Auth middleware.
-		g.Use(gwAuthChecker(s.options.cnf.Security.Auth.AllowUrls))
+		g.Use(gwAuthChecker())
 
 		if gin.IsDebugging() {
 			g.Use(gin.Logger())
@@ -608,8 +699,12 @@ func initialServer(s *HostServer) *ServerState {
 		s.router = httpRouter
 	}
 
-	if s.conf.Server.ListenAddr != "" && s.options.Addr == appDefaultAddr {
-		s.options.Addr = s.conf.Server.ListenAddr
+	if s.Config().Server.ListenAddr != "" && s.options.Addr == appDefaultAddr {
+		s.options.Addr = s.Config().Server.ListenAddr
+	}
+	shutDownTimeout := s.Config().Settings.TimeoutControl.ShutDown
+	if shutDownTimeout > 0 && s.options.ShutDownTimeout == appDefaultShutDownTimeout {
+		s.options.ShutDownTimeout = time.Duration(shutDownTimeout) * time.Millisecond
 	}
 
 	// permission manager initial
@@ -622,6 +717,23 @@ func registerBuiltinRouter(cnf *conf.ApplicationConfig, router *gin.Engine) {
 	if _pprof.Enabled {
 		pprof.Register(router, _pprof.Router)
 	}
+	health := cnf.Service.Health
+	if health.Enabled {
+		if health.Liveness != "" {
+			router.GET(health.Liveness, gwLiveness)
+		}
+		if health.Readiness != "" {
+			router.GET(health.Readiness, gwReadiness)
+		}
+	}
+	metrics := cnf.Service.Metrics
+	if metrics.Enabled && metrics.Router != "" {
+		router.GET(metrics.Router, gwMetrics)
+	}
+	openapi := cnf.Service.OpenAPI
+	if openapi.Enabled && openapi.Router != "" {
+		router.GET(openapi.Router, gwOpenAPI)
+	}
 	authServer := cnf.Security.AuthServer
 	if authServer.EnableAuthServe {
 		for _, m := range authServer.LogIn.Methods {
@@ -633,24 +745,34 @@ func registerBuiltinRouter(cnf *conf.ApplicationConfig, router *gin.Engine) {
 	}
 }
 
+func prepareApps(s *HostServer) {
+	apps, err := sortApps(s.apps, s.appNames)
+	if err != nil {
+		panic(fmt.Sprintf("sort apps fail, err: %v", err))
+	}
+	s.sortedApps = apps
+}
+
 func useApps(s *HostServer) {
-	for _, app := range s.apps {
+	for _, app := range s.sortedApps {
 		app.instance.Use(s.options)
 	}
 }
 
 func onStarts(s *HostServer, state *ServerState) {
-	for _, app := range s.apps {
+	for _, app := range s.sortedApps {
 		app.instance.OnStart(state)
 	}
 }
 
 func registerApps(s *HostServer, state *ServerState) {
-	for _, app := range s.apps {
+	for _, app := range s.sortedApps {
 		if !app.isPatchOnly {
 			logger.Info("register app: %s", app.instance.Name())
 			rg := s.router.Group(app.instance.Router(), nil)
+			s.router.appName = app.instance.Name()
 			app.instance.Register(rg)
+			s.router.appName = ""
 
 		}
 		// migrate
@@ -683,29 +805,49 @@ func (s *HostServer) compile() {
 	if s.state > 0 {
 		return
 	}
+	// No more apps can be registered from now on.
+	s.plugins.stopWatch()
 	// All of app server initial AT here.
+	// apps are initial by dependency order.
+	prepareApps(s)
 	initialConfig(s)
 	useApps(s)
 	state := initialServer(s)
 	registerApps(s, state)
+	registerResponseCacheInvalidators(s)
+	setupMetrics(s)
+	setupTracing(s)
 	prepareHooks(s)
 	onStarts(s, state)
-	servers[s.options.Name].SetState(state)
+	s.hostServer.SetState(state)
 	s.state++
+	// ready for traffic, the Handler(...) can be served without Start(...).
+	atomic.StoreInt32(&s.isReady, 1)
 	go func() {
 		_ = <-s.quit
+		// Flip readiness to failing.
+		atomic.StoreInt32(&s.isShuttingDown, 1)
+		// Notify the long-lived requests(such as SSE streams) closing.
+		close(s.closing)
+		// Stop accepting new connections and draining in-flight requests before notify apps.
+		if err := s.shutDownHttpServer(); err != nil {
+			logger.Error("shutdown http server: %s, err: %v", s.options.Name, err)
+		}
 		for _, handler := range s.options.ShutDownHandlers {
 			err := handler(s)
 			if err != nil {
 				fmt.Printf("call app.ShutDownBeforeHandler, %v", err)
 			}
 		}
-		for _, app := range s.apps {
-			app := app.instance
-			app.OnShutDown(servers[s.options.Name].State)
+		// notify apps by reverse dependency order.
+		for i := len(s.sortedApps) - 1; i >= 0; i-- {
+			app := s.sortedApps[i].instance
+			app.OnShutDown(s.hostServer.State)
 		}
 		s.serverExitSignal <- struct{}{}
-		logger.Info("Shutdown server: %s, Addr: %s", s.options.Name, s.options.Addr)
+		unregisterServer(s)
+		close(s.serverShutDownDone)
+		logger.Info("Shutdown server: %s, Addr: %s", s.options.Name, s.Addr())
 	}()
 }
 
@@ -717,9 +859,18 @@ func (s *HostServer) GetRouters() []RouterInfo {
 	return routerInfos
 }
 
+// Handler returns the http.Handler of the Server, It's can be served by httptest.Server.
+func (s *HostServer) Handler() http.Handler {
+	s.compile()
+	if s.router == nil {
+		return nil
+	}
+	return s.router.server
+}
+
 // DisplayRouterInfo ...
 func (s *HostServer) DisplayRouterInfo() {
-	prtInfo := s.conf.Settings.GwFramework.PrintRouterInfo
+	prtInfo := s.Config().Settings.GwFramework.PrintRouterInfo
 	if !prtInfo.Disabled {
 		logger.NewLine(2)
 		logger.Info("%s ", prtInfo.Title)
@@ -741,15 +892,31 @@ func (s *HostServer) RegisterShutDownHandler(handlers ...ServerHandler) {
 	s.options.ShutDownHandlers = append(s.options.ShutDownHandlers, handlers...)
 }
 
-// Serve start the Server.
-func (s *HostServer) Serve() {
+// Start start the Server with non-block mode.
+//
+// It's returns after the listener has been bound and the Server accepts traffic,
+// or returns a error if the Server start fail (such as the address already in use).
+func (s *HostServer) Start() error {
+	s.locker.Lock()
+	if s.state < 1 {
+		if _, err := sortApps(s.apps, s.appNames); err != nil {
+			s.locker.Unlock()
+			return err
+		}
+	}
+	s.locker.Unlock()
 	s.compile()
-	// signal watch.
-	sigs := make(chan os.Signal, 1)
+	if s.router == nil {
+		return ErrServerCanNotBeStart
+	}
+	listener, proto, err := s.listen()
+	if err != nil {
+		return err
+	}
 	for _, handler := range s.options.StartHandlers {
-		err := handler(s)
-		if err != nil {
-			panic(fmt.Errorf("call app.StartBeforeHandler, %v", err))
+		if err := handler(s); err != nil {
+			s.closeListener()
+			return fmt.Errorf("call app.StartBeforeHandler, %v", err)
 		}
 	}
 
@@ -758,30 +925,125 @@ func (s *HostServer) Serve() {
 	logger.NewLine(2)
 	logger.Info("Service Information")
 	logger.Info("=======================")
-	logger.Info(" Name: %s", s.conf.Service.Name)
-	logger.Info(" Version: %s", s.conf.Service.Version)
-	logger.Info(" Remarks: %s", s.conf.Service.Remarks)
+	logger.Info(" Name: %s", s.Config().Service.Name)
+	logger.Info(" Version: %s", s.Config().Service.Version)
+	logger.Info(" Remarks: %s", s.Config().Service.Remarks)
 	logger.NewLine(2)
-	logger.Info(" Serving HTTP on: %s", s.options.Addr)
+	logger.Info(" Serving %s on: %s", proto, s.Addr())
 	logger.ResetLogFormatter()
-	var err error
+	httpServer := s.httpServerOf()
 	go func() {
-		err = s.router.server.Run(s.options.Addr)
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("serve http on: %s fail, err: %v", s.Addr(), err)
+			s.ShutDown()
+		}
 	}()
-	// TODO(Ocean): has no better solution that can be waiting for gin.Serve() completed with non-block state?
-	time.Sleep(time.Second * 2)
-	if err != nil {
-		panic(fmt.Errorf("start server fail. err: %v", err))
-	}
 	s.serverStartDone <- struct{}{}
+	return nil
+}
+
+// listen binds the listener(and the http.Server) of the Server,
+// the lock are held from the check to the assignment, so the concurrent Start(...) calls bind only once.
+func (s *HostServer) listen() (net.Listener, string, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.listener != nil {
+		return nil, "", ErrServerHasStarted
+	}
+	listener, err := net.Listen("tcp", s.options.Addr)
 	if err != nil {
-		panic(fmt.Errorf("call server.router.Run, %v", err))
+		return nil, "", fmt.Errorf("start server fail, listen on %s, err: %v", s.options.Addr, err)
+	}
+	var proto = "HTTP"
+	if tlsCnf := s.Config().Server.TLS; tlsCnf.Enabled {
+		tlsConfig, err := newTLSConfig(tlsCnf)
+		if err != nil {
+			_ = listener.Close()
+			return nil, "", fmt.Errorf("start server fail, invalid tls configuration, err: %v", err)
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+		proto = "HTTPS"
+	}
+	s.listener = listener
+	s.addr.Store(listener.Addr().String())
+	s.httpServer = &http.Server{
+		Handler: s.router.server,
+	}
+	return listener, proto, nil
+}
+
+// closeListener closes the listener that has not been served(such as the start handlers fail), the Server can be started again.
+func (s *HostServer) closeListener() {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.listener != nil {
+		_ = s.listener.Close()
 	}
+	s.listener = nil
+	s.addr.Store("")
+	s.httpServer = nil
+}
+
+func (s *HostServer) httpServerOf() *http.Server {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	return s.httpServer
+}
+
+// Addr returns the actual address of the Server listening on.
+// It's useful when the Server listen on a ephemeral port (such as ":0").
+// It's not takes the lock of the Server, so the apps can be call it while the Server compiling(such as App.OnStart).
+func (s *HostServer) Addr() string {
+	if addr, _ := s.addr.Load().(string); addr != "" {
+		return addr
+	}
+	return s.options.Addr
+}
+
+// Serve start the Server, and waiting for exit signals.
+// It's reload the ApplicationConfig on SIGHUP, and shutdown the Server on others.
+func (s *HostServer) Serve() {
+	if err := s.Start(); err != nil {
+		panic(err)
+	}
+	// signal watch.
+	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGKILL, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
-	_ = <-sigs
+	for {
+		sig := <-sigs
+		if sig != syscall.SIGHUP {
+			break
+		}
+		// SIGHUP, reload the ApplicationConfig.
+		logger.Info("got signal: %v, reloading config", sig)
+		_ = s.ReloadConfig()
+	}
 	s.ShutDown()
+	<-s.serverShutDownDone
 }
 
+// ShutDown graceful shutdown the Server.
+//
+// The server stop accepting new connections, waiting for in-flight requests completed
+// (but no longer than ServerOption.ShutDownTimeout), and then notify apps by App.OnShutDown(...).
 func (s *HostServer) ShutDown() {
-	s.quit <- true
+	s.shutDownOnce.Do(func() {
+		s.quit <- true
+	})
+}
+
+// ShutDownDone returns a channel that's closed when the Server has been shut down.
+func (s *HostServer) ShutDownDone() <-chan struct{} {
+	return s.serverShutDownDone
+}
+
+func (s *HostServer) shutDownHttpServer() error {
+	httpServer := s.httpServerOf()
+	if httpServer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.ShutDownTimeout)
+	defer cancel()
+	logger.Info("Draining server: %s, Addr: %s, timeout: %v", s.options.Name, s.Addr(), s.options.ShutDownTimeout)
+	return httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,101 @@
+package gw
+
+import (
+	"fmt"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/logger"
+	"reflect"
+	"strings"
+)
+
+// AppConfigValidator represents a ApplicationConfig validator,
+// It's used to check the new ApplicationConfig before it's take effect on reloading.
+type AppConfigValidator func(old, new *conf.ApplicationConfig) error
+
+// Config returns the current ApplicationConfig of the Server.
+func (s *HostServer) Config() *conf.ApplicationConfig {
+	cnf, _ := s.config.Load().(*conf.ApplicationConfig)
+	return cnf
+}
+
+// ReloadConfig re-run the ServerOption.AppConfigHandler pipeline, and swap the current ApplicationConfig.
+//
+// The new ApplicationConfig will be rejected(logged with a diff) if it's failed on validation,
+// apps that implementation IAppConfigChanged will be notified after the ApplicationConfig has been swapped.
+func (s *HostServer) ReloadConfig() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("reload config fail, err: %v", e)
+			logger.Error("%v", err)
+		}
+	}()
+	old, cnf, err := s.swapConfig()
+	if err != nil || cnf == nil {
+		return err
+	}
+	// the apps are notified without the lock, so they can call the methods of the server(such as Addr, GetRouters).
+	for _, app := range s.sortedApps {
+		if notifier, ok := app.instance.(IAppConfigChanged); ok {
+			notifier.OnConfigChanged(old, cnf)
+		}
+	}
+	return nil
+}
+
+// swapConfig swaps the current ApplicationConfig under the lock, the new one are nil if nothing changes.
+func (s *HostServer) swapConfig() (old, cnf *conf.ApplicationConfig, err error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.state < 1 {
+		return nil, nil, fmt.Errorf("server has not been compiled, can not reload config")
+	}
+	old = s.Config()
+	cnf = s.options.AppConfigHandler(s.options.bcs)
+	cnf.Compile()
+	var changes = conf.Diff(old, cnf)
+	if err = validateConfig(s.options.AppConfigValidators, old, cnf); err != nil {
+		logger.Error("reload config rejected, err: %v, changes:\n%s", err, strings.Join(changes, "\n"))
+		return nil, nil, err
+	}
+	if len(changes) == 0 {
+		logger.Info("reload config, nothing changes.")
+		return nil, nil, nil
+	}
+	policies, err := compileRateLimitPolicies(cnf)
+	if err != nil {
+		logger.Error("reload config rejected, err: %v, changes:\n%s", err, strings.Join(changes, "\n"))
+		return nil, nil, err
+	}
+	s.config.Store(cnf)
+	s.rateLimitPolicies.Store(policies)
+	s.options.cnf = cnf
+	logger.Info("config has been reloaded, changes:\n%s", strings.Join(changes, "\n"))
+	return old, cnf, nil
+}
+
+func validateConfig(validators []AppConfigValidator, old, new *conf.ApplicationConfig) error {
+	if err := new.Validate(); err != nil {
+		return err
+	}
+	for _, validator := range validators {
+		if err := validator(old, new); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestartRequiredConfigValidator returns a error if the config items that can not be take effect
+// without restart the Server has been changed. such as server,backend,security.crypto
+func RestartRequiredConfigValidator(old, new *conf.ApplicationConfig) error {
+	if !reflect.DeepEqual(old.Server, new.Server) {
+		return fmt.Errorf("server section has changed, it's requires restart the server")
+	}
+	if !reflect.DeepEqual(old.Backend, new.Backend) {
+		return fmt.Errorf("backend section has changed, it's requires restart the server")
+	}
+	if !reflect.DeepEqual(old.Security.Crypto, new.Security.Crypto) {
+		return fmt.Errorf("security.crypto section has changed, it's requires restart the server")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package gw_test
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func configPing(c *gw.Context) {
+	c.JSON200(c.Config().Security.Auth.Cookie.MaxAge)
+}
+
+func TestServer_ReloadConfig(t *testing.T) {
+	var maxAge = 3600
+	var server *gwtest.Server
+	var notified [][2]int
+	server = gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Security.Auth.Cookie.MaxAge = maxAge
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("ping", configPing)
+		},
+		OnConfigChangedFunc: func(old, new *conf.ApplicationConfig) {
+			// the server methods that takes the lock can be called by the callbacks.
+			assert.NotEmpty(t, server.GetRouters())
+			assert.NotEmpty(t, server.Addr())
+			notified = append(notified, [2]int{old.Security.Auth.Cookie.MaxAge, new.Security.Auth.Cookie.MaxAge})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	var out int
+	client.Call("configPing", nil, nil).AssertOK().DecodePayload(&out)
+	assert.Equal(t, 3600, out)
+
+	// nothing changes.
+	assert.Nil(t, server.ReloadConfig())
+	assert.Empty(t, notified)
+
+	maxAge = 600
+	assert.Nil(t, server.ReloadConfig())
+	assert.Equal(t, [][2]int{{3600, 600}}, notified)
+	assert.Equal(t, 600, server.Config().Security.Auth.Cookie.MaxAge)
+	client.Call("configPing", nil, nil).AssertOK().DecodePayload(&out)
+	assert.Equal(t, 600, out)
+
+	// the invalid config are rejected, the current config are kept.
+	maxAge = -1
+	assert.NotNil(t, server.ReloadConfig())
+	assert.Len(t, notified, 1)
+	assert.Equal(t, 600, server.Config().Security.Auth.Cookie.MaxAge)
+	client.Call("configPing", nil, nil).AssertOK().DecodePayload(&out)
+	assert.Equal(t, 600, out)
+}
+
+func TestServer_InvalidConfig(t *testing.T) {
+	opts := gw.NewServerOption(&conf.BootConfig{})
+	opts.Name = "gwtest-invalid-config"
+	opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+		cnf := gwtest.DefaultConfig()
+		cnf.Security.Cors.Policies = append(cnf.Security.Cors.Policies, conf.CorsPolicy{
+			AllowOrigins:     []string{"*"},
+			AllowCredentials: true,
+		})
+		return cnf
+	}
+	server := gw.NewServerWithOption(opts)
+	// the invalid config are rejected on startup, before the apps are initialized.
+	assert.Panics(t, func() { server.Handler() })
+}
@@ -0,0 +1,312 @@
+package gw
+
+import (
+	"fmt"
+	"github.com/oceanho/gw/logger"
+	"io/ioutil"
+	"path"
+	"plugin"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginManifest represents a gw plugin manifest.
+//
+// A plugin can be exports the AppPlugin symbol as a PluginManifest, It's declares the gw version
+// that the plugin build with and the app's dependencies. see dylib/stor for example.
+type PluginManifest struct {
+	// GwVersion is the gw version that the plugin build with.
+	GwVersion string
+	// DependOn is the app names that the plugin app depends on.
+	DependOn []string
+	// New returns the plugin app instance.
+	New func() App
+}
+
+// PluginError represents a rejected(or failed) plugin file.
+type PluginError struct {
+	File   string
+	Symbol string
+	Err    error
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("plugin: %s, symbol: %s, err: %v", e.File, e.Symbol, e.Err)
+}
+
+// PluginErrors represents a report of rejected(or failed) plugin files.
+type PluginErrors []*PluginError
+
+func (e PluginErrors) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d plugin(s) are rejected:", len(e)))
+	for _, pe := range e {
+		sb.WriteString("\n  ")
+		sb.WriteString(pe.Error())
+	}
+	return sb.String()
+}
+
+type pluginLoader struct {
+	locker   sync.Mutex
+	loaded   map[string]bool
+	rejected map[string]time.Time
+	stop     chan struct{}
+	stopped  bool
+}
+
+func newPluginLoader() *pluginLoader {
+	return &pluginLoader{
+		loaded:   make(map[string]bool),
+		rejected: make(map[string]time.Time),
+		stop:     make(chan struct{}),
+	}
+}
+
+// isLoaded returns true if the file has been loaded.
+func (l *pluginLoader) isLoaded(file string) bool {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	return l.loaded[file]
+}
+
+// markLoaded marks the file as loaded.
+// The file are marked after it's app registered successfully, so the rejected files can be retried(such as replaced by a fixed one).
+func (l *pluginLoader) markLoaded(file string) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	l.loaded[file] = true
+}
+
+// isRejected returns true if the file has been rejected and not changed since then.
+func (l *pluginLoader) isRejected(file string, modTime time.Time) bool {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	t, ok := l.rejected[file]
+	return ok && t.Equal(modTime)
+}
+
+// markRejected remember the rejected file with it's modification time, the watcher retry it after the file changed.
+func (l *pluginLoader) markRejected(file string, modTime time.Time) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	l.rejected[file] = modTime
+}
+
+func (l *pluginLoader) stopWatch() {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	if !l.stopped {
+		l.stopped = true
+		close(l.stop)
+	}
+}
+
+// RegisterByPluginDir register app instances into the server with gw plugin mode.
+//
+// The plugin symbol(ServerOption.PluginSymbolName) can be a App, a *App, a func() App factory or a PluginManifest.
+// returns a PluginErrors report if there are plugins are rejected, the accepted plugins are still registered.
+func (s *HostServer) RegisterByPluginDir(dirs ...string) error {
+	return s.registerByPluginDir(false, dirs...)
+}
+
+// registerByPluginDir register apps from the plugin dirs,
+// the unchanged rejected files are skipped if watching is true, so the watcher does not re-open and report them on every tick.
+func (s *HostServer) registerByPluginDir(watching bool, dirs ...string) error {
+	var errs PluginErrors
+	for _, d := range dirs {
+		rd, err := ioutil.ReadDir(d)
+		if err != nil {
+			errs = append(errs, &PluginError{File: d, Err: err})
+			continue
+		}
+		for _, fi := range rd {
+			pn := path.Join(d, fi.Name())
+			if fi.IsDir() {
+				if err := s.registerByPluginDir(watching, pn); err != nil {
+					errs = append(errs, err.(PluginErrors)...)
+				}
+				continue
+			}
+			if !strings.HasSuffix(fi.Name(), s.options.PluginSymbolSuffix) {
+				logger.Info("suffix not is %s, skipping file: %s", s.options.PluginSymbolSuffix, pn)
+				continue
+			}
+			if watching && s.plugins.isRejected(pn, fi.ModTime()) {
+				continue
+			}
+			if err := s.RegisterByPluginFile(pn); err != nil {
+				s.plugins.markRejected(pn, fi.ModTime())
+				errs = append(errs, err.(*PluginError))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		logger.Error("%v", errs)
+		return errs
+	}
+	return nil
+}
+
+// RegisterByPluginFile register a app instance from the plugin file into the server.
+// It's returns a *PluginError if the plugin can not be loaded or are incompatible with the current gw Version.
+func (s *HostServer) RegisterByPluginFile(file string) error {
+	symbol := s.options.PluginSymbolName
+	if s.plugins.isLoaded(file) {
+		return nil
+	}
+	p, err := plugin.Open(file)
+	if err != nil {
+		return &PluginError{File: file, Symbol: symbol, Err: err}
+	}
+	sym, err := p.Lookup(symbol)
+	if err != nil {
+		return &PluginError{File: file, Symbol: symbol, Err: err}
+	}
+	manifest, err := resolvePluginSymbol(sym)
+	if err != nil {
+		return &PluginError{File: file, Symbol: symbol, Err: err}
+	}
+	return s.registerPluginManifest(file, symbol, manifest)
+}
+
+// registerPluginManifest checks the gw version of the plugin manifest, and register the app of it.
+func (s *HostServer) registerPluginManifest(file, symbol string, manifest *PluginManifest) error {
+	if manifest.GwVersion == "" {
+		if !s.options.PluginAllowUnversioned {
+			return &PluginError{File: file, Symbol: symbol,
+				Err: fmt.Errorf("plugin declares no gw version, exports a PluginManifest with the GwVersion(or enable ServerOption.PluginAllowUnversioned)")}
+		}
+		logger.Warn("plugin: %s declares no gw version, the compatibility are not checked.", file)
+	} else if !isCompatibleVersion(Version, manifest.GwVersion) {
+		return &PluginError{File: file, Symbol: symbol,
+			Err: fmt.Errorf("incompatible gw version, plugin: %s, server: %s", manifest.GwVersion, Version)}
+	}
+	app := manifest.New()
+	if app == nil {
+		return &PluginError{File: file, Symbol: symbol, Err: fmt.Errorf("plugin returns a nil app")}
+	}
+	if err := s.registerApp(app, manifest.DependOn); err != nil {
+		return &PluginError{File: file, Symbol: symbol, Err: err}
+	}
+	s.plugins.markLoaded(file)
+	logger.Info("app %s has been registered from plugin: %s", app.Name(), file)
+	return nil
+}
+
+// WatchPluginDir watching the plugin dirs, and register apps from newly dropped plugin files.
+// The rejected files are reported once, and retried only after they are changed.
+// The watcher stops when the server starts, apps can not be registered after that.
+func (s *HostServer) WatchPluginDir(interval time.Duration, dirs ...string) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.plugins.stop:
+				return
+			case <-ticker.C:
+				_ = s.registerByPluginDir(true, dirs...)
+			}
+		}
+	}()
+}
+
+// registerApp returns a error if the app can not be registered(the server has been started, or the app name are registered).
+func (s *HostServer) registerApp(app App, dependOn []string) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	appName := app.Name()
+	if s.state > 0 {
+		return fmt.Errorf("server has been started, app %s can not be registered", appName)
+	}
+	if _, ok := s.apps[appName]; ok {
+		return fmt.Errorf("app %s has been registered", appName)
+	}
+	s.apps[appName] = internalApp{
+		instance: app,
+		dependOn: dependOn,
+	}
+	s.appNames = append(s.appNames, appName)
+	return nil
+}
+
+var appType = reflect.TypeOf((*App)(nil)).Elem()
+
+// resolvePluginSymbol returns a PluginManifest from the plugin symbol.
+//
+// plugin.Lookup returns a pointer of the variable symbol, so the symbol can be
+// App, *App, **App, func() App, *func() App, PluginManifest or *PluginManifest.
+// The manifest of the non-manifest symbols has no GwVersion, they are rejected unless ServerOption.PluginAllowUnversioned.
+func resolvePluginSymbol(sym interface{}) (*PluginManifest, error) {
+	switch v := sym.(type) {
+	case *PluginManifest:
+		if v == nil || v.New == nil {
+			return nil, fmt.Errorf("manifest missing New func")
+		}
+		return v, nil
+	case PluginManifest:
+		return resolvePluginSymbol(&v)
+	case func() App:
+		return &PluginManifest{New: v}, nil
+	case *func() App:
+		if v == nil || *v == nil {
+			return nil, fmt.Errorf("nil app factory")
+		}
+		return &PluginManifest{New: *v}, nil
+	}
+	val := reflect.ValueOf(sym)
+	for val.IsValid() {
+		if val.Type().Implements(appType) && !(val.Kind() == reflect.Ptr && val.IsNil()) {
+			app := val.Interface().(App)
+			return &PluginManifest{New: func() App { return app }}, nil
+		}
+		if val.Kind() != reflect.Ptr || val.IsNil() {
+			break
+		}
+		val = val.Elem()
+	}
+	return nil, fmt.Errorf("symbol type %T is not a App, *App, func() App or PluginManifest", sym)
+}
+
+// isCompatibleVersion returns true if the plugin that build with gw version can be load by the host gw version.
+// The major version must be equals (also the minor version if major is 0),
+// and the plugin minor version must not greater than the host.
+// An empty version are treated as a development build, It's always compatible.
+func isCompatibleVersion(host, plugin string) bool {
+	if host == "" || plugin == "" {
+		return true
+	}
+	hMajor, hMinor, ok1 := parseMajorMinor(host)
+	pMajor, pMinor, ok2 := parseMajorMinor(plugin)
+	if !ok1 || !ok2 {
+		return host == plugin
+	}
+	if hMajor != pMajor {
+		return false
+	}
+	if hMajor == 0 {
+		return hMinor == pMinor
+	}
+	return pMinor <= hMinor
+}
+
+func parseMajorMinor(version string) (int, int, bool) {
+	version = strings.TrimPrefix(version, "v")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,149 @@
+package gw
+
+import (
+	"github.com/oceanho/gw/conf"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestResolvePluginSymbol(t *testing.T) {
+	var app App = testerDependApp{name: "a"}
+	var appPtr = &testerDependApp{name: "b"}
+	var factory = func() App { return testerDependApp{name: "c"} }
+	var manifest = PluginManifest{
+		GwVersion: "1.2.0",
+		DependOn:  []string{"a"},
+		New:       factory,
+	}
+	var cases = []struct {
+		sym  interface{}
+		name string
+	}{
+		{&app, "a"},
+		{&appPtr, "b"},
+		{factory, "c"},
+		{&factory, "c"},
+		{&manifest, "c"},
+	}
+	for _, c := range cases {
+		m, err := resolvePluginSymbol(c.sym)
+		assert.Nil(t, err)
+		assert.Equal(t, c.name, m.New().Name())
+	}
+	m, _ := resolvePluginSymbol(&manifest)
+	assert.Equal(t, []string{"a"}, m.DependOn)
+
+	var nilPtr *testerDependApp
+	var str = "not a app"
+	for _, sym := range []interface{}{&nilPtr, &str, &PluginManifest{}} {
+		_, err := resolvePluginSymbol(sym)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestIsCompatibleVersion(t *testing.T) {
+	assert.True(t, isCompatibleVersion("", "1.0.0"))
+	assert.True(t, isCompatibleVersion("1.2.0", ""))
+	assert.True(t, isCompatibleVersion("v1.2.3", "1.2.0"))
+	assert.True(t, isCompatibleVersion("1.3.0", "1.2.9"))
+	assert.False(t, isCompatibleVersion("1.2.0", "1.3.0"))
+	assert.False(t, isCompatibleVersion("2.0.0", "1.0.0"))
+	assert.False(t, isCompatibleVersion("0.2.0", "0.1.0"))
+	assert.True(t, isCompatibleVersion("dev", "dev"))
+	assert.False(t, isCompatibleVersion("dev", "1.0.0"))
+}
+
+func TestSortApps_PluginDependency(t *testing.T) {
+	apps, names := newTesterDependApps(testerDependApp{name: "b"}, testerDependApp{name: "a"})
+	b := apps["b"]
+	b.dependOn = []string{"a"}
+	apps["b"] = b
+	sorted, err := sortApps(apps, names)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", sorted[0].instance.Name())
+	assert.Equal(t, "b", sorted[1].instance.Name())
+}
+
+func TestRegisterByPluginFile_Rejected(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "bad.so")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("not a plugin"), 0644))
+	opts := NewServerOption(conf.DefaultBootConfig())
+	opts.Name = "gw.plugin.tester"
+	s := NewServerWithOption(opts)
+	// the rejected files are not marked as loaded, they are reported on every attempts.
+	for i := 0; i < 2; i++ {
+		err := s.RegisterByPluginFile(file)
+		assert.IsType(t, &PluginError{}, err)
+		assert.False(t, s.plugins.isLoaded(file))
+	}
+	err := s.RegisterByPluginDir(dir)
+	assert.IsType(t, PluginErrors{}, err)
+	assert.Len(t, err.(PluginErrors), 1)
+}
+
+func TestRegisterByPluginDir_Watching(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "bad.so")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("not a plugin"), 0644))
+	opts := NewServerOption(conf.DefaultBootConfig())
+	opts.Name = "gw.plugin.watcher.tester"
+	s := NewServerWithOption(opts)
+
+	err := s.registerByPluginDir(true, dir)
+	assert.IsType(t, PluginErrors{}, err)
+	assert.Len(t, err.(PluginErrors), 1)
+
+	// the unchanged rejected file are skipped by the watcher.
+	assert.Nil(t, s.registerByPluginDir(true, dir))
+
+	// but still reported by the explicit calls.
+	assert.NotNil(t, s.RegisterByPluginDir(dir))
+
+	// the changed file are retried.
+	modTime := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(file, modTime, modTime))
+	err = s.registerByPluginDir(true, dir)
+	assert.IsType(t, PluginErrors{}, err)
+	assert.Len(t, err.(PluginErrors), 1)
+	assert.Nil(t, s.registerByPluginDir(true, dir))
+}
+
+func TestRegisterPluginManifest(t *testing.T) {
+	opts := NewServerOption(conf.DefaultBootConfig())
+	opts.Name = "gw.plugin.manifest.tester"
+	s := NewServerWithOption(opts)
+	version := Version
+	Version = "v1.2.0"
+	defer func() {
+		Version = version
+	}()
+	newApp := func(name string) func() App {
+		return func() App { return testerDependApp{name: name} }
+	}
+
+	// the plugins that declares no gw version are rejected by default.
+	err := s.registerPluginManifest("a.so", "AppPlugin", &PluginManifest{New: newApp("a")})
+	assert.IsType(t, &PluginError{}, err)
+	assert.Contains(t, err.Error(), "declares no gw version")
+	assert.False(t, s.plugins.isLoaded("a.so"))
+	s.options.PluginAllowUnversioned = true
+	assert.Nil(t, s.registerPluginManifest("a.so", "AppPlugin", &PluginManifest{New: newApp("a")}))
+	assert.True(t, s.plugins.isLoaded("a.so"))
+
+	err = s.registerPluginManifest("b.so", "AppPlugin", &PluginManifest{GwVersion: "v1.3.0", New: newApp("b")})
+	assert.Contains(t, err.Error(), "incompatible gw version")
+
+	// the apps that can not be registered are reported.
+	err = s.registerPluginManifest("a2.so", "AppPlugin", &PluginManifest{GwVersion: Version, New: newApp("a")})
+	assert.Contains(t, err.Error(), "app a has been registered")
+	s.state++
+	err = s.registerPluginManifest("c.so", "AppPlugin", &PluginManifest{GwVersion: Version, New: newApp("c")})
+	assert.IsType(t, &PluginError{}, err)
+	assert.Contains(t, err.Error(), "server has been started")
+	assert.False(t, s.plugins.isLoaded("c.so"))
+}
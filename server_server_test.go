@@ -0,0 +1,251 @@
+package gw_test
+
+import (
+	"fmt"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func serverPing(c *gw.Context) {
+	c.JSON200("pong")
+}
+
+func newStartServer(t *testing.T, addr string) *gwtest.Server {
+	return gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.Addr = addr
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("ping", serverPing)
+		},
+	})
+}
+
+func TestServer_Start(t *testing.T) {
+	server := newStartServer(t, "127.0.0.1:0")
+	assert.Equal(t, "127.0.0.1:0", server.Addr())
+
+	// the concurrent starts are bound only once.
+	var wg sync.WaitGroup
+	var errs = make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = server.Start()
+		}(i)
+	}
+	wg.Wait()
+	var started int
+	for _, err := range errs {
+		if err == nil {
+			started++
+			continue
+		}
+		assert.Equal(t, gw.ErrServerHasStarted, err)
+	}
+	assert.Equal(t, 1, started)
+
+	// the actual address of the ephemeral port.
+	_, port, err := net.SplitHostPort(server.Addr())
+	assert.Nil(t, err)
+	assert.NotEqual(t, "0", port)
+	resp, err := http.Get(fmt.Sprintf("http://%s%s", server.Addr(), server.Client().Router("serverPing").UrlPath))
+	if assert.Nil(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestServer_Start_BindError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer listener.Close()
+	server := newStartServer(t, listener.Addr().String())
+	err = server.Start()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "listen on "+listener.Addr().String())
+	assert.Equal(t, listener.Addr().String(), server.Addr())
+}
+
+func TestServer_Addr_Compiling(t *testing.T) {
+	var store *gwtest.Store
+	opts := gw.NewServerOption(&conf.BootConfig{})
+	opts.Name = "gw.addr.tester"
+	opts.Addr = "127.0.0.1:0"
+	opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+		return gwtest.DefaultConfig()
+	}
+	opts.BackendStoreHandler = func(cnf *conf.ApplicationConfig) gw.IStore {
+		store, _ = gwtest.NewStore(cnf)
+		return store
+	}
+	server := gw.NewServerWithOption(opts)
+	var addr string
+	server.Register(&gwtest.App{
+		OnStartFunc: func(state *gw.ServerState) {
+			// the apps can be call Addr() while the server compiling.
+			addr = server.Addr()
+		},
+	})
+	compiled := make(chan struct{})
+	go func() {
+		defer close(compiled)
+		server.Handler()
+	}()
+	select {
+	case <-compiled:
+		assert.Equal(t, "127.0.0.1:0", addr)
+		server.ShutDown()
+		<-server.ShutDownDone()
+		store.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server compiling are dead locked by Addr()")
+	}
+}
+
+type shutdownApp struct {
+	*gwtest.App
+	deps []string
+}
+
+func (a shutdownApp) DependOn() []string {
+	return a.deps
+}
+
+func TestServer_ShutDown_Drain(t *testing.T) {
+	var locker sync.Mutex
+	var events []string
+	record := func(event string) {
+		locker.Lock()
+		defer locker.Unlock()
+		events = append(events, event)
+	}
+	started, release := make(chan struct{}), make(chan struct{})
+	appOf := func(name string, deps ...string) gw.App {
+		return shutdownApp{
+			App: &gwtest.App{
+				AppName:    name,
+				RouterPath: name,
+				OnShutDownFunc: func(state *gw.ServerState) {
+					record("app:" + name)
+				},
+			},
+			deps: deps,
+		}
+	}
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Service.Health.Enabled = true
+			cnf.Service.Health.Readiness = "/readyz"
+			cnf.Security.Auth.AllowUrls = []conf.AllowUrl{{Name: "slow", Urls: []string{"GET:/api/v1/tester/slow"}}}
+			return cnf
+		}
+	}, appOf("b", "a"), &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("slow", func(c *gw.Context) {
+				close(started)
+				<-release
+				record("request")
+				c.JSON200("done")
+			})
+		},
+	}, appOf("a"))
+	server.RegisterShutDownHandler(func(s *gw.HostServer) error {
+		record("handler")
+		return nil
+	})
+	if !assert.Nil(t, server.Start()) {
+		return
+	}
+
+	var resp *http.Response
+	var err error
+	requested := make(chan struct{})
+	go func() {
+		defer close(requested)
+		resp, err = http.Get(fmt.Sprintf("http://%s/api/v1/tester/slow", server.Addr()))
+	}()
+	<-started
+	go server.ShutDown()
+
+	// the readiness flips while the request is in flight.
+	client := server.Client()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.Get("/readyz").StatusCode != http.StatusServiceUnavailable && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	record("not-ready")
+	close(release)
+	<-requested
+	if assert.Nil(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// the handlers and the apps(by reverse dependency order) are notified after the in-flight requests finished.
+	deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		locker.Lock()
+		n := len(events)
+		locker.Unlock()
+		if n >= 5 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	locker.Lock()
+	defer locker.Unlock()
+	assert.Equal(t, []string{"not-ready", "request", "handler", "app:b", "app:a"}, events)
+}
+
+func TestServer_ShutDown_Timeout(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.ShutDownTimeout = 20 * time.Millisecond
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Security.Auth.AllowUrls = []conf.AllowUrl{{Name: "slow", Urls: []string{"GET:/api/v1/tester/slow"}}}
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("slow", func(c *gw.Context) {
+				close(started)
+				<-release
+				c.JSON200(c.Config().Service.Name)
+			})
+		},
+	})
+	if !assert.Nil(t, server.Start()) {
+		return
+	}
+	var resp *http.Response
+	var err error
+	requested := make(chan struct{})
+	go func() {
+		defer close(requested)
+		resp, err = http.Get(fmt.Sprintf("http://%s/api/v1/tester/slow", server.Addr()))
+	}()
+	<-started
+	server.ShutDown()
+
+	// the request that still running after the drain timeout are served by the unregistered server.
+	<-server.ShutDownDone()
+	assert.NotNil(t, server.State())
+	close(release)
+	<-requested
+	if assert.Nil(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
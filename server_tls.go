@@ -0,0 +1,210 @@
+package gw
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/logger"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+	tlsClientAuthModes = map[string]tls.ClientAuthType{
+		"":                   tls.NoClientCert,
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify-if-given":    tls.VerifyClientCertIfGiven,
+		"require-and-verify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// certificateReloader represents a server certificate holder,
+// It's reload the certificate from disk when the cert/key files has changed.
+type certificateReloader struct {
+	locker    sync.RWMutex
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string, interval time.Duration) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certificateReloader) reload() error {
+	modTime, err := r.lastModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate(cert: %s, key: %s), err: %v", r.certFile, r.keyFile, err)
+	}
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.lastCheck = time.Now()
+	return nil
+}
+
+func (r *certificateReloader) lastModTime() (time.Time, error) {
+	var modTime time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return modTime, err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+func (r *certificateReloader) shouldReload() bool {
+	if r.interval <= 0 {
+		return false
+	}
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if time.Since(r.lastCheck) < r.interval {
+		return false
+	}
+	r.lastCheck = time.Now()
+	modTime, err := r.lastModTime()
+	if err != nil {
+		logger.Warn("check certificate files fail, err: %v", err)
+		return false
+	}
+	return modTime.After(r.modTime)
+}
+
+// GetCertificate returns the current certificate, It's used by tls.Config.GetCertificate.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.shouldReload() {
+		if err := r.reload(); err != nil {
+			// keep the old certificate.
+			logger.Error("reload certificate fail, err: %v", err)
+		} else {
+			logger.Info("certificate has been reloaded, cert: %s", r.certFile)
+		}
+	}
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+	return r.cert, nil
+}
+
+func readTLSMaterial(content, format string) ([]byte, error) {
+	if strings.ToLower(format) == "base64" {
+		return base64.StdEncoding.DecodeString(content)
+	}
+	return ioutil.ReadFile(content)
+}
+
+func newTLSConfig(cnf conf.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"http/1.1"},
+	}
+	if cnf.MinVersion != "" {
+		v, ok := tlsVersions[cnf.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("not supports tls minVersion: %s", cnf.MinVersion)
+		}
+		tlsConfig.MinVersion = v
+	}
+	if len(cnf.CipherSuites) > 0 {
+		var suites = make(map[string]uint16)
+		for _, c := range tls.CipherSuites() {
+			suites[c.Name] = c.ID
+		}
+		for _, name := range cnf.CipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("not supports(or insecure) tls cipher suite: %s", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	// server certificate.
+	cert := cnf.Certificate
+	if strings.ToLower(cert.Format) == "base64" {
+		certPEM, err := base64.StdEncoding.DecodeString(cert.Cert)
+		if err != nil {
+			return nil, fmt.Errorf("decode tls certificate, err: %v", err)
+		}
+		keyPEM, err := base64.StdEncoding.DecodeString(cert.Key)
+		if err != nil {
+			return nil, fmt.Errorf("decode tls certificate key, err: %v", err)
+		}
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("load tls certificate, err: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	} else {
+		reloader, err := newCertificateReloader(cert.Cert, cert.Key, time.Duration(cnf.ReloadInterval)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = reloader.GetCertificate
+	}
+
+	// mutual-TLS.
+	clientAuth := cnf.ClientAuth
+	mode, ok := tlsClientAuthModes[strings.ToLower(clientAuth.Mode)]
+	if !ok {
+		return nil, fmt.Errorf("not supports tls clientAuth mode: %s", clientAuth.Mode)
+	}
+	tlsConfig.ClientAuth = mode
+	if mode != tls.NoClientCert && clientAuth.CA != "" {
+		b, err := readTLSMaterial(clientAuth.CA, clientAuth.Format)
+		if err != nil {
+			return nil, fmt.Errorf("read tls client CA, err: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("invalid tls client CA, no certificates found")
+		}
+		tlsConfig.ClientCAs = pool
+	} else if mode == tls.VerifyClientCertIfGiven || mode == tls.RequireAndVerifyClientCert {
+		return nil, fmt.Errorf("tls clientAuth mode: %s, but missing clientAuth.ca", clientAuth.Mode)
+	}
+	return tlsConfig, nil
+}
+
+// ClientCertificate returns the verified client certificate of the mutual-TLS connection.
+// returns nil if the request are not a mutual-TLS request or the client certificate not verified.
+func (c *Context) ClientCertificate() *x509.Certificate {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
@@ -0,0 +1,81 @@
+package gw
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/oceanho/gw/conf"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	certFile = filepath.Join(dir, "app.crt")
+	keyFile = filepath.Join(dir, "app.key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	assert.Nil(t, ioutil.WriteFile(certFile, certPEM, 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gw-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCertificate(t, dir, "gw-1")
+
+	var cnf conf.TLS
+	cnf.Enabled = true
+	cnf.MinVersion = "1.3"
+	cnf.Certificate.Cert = certFile
+	cnf.Certificate.Key = keyFile
+	cnf.ReloadInterval = 1
+	tlsConfig, err := newTLSConfig(cnf)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+
+	cert, err := tlsConfig.GetCertificate(nil)
+	assert.Nil(t, err)
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	assert.Equal(t, "gw-1", leaf.Subject.CommonName)
+
+	// hot reload.
+	time.Sleep(time.Millisecond * 1100)
+	writeTestCertificate(t, dir, "gw-2")
+	future := time.Now().Add(time.Second * 2)
+	_ = os.Chtimes(certFile, future, future)
+	cert, err = tlsConfig.GetCertificate(nil)
+	assert.Nil(t, err)
+	leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+	assert.Equal(t, "gw-2", leaf.Subject.CommonName)
+
+	// mTLS requires client CA.
+	cnf.ClientAuth.Mode = "require-and-verify"
+	_, err = newTLSConfig(cnf)
+	assert.NotNil(t, err)
+
+	cnf.ClientAuth.Mode = "none"
+	cnf.MinVersion = "2.0"
+	_, err = newTLSConfig(cnf)
+	assert.NotNil(t, err)
+}
@@ -26,7 +26,7 @@ const (
 )
 
 // GW framework state Middleware.
-func gwState(serverName string) gin.HandlerFunc {
+func gwState(server *HostServer) gin.HandlerFunc {
 	// code copies from gin framework.
 	var out io.Writer = os.Stderr
 	var ginLogger *log.Logger
@@ -39,7 +39,7 @@ func gwState(serverName string) gin.HandlerFunc {
 		// 1. register the HostServer state into gin.Context
 		// 2. process request, try got User from the http requests.
 		//
-		c.Set(gwAppKey, serverName)
+		c.Set(gwAppKey, server)
 		s := getHostServer(c)
 		requestId := getRequestId(s, c)
 		defer func() {
@@ -47,6 +47,7 @@ func gwState(serverName string) gin.HandlerFunc {
 			var httpRequest []byte
 			var headers []string
 			if err := recover(); err != nil {
+				recordPanic(s, c)
 				// Check for a broken connection, as it is not really a
 				// condition that warrants a panic stack trace.
 				var brokenPipe bool
@@ -83,8 +84,8 @@ func gwState(serverName string) gin.HandlerFunc {
 					c.Error(err.(error)) // nolint: errcheck
 					c.Abort()
 				} else {
-					body := s.RespBodyBuildFunc(http.StatusInternalServerError, requestId, errDefault500Msg, nil)
-					c.JSON(http.StatusInternalServerError, body)
+					respErr(c, requestId, http.StatusInternalServerError,
+						ErrInternalServerError.WithMessage(errDefault500Msg), nil)
 				}
 			}
 			// handle panic Errors
@@ -102,17 +103,20 @@ func gwState(serverName string) gin.HandlerFunc {
 				}
 			}
 			// After handlers.
-			if s.afterHookMaxIdx > 0 {
+			if s.afterHookMaxIdx >= 0 {
 				for i := s.afterHookMaxIdx; i >= 0; i-- {
 					s.afterHooks[i].OnAfter(c)
 				}
 			}
 		}()
-		// before handlers
+		// before handlers, the request are responded if a hook aborted it(such as CORS preflight, body limit).
 		for _, hook := range s.beforeHooks {
 			if hook.OnBefore != nil {
 				hook.OnBefore(c)
 			}
+			if c.IsAborted() {
+				return
+			}
 		}
 		// gw framework handler.
 		sid, ok := getSid(s, c)
@@ -132,7 +136,15 @@ func encryptSid(s *HostServer, authParam AuthParameter) (sid, credential string,
 
 	// Create a sid.
 	sid = s.SessionSidCreationFunc(authParam)
+	credential, ok = encryptSidCredential(s, sid)
+	if !ok {
+		return "", "", false
+	}
+	return sid, credential, true
+}
 
+// encryptSidCredential returns the credential of the sid, It's can be sent by the cookie or X-Auth-Token header.
+func encryptSidCredential(s *HostServer, sid string) (string, bool) {
 	// sid protect password keys
 	rdnKey := secure.RandomStr(32)
 	block := secure.AesBlock(rdnKey)
@@ -140,11 +152,12 @@ func encryptSid(s *HostServer, authParam AuthParameter) (sid, credential string,
 	passportSrc := []byte(sid)
 	passportDst := make([]byte, len(passportSrc))
 	encryptor.XORKeyStream(passportDst, passportSrc)
-	encryptedPassport := string(passportDst)
+	// the encrypted parts are encoded, so they have no separators(",") in it.
+	encryptedPassport := secure.EncodeBase64URL(passportDst)
 	rdnKeySrc := []byte(rdnKey)
 	rdnKeyDst := make([]byte, len(rdnKeySrc))
 	_ = s.Protect.Encrypt(rdnKeyDst, rdnKeySrc)
-	encryptedRdnKey := string(rdnKeyDst)
+	encryptedRdnKey := secure.EncodeBase64URL(rdnKeyDst)
 
 	_sid := fmt.Sprintf("%s,%s", encryptedPassport, encryptedRdnKey)
 
@@ -161,9 +174,19 @@ func encryptSid(s *HostServer, authParam AuthParameter) (sid, credential string,
 	err := s.Protect.Encrypt(dst, src)
 	if err != nil {
 		logger.Error("encryptSid() -> s.crypto.Encrypt(dst,b) fail, err: %v.", err)
-		return "", "", false
+		return "", false
+	}
+	return secure.EncodeBase64URL(dst), true
+}
+
+// SessionCredential returns the credential of a saved session, the same one as the login API responds.
+// It's can be sent by the cookie(Security.Auth.Cookie.Key) or X-Auth-Token header, such as tests and SSO bridges.
+func (s *HostServer) SessionCredential(sid string) (string, error) {
+	credential, ok := encryptSidCredential(s, sid)
+	if !ok {
+		return "", fmt.Errorf("create credential of session fail")
 	}
-	return sid, secure.EncodeBase64URL(dst), true
+	return credential, nil
 }
 
 func decryptSid(s *HostServer, secureSid string, client string) (passport string, ok bool) {
@@ -197,14 +220,26 @@ func decryptSid(s *HostServer, secureSid string, client string) (passport string
 		return "", false
 	}
 
-	rdnKeySrc := []byte(encryptedRdnKey)
+	rdnKeySrc, err := secure.DecodeBase64URL(encryptedRdnKey)
+	if err != nil {
+		logger.Warn("got a invalid secureSid key from %s", client)
+		return "", false
+	}
 	rdnKeyDst := make([]byte, len(rdnKeySrc))
 	_ = s.Protect.Decrypt(rdnKeyDst, rdnKeySrc)
 	rdnKey := string(rdnKeyDst)
+	if len(rdnKey) != 32 {
+		logger.Warn("got a invalid secureSid key from %s", client)
+		return "", false
+	}
 
 	block := secure.AesBlock(rdnKey)
 	decryptor := secure.AesDecryptCFB(rdnKey, block)
-	sidSrc := []byte(encryptedSid)
+	sidSrc, err := secure.DecodeBase64URL(encryptedSid)
+	if err != nil {
+		logger.Warn("got a invalid secureSid data from %s", client)
+		return "", false
+	}
 	sidDst := make([]byte, len(sidSrc))
 	decryptor.XORKeyStream(sidDst, sidSrc)
 	return string(sidDst), true
@@ -221,7 +256,7 @@ func getSid(s *HostServer, c *gin.Context) (string, bool) {
 	}
 	// Trust Sid header.
 	// sid can be decrypt AT gateway (OpenResty, Nginx etc.) layout.
-	sidKey := s.conf.Security.Auth.TrustSidKey
+	sidKey := s.Config().Security.Auth.TrustSidKey
 	if sidKey != "" {
 		sid := c.GetHeader(sidKey)
 		if sid != "" {
@@ -229,7 +264,7 @@ func getSid(s *HostServer, c *gin.Context) (string, bool) {
 		}
 	}
 	client := getClient(c)
-	cks := s.conf.Security.Auth.Cookie
+	cks := s.Config().Security.Auth.Cookie
 	// 1. Query cookie get Sid.
 	_sid, err := c.Cookie(cks.Key)
 	if _sid == "" || err != nil {
@@ -243,12 +278,13 @@ func getSid(s *HostServer, c *gin.Context) (string, bool) {
 }
 
 func config(c *gin.Context) *conf.ApplicationConfig {
-	return getHostServer(c).conf
+	return getHostServer(c).Config()
 }
 
+// getHostServer returns the HostServer of the request, It's not looked up by name,
+// so the requests that still running after the server unregistered(shut down) are not affected.
 func getHostServer(c *gin.Context) *HostServer {
-	serverName := c.MustGet(gwAppKey).(string)
-	return servers[serverName].Server
+	return c.MustGet(gwAppKey).(*HostServer)
 }
 
 //
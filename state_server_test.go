@@ -0,0 +1,39 @@
+package gw_test
+
+import (
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func sessionUser(c *gw.Context) {
+	c.JSON200(c.User().Passport)
+}
+
+func TestServer_Session(t *testing.T) {
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Security.Auth.TrustSidKey = "X-Gw-Trust-Sid"
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("user", sessionUser)
+		},
+	})
+	// the credentials are always can be decrypted.
+	for i := 0; i < 64; i++ {
+		var passport string
+		client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+		client.Call("sessionUser", nil, nil).AssertOK().DecodePayload(&passport)
+		assert.Equal(t, "gw", passport)
+	}
+	// the raw sid from the trust sid header are not accepted.
+	assert.Nil(t, server.State().SessionStateManager().Save("raw-sid", gw.User{ID: 2, TenantId: 10, Passport: "raw"}))
+	server.Client().WithHeader("X-Gw-Trust-Sid", "raw-sid").
+		Call("sessionUser", nil, nil).AssertStatus(http.StatusUnauthorized)
+}
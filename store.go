@@ -6,9 +6,12 @@ import (
 	"github.com/go-redis/redis/v8"
 	mysqlDb "github.com/go-sql-driver/mysql"
 	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/logger"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // IStore represents a Store engine of gw framework.
@@ -91,6 +94,75 @@ func (b *backendWrapper) GetCacheStoreByName(name string) *redis.Client {
 	return b.globalCacheSetup(db)
 }
 
+// cacheStoreByName returns the cache store of name, returns a error if the store are not available.
+func cacheStoreByName(s *HostServer, name string) (client *redis.Client, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return s.Store.GetCacheStoreByName(name), nil
+}
+
+const (
+	cacheStoreRetryMinBackoff = time.Second
+	cacheStoreRetryMaxBackoff = time.Minute
+)
+
+// cacheStoreResolver resolves the named cache stores of the decorators(such as rate limit, idempotency),
+// the not available stores are retried with backoff(1s, 2s, 4s... up to 1m), the callers fallback to
+// the in-process stores meanwhile.
+type cacheStoreResolver struct {
+	locker  sync.Mutex
+	clients map[string]*redis.Client
+	retries map[string]cacheStoreRetry
+}
+
+type cacheStoreRetry struct {
+	backoff time.Duration
+	next    time.Time
+}
+
+func newCacheStoreResolver() *cacheStoreResolver {
+	return &cacheStoreResolver{
+		clients: make(map[string]*redis.Client),
+		retries: make(map[string]cacheStoreRetry),
+	}
+}
+
+// resolve returns the cache store of name, returns false if the store are not available,
+// usage is the decorator that uses the store, it's used for logging.
+func (r *cacheStoreResolver) resolve(s *HostServer, name, usage string) (*redis.Client, bool) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if client, ok := r.clients[name]; ok {
+		return client, true
+	}
+	now := time.Now()
+	retry, ok := r.retries[name]
+	if ok && now.Before(retry.next) {
+		return nil, false
+	}
+	client, err := cacheStoreByName(s, name)
+	if err != nil {
+		retry.backoff *= 2
+		if retry.backoff < cacheStoreRetryMinBackoff {
+			retry.backoff = cacheStoreRetryMinBackoff
+		}
+		if retry.backoff > cacheStoreRetryMaxBackoff {
+			retry.backoff = cacheStoreRetryMaxBackoff
+		}
+		retry.next = now.Add(retry.backoff)
+		r.retries[name] = retry
+		logger.Error("%s cache store: %s not available, uses in-process store, retry after %v, err: %v",
+			usage, name, retry.backoff, err)
+		return nil, false
+	}
+	delete(r.retries, name)
+	r.clients[name] = client
+	return client, true
+}
+
 type DefaultBackendImpl struct {
 	dbs    map[string]*gorm.DB
 	caches map[string]*redis.Client
@@ -178,10 +250,29 @@ func createDb(db conf.Db) *gorm.DB {
 	return gDb
 }
 
+// RegisterDbCallbacks registers the gw callbacks(the DbOpProcessor hooks and the tenant filter) on the db,
+// It's used by the custom IStore backends, the DefaultBackend dbs are registered already.
+func RegisterDbCallbacks(db *gorm.DB) {
+	setupDb(db)
+}
+
+// beforeDbOperation starts the metrics and the span of the db operation.
+func beforeDbOperation(db *gorm.DB, operation string) {
+	startDbMetrics(db)
+	startDbSpan(db, operation)
+}
+
+// afterDbOperation records the metrics and ends the span of the db operation.
+func afterDbOperation(db *gorm.DB, operation string) {
+	observeDbMetrics(db, operation)
+	endDbSpan(db)
+}
+
 func setupDb(db *gorm.DB) {
 	err := db.Callback().Create().Before("gorm:create").Register("gw:create_before", func(db *gorm.DB) {
+		beforeDbOperation(db, "create")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -197,8 +288,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Create().After("gorm:create").Register("gw:create_after", func(db *gorm.DB) {
+		afterDbOperation(db, "create")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -214,8 +306,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Update().Before("gorm:update").Register("gw:update_before", func(db *gorm.DB) {
+		beforeDbOperation(db, "update")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -231,8 +324,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Update().After("gorm:update").Register("gw:update_after", func(db *gorm.DB) {
+		afterDbOperation(db, "update")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -248,8 +342,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Delete().Before("gorm:delete").Register("gw:update_before", func(db *gorm.DB) {
+		beforeDbOperation(db, "delete")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -265,8 +360,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Delete().After("gorm:delete").Register("gw:delete_after", func(db *gorm.DB) {
+		afterDbOperation(db, "delete")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -282,8 +378,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Query().Before("gorm:query").Register("gw:query_before", func(db *gorm.DB) {
+		beforeDbOperation(db, "query")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
@@ -316,8 +413,9 @@ func setupDb(db *gorm.DB) {
 		panic(fmt.Sprintf("setup db hooks fail, err: %v", err))
 	}
 	err = db.Callback().Query().After("gorm:query").Register("gw:query_after", func(db *gorm.DB) {
+		afterDbOperation(db, "query")
 		var obj, ok = db.Get(gwDbContextKey)
-		if !ok {
+		if !ok || db.Statement.Schema == nil {
 			return
 		}
 		if ctx, ok := obj.(*Context); ok {
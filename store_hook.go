@@ -86,12 +86,14 @@ func (h *DbOpTyperHandlers) Register(handler DbOpHandler, models ...interface{})
 	for _, m := range models {
 		_model := m
 		typer := reflect.TypeOf(_model)
-		if h.handlers[typer] == nil {
-			if len(h.handlers[typer]) == 0 {
-				h.handlers[typer] = make([]DbOpHandler, 0, 8)
-			}
-			h.handlers[typer] = append(h.handlers[typer], handler)
+		// the handlers are matched by the gorm schema's ModelType, it's a struct type.
+		for typer.Kind() == reflect.Ptr {
+			typer = typer.Elem()
 		}
+		if len(h.handlers[typer]) == 0 {
+			h.handlers[typer] = make([]DbOpHandler, 0, 8)
+		}
+		h.handlers[typer] = append(h.handlers[typer], handler)
 	}
 	return h
 }
@@ -0,0 +1,54 @@
+package gw
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// cacheStoreTester represents a IStore that the cache stores are available after failures.
+type cacheStoreTester struct {
+	IStore
+	failures int
+	calls    int
+	client   *redis.Client
+}
+
+func (s *cacheStoreTester) GetCacheStoreByName(name string) *redis.Client {
+	s.calls++
+	if s.calls <= s.failures {
+		panic("cache store: " + name + " not available")
+	}
+	return s.client
+}
+
+func TestCacheStoreResolver(t *testing.T) {
+	store := &cacheStoreTester{failures: 2, client: redis.NewClient(&redis.Options{})}
+	server := &HostServer{Store: store}
+	resolver := newCacheStoreResolver()
+
+	_, ok := resolver.resolve(server, "primary", "tester")
+	assert.False(t, ok)
+	assert.Equal(t, cacheStoreRetryMinBackoff, resolver.retries["primary"].backoff)
+	// the failed store are not retried before the backoff.
+	_, ok = resolver.resolve(server, "primary", "tester")
+	assert.False(t, ok)
+	assert.Equal(t, 1, store.calls)
+
+	resolver.retries["primary"] = cacheStoreRetry{backoff: resolver.retries["primary"].backoff, next: time.Now()}
+	_, ok = resolver.resolve(server, "primary", "tester")
+	assert.False(t, ok)
+	assert.Equal(t, 2*cacheStoreRetryMinBackoff, resolver.retries["primary"].backoff)
+
+	resolver.retries["primary"] = cacheStoreRetry{backoff: cacheStoreRetryMaxBackoff, next: time.Now()}
+	client, ok := resolver.resolve(server, "primary", "tester")
+	assert.True(t, ok)
+	assert.Equal(t, store.client, client)
+	assert.Empty(t, resolver.retries)
+	// the available store are cached.
+	client, ok = resolver.resolve(server, "primary", "tester")
+	assert.True(t, ok)
+	assert.Equal(t, store.client, client)
+	assert.Equal(t, 3, store.calls)
+}
@@ -0,0 +1,499 @@
+package gw
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/oceanho/gw/libs/gwtrace"
+	"github.com/oceanho/gw/logger"
+	"gorm.io/gorm"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	tracingSpanKey         = "gw-tracing-span"
+	gwDbSpanKey            = "gw:tracing_span"
+	defaultTraceIdHeader   = "X-Trace-Id"
+	tracingQueueSize       = 1024
+	defaultTracingTimeout  = 10 * time.Second
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+func init() {
+	// the logs of the InfoCtx/ErrorCtx/WarnCtx/DebugCtx APIs are carries the trace context.
+	logger.RegisterContextFieldsFunc(func(ctx context.Context) string {
+		if ctx == nil {
+			return ""
+		}
+		if sc, ok := gwtrace.SpanContextFromContext(ctx); ok {
+			return fmt.Sprintf("traceId: %s, spanId: %s", sc.TraceID, sc.SpanID)
+		}
+		return ""
+	})
+}
+
+// SpanKind represents the kind of a span, the values are same as the OTLP.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Span represents a timed operation of a trace, such as a request, a decorator, a db operation or a redis command.
+type Span struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  gwtrace.SpanContext
+	ParentSpanID gwtrace.SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]interface{}
+	Error        string
+	locker       sync.Mutex
+	ended        bool
+	tracer       *Tracer
+	trace        *traceSpans
+}
+
+// SetAttribute sets a attribute of the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed, nothing to do if err is nil.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.Error = err.Error()
+}
+
+// IsRecording returns true if the span are sampled, the not sampled spans are not exported.
+func (s *Span) IsRecording() bool {
+	return s.SpanContext.IsSampled()
+}
+
+// End ends the span, the spans of a request are exported after the root span ended.
+// It's can be called many times, only the first call are take effect.
+func (s *Span) End() {
+	s.locker.Lock()
+	if s.ended {
+		s.locker.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.locker.Unlock()
+	if !s.IsRecording() {
+		return
+	}
+	if spans := s.trace.end(s); len(spans) > 0 {
+		s.tracer.export(spans)
+	}
+}
+
+func (s *Span) MarshalJSON() ([]byte, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	var parent string
+	if s.ParentSpanID.IsValid() {
+		parent = s.ParentSpanID.String()
+	}
+	return json.Marshal(struct {
+		TraceId      string                 `json:"traceId"`
+		SpanId       string                 `json:"spanId"`
+		ParentSpanId string                 `json:"parentSpanId,omitempty"`
+		Name         string                 `json:"name"`
+		Kind         string                 `json:"kind"`
+		StartTime    time.Time              `json:"startTime"`
+		EndTime      time.Time              `json:"endTime"`
+		Duration     string                 `json:"duration"`
+		Attributes   map[string]interface{} `json:"attributes,omitempty"`
+		Error        string                 `json:"error,omitempty"`
+	}{
+		TraceId:      s.SpanContext.TraceID.String(),
+		SpanId:       s.SpanContext.SpanID.String(),
+		ParentSpanId: parent,
+		Name:         s.Name,
+		Kind:         s.Kind.String(),
+		StartTime:    s.StartTime,
+		EndTime:      s.EndTime,
+		Duration:     s.EndTime.Sub(s.StartTime).String(),
+		Attributes:   s.Attributes,
+		Error:        s.Error,
+	})
+}
+
+// traceSpans represents the ended spans of a local root span(such as the request span),
+// they are exported together after the root span ended, the spans that ended later are exported alone.
+type traceSpans struct {
+	locker sync.Mutex
+	root   *Span
+	spans  []*Span
+	done   bool
+}
+
+func (t *traceSpans) end(s *Span) []*Span {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	if t.done {
+		return []*Span{s}
+	}
+	t.spans = append(t.spans, s)
+	if s != t.root {
+		return nil
+	}
+	t.done = true
+	spans := t.spans
+	t.spans = nil
+	return spans
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the current span of ctx, returns nil if ctx has no span(such as the tracing are disabled).
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Tracer represents the span creator and exporter of a server.
+type Tracer struct {
+	locker    sync.Mutex
+	server    *HostServer
+	exporters []ISpanExporter
+	queue     chan []*Span
+	done      chan struct{}
+}
+
+func newTracer(s *HostServer) *Tracer {
+	return &Tracer{
+		server: s,
+	}
+}
+
+// Start starts a span, the span are a child of the span(local or remote) of ctx, or a root span of a new trace
+// that sampled by the settings.tracing.sampleRatio.
+// It's returns a copy of ctx that carries the span, the span should be ended by Span.End().
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+		tracer:    t,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.SpanContext = parent.SpanContext
+		span.ParentSpanID = parent.SpanContext.SpanID
+		span.trace = parent.trace
+	} else {
+		if remote, ok := gwtrace.SpanContextFromContext(ctx); ok {
+			span.SpanContext = remote
+			span.ParentSpanID = remote.SpanID
+		} else {
+			span.SpanContext.TraceID = gwtrace.NewTraceID()
+			if t.shouldSample() {
+				span.SpanContext.Flags = gwtrace.FlagsSampled
+			}
+		}
+		span.trace = &traceSpans{root: span}
+	}
+	span.SpanContext.SpanID = gwtrace.NewSpanID()
+	ctx = gwtrace.ContextWithSpanContext(ctx, span.SpanContext)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *Tracer) shouldSample() bool {
+	ratio := t.server.Config().Settings.Tracing.SampleRatio
+	return ratio >= 1 || (ratio > 0 && rand.Float64() < ratio)
+}
+
+// start starts the export worker, the ended spans are dropped before the worker started.
+func (t *Tracer) start(exporters []ISpanExporter) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	if t.queue != nil {
+		return
+	}
+	t.exporters = exporters
+	t.queue = make(chan []*Span, tracingQueueSize)
+	t.done = make(chan struct{})
+	go func(queue chan []*Span, done chan struct{}) {
+		defer close(done)
+		for spans := range queue {
+			for _, e := range t.exporters {
+				ctx, cancel := context.WithTimeout(context.Background(), defaultTracingTimeout)
+				if err := e.Export(ctx, spans); err != nil {
+					logger.Error("export %d spans fail, err: %v", len(spans), err)
+				}
+				cancel()
+			}
+		}
+	}(t.queue, t.done)
+}
+
+func (t *Tracer) export(spans []*Span) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	if t.queue == nil {
+		return
+	}
+	select {
+	case t.queue <- spans:
+	default:
+		logger.Warn("tracing export queue are full, %d spans are dropped", len(spans))
+	}
+}
+
+// shutdown exports the queued spans and shutdown the exporters.
+func (t *Tracer) shutdown() {
+	t.locker.Lock()
+	queue, done := t.queue, t.done
+	t.queue = nil
+	t.locker.Unlock()
+	if queue == nil {
+		return
+	}
+	close(queue)
+	<-done
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	for _, e := range t.exporters {
+		if err := e.Shutdown(ctx); err != nil {
+			logger.Error("shutdown span exporter fail, err: %v", err)
+		}
+	}
+}
+
+// TraceId returns the trace ID of the request, returns empty if the tracing are disabled.
+func (c *Context) TraceId() string {
+	if span := SpanFromContext(c); span != nil {
+		return span.SpanContext.TraceID.String()
+	}
+	return ""
+}
+
+// spanScope represents the context of the current child span of a request.
+type spanScope struct {
+	ctx context.Context
+}
+
+// spanContext returns the context of the current span, it's a child of the request context.
+// The request context are never replaced, so it's safe to be used by the other goroutines(such as database/sql).
+func (c *Context) spanContext() context.Context {
+	if scope, ok := c.spanScope.Load().(spanScope); ok {
+		return scope.ctx
+	}
+	return c.ctx
+}
+
+// startSpan starts a child span of the current span, the span are the current span until the returned func are called,
+// so the db operations and redis commands are the children of the span.
+func (c *Context) startSpan(name string) func(err error) {
+	parent := c.spanContext()
+	if SpanFromContext(parent) == nil {
+		return func(err error) {}
+	}
+	ctx, span := c.server.Tracer.Start(parent, name, SpanKindInternal)
+	c.spanScope.Store(spanScope{ctx: ctx})
+	return func(err error) {
+		span.SetError(err)
+		span.End()
+		c.spanScope.Store(spanScope{ctx: parent})
+	}
+}
+
+// newTracingHook returns the built-in tracing hook, it's starts the request span(a child of the traceparent header),
+// and responds the trace ID by the settings.tracing.responseHeader(default X-Trace-Id).
+func newTracingHook(s *HostServer) *Hook {
+	return NewHook(HookTracing, func(c *gin.Context) {
+		cnf := s.Config().Settings.Tracing
+		if !cnf.Enabled {
+			return
+		}
+		ctx := c.Request.Context()
+		if sc, ok := gwtrace.Extract(c.Request.Header); ok {
+			ctx = gwtrace.ContextWithSpanContext(ctx, sc)
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedHandler
+		}
+		ctx, span := s.Tracer.Start(ctx, c.Request.Method+" "+route, SpanKindServer)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.target", c.Request.URL.RequestURI())
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("gw.request_id", getRequestId(s, c))
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(tracingSpanKey, span)
+		header := cnf.ResponseHeader
+		if header == "" {
+			header = defaultTraceIdHeader
+		}
+		c.Header(header, span.SpanContext.TraceID.String())
+	}, func(c *gin.Context) {
+		v, ok := c.Get(tracingSpanKey)
+		if !ok {
+			return
+		}
+		span := v.(*Span)
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if app, _ := routerLabels(c); app != "" {
+			span.SetAttribute("gw.app", app)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+		}
+		span.End()
+	})
+}
+
+// setupTracing starts the span exporters of the settings.tracing.exporters, it's called after the server initialed.
+func setupTracing(s *HostServer) {
+	cnf := s.Config().Settings.Tracing
+	if !cnf.Enabled {
+		return
+	}
+	var exporters []ISpanExporter
+	for _, e := range cnf.Exporters {
+		fn, ok := spanExporterOf(e.Name)
+		if !ok {
+			panic(fmt.Sprintf("span exporter: %s are not registered", e.Name))
+		}
+		exporter, err := fn(s, e)
+		if err != nil {
+			panic(fmt.Sprintf("create span exporter: %s fail, err: %v", e.Name, err))
+		}
+		exporters = append(exporters, exporter)
+	}
+	s.Tracer.start(exporters)
+	s.RegisterShutDownHandler(func(s *HostServer) error {
+		s.Tracer.shutdown()
+		return nil
+	})
+	for _, cache := range s.Config().Backend.Cache {
+		client, err := cacheStoreByName(s, cache.Name)
+		if err != nil {
+			logger.Error("setup tracing of cache store: %s fail, err: %v", cache.Name, err)
+			continue
+		}
+		client.AddHook(redisTracingHook{store: cache.Name})
+	}
+}
+
+type redisSpanKey struct{}
+
+// redisTracingHook represents a redis.Hook that records the commands as the child spans of the request.
+type redisTracingHook struct {
+	store string
+}
+
+func (h redisTracingHook) start(ctx context.Context, name string) context.Context {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx
+	}
+	ctx, span := parent.tracer.Start(ctx, "redis "+name, SpanKindClient)
+	span.SetAttribute("db.system", "redis")
+	span.SetAttribute("gw.store", h.store)
+	return context.WithValue(ctx, redisSpanKey{}, span)
+}
+
+func (h redisTracingHook) end(ctx context.Context, err error) {
+	if span, ok := ctx.Value(redisSpanKey{}).(*Span); ok {
+		if err != redis.Nil {
+			span.SetError(err)
+		}
+		span.End()
+	}
+}
+
+func (h redisTracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.start(ctx, cmd.Name()), nil
+}
+
+func (h redisTracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.end(ctx, cmd.Err())
+	return nil
+}
+
+func (h redisTracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.start(ctx, "pipeline"), nil
+}
+
+func (h redisTracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	h.end(ctx, err)
+	return nil
+}
+
+// startDbSpan starts the span of the db operation, only the operations of the traced requests are recorded.
+func startDbSpan(db *gorm.DB, operation string) {
+	obj, ok := db.Get(gwDbContextKey)
+	if !ok {
+		return
+	}
+	ctx, ok := obj.(*Context)
+	if !ok || SpanFromContext(ctx) == nil {
+		return
+	}
+	_, span := ctx.server.Tracer.Start(ctx, "db."+operation, SpanKindClient)
+	if db.Dialector != nil {
+		span.SetAttribute("db.system", db.Dialector.Name())
+	}
+	db.InstanceSet(gwDbSpanKey, span)
+}
+
+// endDbSpan ends the span of the db operation.
+func endDbSpan(db *gorm.DB) {
+	obj, ok := db.InstanceGet(gwDbSpanKey)
+	if !ok {
+		return
+	}
+	span := obj.(*Span)
+	span.SetAttribute("db.table", db.Statement.Table)
+	span.SetAttribute("db.statement", db.Statement.SQL.String())
+	span.SetAttribute("db.rows_affected", db.RowsAffected)
+	if db.Error != gorm.ErrRecordNotFound {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
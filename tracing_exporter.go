@@ -0,0 +1,238 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/oceanho/gw/conf"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ISpanExporter represents a exporter that exports the ended spans, such as to stdout or a OTLP collector.
+type ISpanExporter interface {
+	// Export exports the spans, it's called by a single goroutine.
+	Export(ctx context.Context, spans []*Span) error
+	// Shutdown flushes and releases the exporter, it's called on the server shutdown.
+	Shutdown(ctx context.Context) error
+}
+
+// SpanExporterFunc returns a exporter of the settings.tracing.exporters item.
+type SpanExporterFunc func(s *HostServer, cnf conf.TracingExporter) (ISpanExporter, error)
+
+var (
+	spanExportersLocker sync.RWMutex
+	spanExporters       = map[string]SpanExporterFunc{
+		"stdout": func(s *HostServer, cnf conf.TracingExporter) (ISpanExporter, error) {
+			return NewStdoutSpanExporter(os.Stdout), nil
+		},
+		"otlp": func(s *HostServer, cnf conf.TracingExporter) (ISpanExporter, error) {
+			return NewOtlpSpanExporter(s.Config().Service.Name, cnf), nil
+		},
+	}
+)
+
+// RegisterSpanExporter registers a exporter that can be used by the settings.tracing.exporters, the registered exporter are replaced.
+// The stdout and otlp exporters are built-in, the others should be registered in the init stage.
+func RegisterSpanExporter(name string, fn SpanExporterFunc) {
+	spanExportersLocker.Lock()
+	defer spanExportersLocker.Unlock()
+	spanExporters[strings.ToLower(name)] = fn
+}
+
+func spanExporterOf(name string) (SpanExporterFunc, bool) {
+	spanExportersLocker.RLock()
+	defer spanExportersLocker.RUnlock()
+	fn, ok := spanExporters[strings.ToLower(name)]
+	return fn, ok
+}
+
+// StdoutSpanExporter represents a exporter that writes the spans as JSON lines.
+type StdoutSpanExporter struct {
+	locker sync.Mutex
+	w      io.Writer
+}
+
+func NewStdoutSpanExporter(w io.Writer) *StdoutSpanExporter {
+	return &StdoutSpanExporter{w: w}
+}
+
+func (e *StdoutSpanExporter) Export(ctx context.Context, spans []*Span) error {
+	e.locker.Lock()
+	defer e.locker.Unlock()
+	encoder := json.NewEncoder(e.w)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *StdoutSpanExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// OtlpSpanExporter represents a exporter that posts the spans to a OTLP/HTTP collector(JSON encoding),
+// such as http://localhost:4318/v1/traces.
+type OtlpSpanExporter struct {
+	service  string
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+func NewOtlpSpanExporter(service string, cnf conf.TracingExporter) *OtlpSpanExporter {
+	timeout := defaultTracingTimeout
+	if cnf.Timeout > 0 {
+		timeout = time.Duration(cnf.Timeout) * time.Millisecond
+	}
+	return &OtlpSpanExporter{
+		service:  service,
+		endpoint: cnf.Endpoint,
+		headers:  cnf.Headers,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *OtlpSpanExporter) Export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(newOtlpTraces(e.service, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("otlp collector: %s responds %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (e *OtlpSpanExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// The OTLP/JSON trace models, see https://github.com/open-telemetry/opentelemetry-proto.
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func newOtlpTraces(service string, spans []*Span) otlpTraces {
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpKeyValue{newOtlpKeyValue("service.name", service)}
+	var ss otlpScopeSpans
+	ss.Scope.Name = "github.com/oceanho/gw"
+	ss.Scope.Version = Version
+	for _, span := range spans {
+		ss.Spans = append(ss.Spans, newOtlpSpan(span))
+	}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+func newOtlpSpan(span *Span) otlpSpan {
+	span.locker.Lock()
+	defer span.locker.Unlock()
+	s := otlpSpan{
+		TraceId:           span.SpanContext.TraceID.String(),
+		SpanId:            span.SpanContext.SpanID.String(),
+		TraceState:        span.SpanContext.TraceState,
+		Name:              span.Name,
+		Kind:              int(span.Kind),
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+	}
+	if span.ParentSpanID.IsValid() {
+		s.ParentSpanId = span.ParentSpanID.String()
+	}
+	var keys []string
+	for k := range span.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.Attributes = append(s.Attributes, newOtlpKeyValue(k, span.Attributes[k]))
+	}
+	if span.Error != "" {
+		s.Status.Code = 2
+		s.Status.Message = span.Error
+	}
+	return s
+}
+
+// newOtlpKeyValue returns the OTLP attribute, the int values are encoded as string(int64 JSON mapping).
+func newOtlpKeyValue(key string, value interface{}) otlpKeyValue {
+	var v map[string]interface{}
+	switch val := value.(type) {
+	case string:
+		v = map[string]interface{}{"stringValue": val}
+	case bool:
+		v = map[string]interface{}{"boolValue": val}
+	case int:
+		v = map[string]interface{}{"intValue": strconv.Itoa(val)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": val}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,122 @@
+package gw_test
+
+import (
+	"encoding/json"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/oceanho/gw/libs/gwtrace"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tracingUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+}
+
+func tracingListUsers(c *gw.Context) {
+	var users []tracingUser
+	if err := c.Store().GetDbStore().Where("tenant_id = ?", c.User().TenantId).Find(&users).Error; err != nil {
+		c.JSON500Msg(0, err)
+		return
+	}
+	c.JSON200(users)
+}
+
+func tracingCrash(c *gw.Context) {
+	panic("crash")
+}
+
+func TestServer_Tracing(t *testing.T) {
+	type otlpSpan struct {
+		TraceId      string
+		SpanId       string
+		ParentSpanId string
+		Name         string
+		Status       struct{ Code int }
+	}
+	var traces = make(chan []otlpSpan, 8)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan
+				}
+			}
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		traces <- body.ResourceSpans[0].ScopeSpans[0].Spans
+	}))
+	defer collector.Close()
+	server := gwtest.NewServerWithOption(t, func(opts *gw.ServerOption) {
+		opts.AppConfigHandler = func(bcs *conf.BootConfig) *conf.ApplicationConfig {
+			cnf := gwtest.DefaultConfig()
+			cnf.Settings.ResponseCache.Store = "primary"
+			cnf.Settings.Tracing.Enabled = true
+			cnf.Settings.Tracing.SampleRatio = 1
+			cnf.Settings.Tracing.Exporters = []conf.TracingExporter{{Name: "otlp", Endpoint: collector.URL + "/v1/traces"}}
+			return cnf
+		}
+	}, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.GET("users", tracingListUsers, gw.NewResponseCacheDecorator(time.Minute, tracingUser{}))
+			router.GET("crash", tracingCrash)
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			_ = state.Store().GetDbStore().AutoMigrate(&tracingUser{})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+
+	// the incoming trace context are continued.
+	parent := gwtrace.SpanContext{TraceID: gwtrace.NewTraceID(), SpanID: gwtrace.NewSpanID(), Flags: gwtrace.FlagsSampled}
+	resp := client.WithHeader(gwtrace.TraceParentHeader, parent.TraceParent()).Call("tracingListUsers", nil, nil).AssertOK()
+	assert.Equal(t, parent.TraceID.String(), resp.Header.Get("X-Trace-Id"))
+
+	var spans []otlpSpan
+	select {
+	case spans = <-traces:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the spans are not exported")
+	}
+	var byName = make(map[string]otlpSpan)
+	for _, span := range spans {
+		assert.Equal(t, parent.TraceID.String(), span.TraceId)
+		if strings.HasPrefix(span.Name, "redis ") {
+			byName["redis"] = span
+			continue
+		}
+		byName[span.Name] = span
+	}
+	listUsers := client.Router("tracingListUsers")
+	root := byName["GET "+listUsers.UrlPath]
+	assert.Equal(t, parent.SpanID.String(), root.ParentSpanId)
+	handler := byName["handler "+listUsers.Name()]
+	assert.Equal(t, root.SpanId, handler.ParentSpanId)
+	assert.Equal(t, handler.SpanId, byName["db.query"].ParentSpanId)
+	before := byName["decorator.before gw_framework_response_cache"]
+	assert.Equal(t, root.SpanId, before.ParentSpanId)
+	assert.NotEmpty(t, byName["redis"].ParentSpanId)
+	assert.NotEqual(t, root.SpanId, byName["redis"].ParentSpanId)
+
+	// the new traces are started without the incoming trace context, the errors are recorded.
+	resp = client.Call("tracingCrash", nil, nil).AssertStatus(http.StatusInternalServerError)
+	traceId := resp.Header.Get("X-Trace-Id")
+	assert.Len(t, traceId, 32)
+	assert.NotEqual(t, parent.TraceID.String(), traceId)
+	select {
+	case spans = <-traces:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the spans are not exported")
+	}
+	root = spans[len(spans)-1]
+	assert.Equal(t, traceId, root.TraceId)
+	assert.Empty(t, root.ParentSpanId)
+	assert.Equal(t, 2, root.Status.Code)
+}
@@ -0,0 +1,145 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/oceanho/gw/conf"
+	"github.com/oceanho/gw/libs/gwtrace"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testSpanExporter struct {
+	batches chan []*Span
+}
+
+func (e *testSpanExporter) Export(ctx context.Context, spans []*Span) error {
+	e.batches <- spans
+	return nil
+}
+
+func (e *testSpanExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func newTestTracer(ratio float64) *Tracer {
+	cnf := &conf.ApplicationConfig{}
+	cnf.Settings.Tracing.SampleRatio = ratio
+	s := &HostServer{}
+	s.config.Store(cnf)
+	return newTracer(s)
+}
+
+func TestTracer_Start(t *testing.T) {
+	tracer := newTestTracer(1)
+	exporter := &testSpanExporter{batches: make(chan []*Span, 2)}
+	tracer.start([]ISpanExporter{exporter})
+
+	remote := gwtrace.SpanContext{TraceID: gwtrace.NewTraceID(), SpanID: gwtrace.NewSpanID(), Flags: gwtrace.FlagsSampled}
+	ctx, root := tracer.Start(gwtrace.ContextWithSpanContext(context.Background(), remote), "GET /users", SpanKindServer)
+	assert.Equal(t, remote.TraceID, root.SpanContext.TraceID)
+	assert.Equal(t, remote.SpanID, root.ParentSpanID)
+	assert.NotEqual(t, remote.SpanID, root.SpanContext.SpanID)
+	assert.Equal(t, root, SpanFromContext(ctx))
+
+	_, child := tracer.Start(ctx, "db.query", SpanKindClient)
+	assert.Equal(t, root.SpanContext.TraceID, child.SpanContext.TraceID)
+	assert.Equal(t, root.SpanContext.SpanID, child.ParentSpanID)
+	child.SetError(errors.New("connection refused"))
+	child.End()
+	child.End()
+	root.End()
+	tracer.shutdown()
+
+	spans := <-exporter.batches
+	assert.Equal(t, []*Span{child, root}, spans)
+	assert.Equal(t, "connection refused", child.Error)
+	assert.Len(t, exporter.batches, 0)
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	_, span := newTestTracer(0).Start(context.Background(), "GET /users", SpanKindServer)
+	assert.True(t, span.SpanContext.IsValid())
+	assert.False(t, span.IsRecording())
+	_, span = newTestTracer(1).Start(context.Background(), "GET /users", SpanKindServer)
+	assert.True(t, span.IsRecording())
+
+	// the sampled flag of the remote parent are respected.
+	remote := gwtrace.SpanContext{TraceID: gwtrace.NewTraceID(), SpanID: gwtrace.NewSpanID()}
+	_, span = newTestTracer(1).Start(gwtrace.ContextWithSpanContext(context.Background(), remote), "GET /users", SpanKindServer)
+	assert.False(t, span.IsRecording())
+}
+
+func TestStdoutSpanExporter(t *testing.T) {
+	_, span := newTestTracer(1).Start(context.Background(), "GET /users", SpanKindServer)
+	span.SetAttribute("http.status_code", 200)
+	span.End()
+	var buf bytes.Buffer
+	assert.Nil(t, NewStdoutSpanExporter(&buf).Export(context.Background(), []*Span{span}))
+	var out map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, span.SpanContext.TraceID.String(), out["traceId"])
+	assert.Equal(t, "server", out["kind"])
+	assert.Equal(t, float64(200), out["attributes"].(map[string]interface{})["http.status_code"])
+	assert.NotContains(t, out, "parentSpanId")
+}
+
+func TestOtlpSpanExporter(t *testing.T) {
+	var body []byte
+	var header http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer collector.Close()
+
+	ctx, root := newTestTracer(1).Start(context.Background(), "GET /users", SpanKindServer)
+	_, child := root.tracer.Start(ctx, "redis get", SpanKindClient)
+	child.SetAttribute("gw.store", "primary")
+	child.SetError(errors.New("timeout"))
+	child.End()
+	root.End()
+	exporter := NewOtlpSpanExporter("gw-test", conf.TracingExporter{
+		Endpoint: collector.URL + "/v1/traces",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	assert.Nil(t, exporter.Export(context.Background(), []*Span{child, root}))
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+
+	var traces otlpTraces
+	assert.Nil(t, json.Unmarshal(body, &traces))
+	rs := traces.ResourceSpans[0]
+	assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, "gw-test", rs.Resource.Attributes[0].Value["stringValue"])
+	spans := rs.ScopeSpans[0].Spans
+	assert.Len(t, spans, 2)
+	assert.Equal(t, root.SpanContext.SpanID.String(), spans[0].ParentSpanId)
+	assert.Equal(t, 3, spans[0].Kind)
+	assert.Equal(t, 2, spans[0].Status.Code)
+	assert.Equal(t, "timeout", spans[0].Status.Message)
+	assert.Equal(t, "", spans[1].ParentSpanId)
+	assert.Equal(t, 0, spans[1].Status.Code)
+	assert.True(t, strings.Contains(string(body), `"startTimeUnixNano":"`))
+
+	collector.Close()
+	assert.NotNil(t, exporter.Export(context.Background(), []*Span{root}))
+}
+
+func TestSpanExporterOf(t *testing.T) {
+	_, ok := spanExporterOf("OTLP")
+	assert.True(t, ok)
+	_, ok = spanExporterOf("zipkin")
+	assert.False(t, ok)
+	RegisterSpanExporter("zipkin", func(s *HostServer, cnf conf.TracingExporter) (ISpanExporter, error) {
+		return NewStdoutSpanExporter(ioutil.Discard), nil
+	})
+	_, ok = spanExporterOf("zipkin")
+	assert.True(t, ok)
+}
@@ -18,7 +18,7 @@ func Run(servers ...*HostServer) {
 			started.Done()
 			<-s.serverExitSignal
 			close(s.serverExitSignal)
-			logger.Info("Server: %s, Addr: %s exiting", s.options.Name, s.options.Addr)
+			logger.Info("Server: %s, Addr: %s exiting", s.options.Name, s.Addr())
 			shutdown.Done()
 		}(servers[i])
 	}
@@ -1,5 +1,238 @@
 package gw
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrValidationFailed = NewError(http.StatusBadRequest, ErrCodeValidationFailed, "validation failed")
+	ErrValidationEngine = fmt.Errorf("the binding.Validator's engine are not a *validator.Validate")
+	validatorMsgLocker  sync.RWMutex
+	validatorMessages   = map[string]string{
+		"required":         "is required",
+		"required_with":    "is required",
+		"required_without": "is required",
+		"email":            "must be a valid email address",
+		"url":              "must be a valid URL",
+		"min":              "must be at least %s",
+		"max":              "must be at most %s",
+		"len":              "length must be %s",
+		"gt":               "must be greater than %s",
+		"gte":              "must be greater than or equal to %s",
+		"lt":               "must be less than %s",
+		"lte":              "must be less than or equal to %s",
+		"oneof":            "must be one of [%s]",
+		"eqfield":          "must be equal to %s",
+		"nefield":          "must not be equal to %s",
+		"gtfield":          "must be greater than %s",
+		"gtefield":         "must be greater than or equal to %s",
+		"ltfield":          "must be less than %s",
+		"ltefield":         "must be less than or equal to %s",
+		"unique_in_tenant": "already exists",
+		"exists_in_tenant": "does not exist",
+	}
+)
+
 func init() {
 	// register gw-self validators.
+	RegisterValidator("unique_in_tenant", uniqueInTenantValidator, "")
+	RegisterValidator("exists_in_tenant", existsInTenantValidator, "")
+	validatorEngine().RegisterTagNameFunc(validationFieldName)
+}
+
+// ValidatorFunc represents a custom tag validator,
+// ctx is the request Context, it's nil if the obj validated out of gw(such as gin's ShouldBind...).
+type ValidatorFunc func(ctx *Context, fl validator.FieldLevel) bool
+
+// StructValidatorFunc represents a struct level validator, it's used for the cross-field rules.
+type StructValidatorFunc func(ctx *Context, sl validator.StructLevel)
+
+// FieldError represents a validation error of a field.
+type FieldError struct {
+	Field   string // the field path, such as Items[0].Name, the json(or form, uri) names are used.
+	Rule    string // the validation tag, such as required, min, unique_in_tenant.
+	Param   string
+	Message string
+}
+
+// ValidationErrors represents the validation errors of a object.
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	var msgs = make([]string, 0, len(ve))
+	for _, e := range ve {
+		msgs = append(msgs, e.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// RegisterValidator registers a custom tag validator, message is the error message of the rule,
+// it's can has a %s verb of the rule param, and it's also the message key of translation.
+// It's should be called in the init or App.Use(...) stage.
+func RegisterValidator(tag string, fn ValidatorFunc, message string, callValidationEvenIfNull ...bool) {
+	validatorMsgLocker.Lock()
+	defer validatorMsgLocker.Unlock()
+	err := validatorEngine().RegisterValidationCtx(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
+		c, _ := ctx.(*Context)
+		return fn(c, fl)
+	}, callValidationEvenIfNull...)
+	if err != nil {
+		panic(fmt.Sprintf("register validator: %s fail, err: %v", tag, err))
+	}
+	if message != "" {
+		validatorMessages[tag] = message
+	}
+}
+
+// RegisterStructValidator registers a struct level validator of the types, it's used for the cross-field rules,
+// the errors should be reported by sl.ReportError(...).
+func RegisterStructValidator(fn StructValidatorFunc, types ...interface{}) {
+	validatorEngine().RegisterStructValidationCtx(func(ctx context.Context, sl validator.StructLevel) {
+		c, _ := ctx.(*Context)
+		fn(c, sl)
+	}, types...)
+}
+
+func validatorEngine() *validator.Validate {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic(ErrValidationEngine)
+	}
+	return v
+}
+
+// validationFieldName returns the field name of json, form or uri tags.
+func validationFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form", "uri"} {
+		name := strings.Split(field.Tag.Get(key), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// Validate validates the obj by binding tags and the registered validators,
+// returns ValidationErrors if the obj are invalid, or a internal_error(500) *Error if the validators are failed(such as db errors).
+func (c *Context) Validate(obj interface{}) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	var ctx context.Context = c
+	if c == nil {
+		ctx = context.Background()
+	}
+	err := validatorEngine().StructCtx(ctx, obj)
+	if c != nil && c.validateErr != nil {
+		err, c.validateErr = c.validateErr, nil
+		return err
+	}
+	return parseValidationErr(c, err)
+}
+
+// JSON400Validation response the structured field errors with http status = 400, the error code are ErrCodeValidationFailed.
+func (c *Context) JSON400Validation(errs ValidationErrors) {
+	c.StatusJSON(http.StatusBadRequest, 0, errs, nil)
+}
+
+// respBindErr response the bind error, the ValidationErrors are responded by JSON400Validation,
+// the typed errors(such as ErrRequestEntityTooLarge of the body reads) are responded by it's status.
+func (c *Context) respBindErr(err error) {
+	if errs, ok := err.(ValidationErrors); ok {
+		c.JSON400Validation(errs)
+		return
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		respErr(c.Context, c.RequestId(), 0, e, nil)
+		return
+	}
+	c.JSON400Msg(http.StatusBadRequest, fmt.Sprintf("invalid request parameters, details: \n%v", err))
+}
+
+// parseValidationErr convert the validator.ValidationErrors to ValidationErrors, the messages are translated by c.T(...).
+func parseValidationErr(c *Context, err error) error {
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	var fieldErrs = make(ValidationErrors, 0, len(errs))
+	for _, e := range errs {
+		field := e.Namespace()
+		if idx := strings.Index(field, "."); idx >= 0 {
+			field = field[idx+1:]
+		}
+		validatorMsgLocker.RLock()
+		msg, ok := validatorMessages[e.Tag()]
+		validatorMsgLocker.RUnlock()
+		if !ok {
+			msg = c.T("failed on the '%s' rule", e.Tag())
+		} else if strings.Contains(msg, "%s") {
+			msg = c.T(msg, e.Param())
+		} else {
+			msg = c.T(msg)
+		}
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   field,
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: fmt.Sprintf("%s %s", field, msg),
+		})
+	}
+	return fieldErrs
+}
+
+// uniqueInTenantValidator validates the field value not exists in the table's column of the user's tenant.
+//
+// Usage: binding:"unique_in_tenant=table.column", the table must has a tenant_id column.
+//
+// It's fail closed(always fails) if the obj validated out of a gw request, because there is no tenant to check.
+func uniqueInTenantValidator(ctx *Context, fl validator.FieldLevel) bool {
+	if ctx == nil {
+		return false
+	}
+	n, err := countInTenant(ctx, fl)
+	return err != nil || n == 0
+}
+
+// existsInTenantValidator validates the field value exists in the table's column of the user's tenant.
+//
+// Usage: binding:"exists_in_tenant=table.column", the table must has a tenant_id column.
+//
+// It's fail closed(always fails) if the obj validated out of a gw request, because there is no tenant to check.
+func existsInTenantValidator(ctx *Context, fl validator.FieldLevel) bool {
+	if ctx == nil {
+		return false
+	}
+	n, err := countInTenant(ctx, fl)
+	return err != nil || n > 0
+}
+
+// countInTenant returns the count of the field value in the table's column of the user's tenant,
+// the db errors are recorded into the ctx, so the request are failed with a internal_error(500) response.
+func countInTenant(ctx *Context, fl validator.FieldLevel) (int64, error) {
+	params := strings.SplitN(fl.Param(), ".", 2)
+	if len(params) != 2 {
+		panic(fmt.Sprintf("invalid %s param: %s, should be table.column", fl.GetTag(), fl.Param()))
+	}
+	var n int64
+	err := ctx.Store().GetDbStore().Table(params[0]).
+		Where(fmt.Sprintf("%s = ? and tenant_id = ?", params[1]), fl.Field().Interface(), ctx.User().TenantId).
+		Count(&n).Error
+	if err != nil && ctx.validateErr == nil {
+		ctx.validateErr = ErrInternalServerError.WithCause(fmt.Errorf("validate %s fail, err: %w", fl.GetTag(), err))
+	}
+	return n, err
 }
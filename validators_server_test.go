@@ -0,0 +1,91 @@
+package gw_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oceanho/gw"
+	"github.com/oceanho/gw/gwtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type validUser struct {
+	ID       uint64
+	TenantId uint64
+	Name     string
+}
+
+type validRegister struct {
+	Name            string `json:"name" binding:"required,unique_in_tenant=valid_users.name"`
+	Password        string `json:"password" binding:"required,min=6"`
+	ConfirmPassword string `json:"confirmPassword" binding:"eqfield=Password"`
+	Items           []struct {
+		Qty int `json:"qty" binding:"gt=0"`
+	} `json:"items" binding:"dive"`
+}
+
+func validRegisterUser(c *gw.Context) {
+	var in validRegister
+	if c.Bind(&in) != nil {
+		return
+	}
+	c.JSON200(in.Name)
+}
+
+func validRegisterMissing(c *gw.Context) {
+	var in struct {
+		Name string `json:"name" binding:"required,unique_in_tenant=valid_missing_users.name"`
+	}
+	if c.Bind(&in) != nil {
+		return
+	}
+	c.JSON200(in.Name)
+}
+
+func TestServer_Validation(t *testing.T) {
+	server := gwtest.NewServer(t, &gwtest.App{
+		RegisterFunc: func(router *gw.RouterGroup) {
+			router.POST("register", validRegisterUser)
+			router.POST("register/missing", validRegisterMissing)
+		},
+		MigrateFunc: func(state *gw.ServerState) {
+			db := state.Store().GetDbStore()
+			_ = db.AutoMigrate(&validUser{})
+			db.Create(&validUser{ID: 1, TenantId: 10, Name: "gw"})
+		},
+	})
+	client := server.Client().LoginAs(gw.User{ID: 1, TenantId: 10, Passport: "gw"})
+	client.Call("validRegisterUser", nil, gin.H{"name": "new", "password": "123456", "confirmPassword": "123456"}).AssertOK()
+
+	resp := client.Call("validRegisterUser", nil, gin.H{
+		"name":            "gw",
+		"password":        "123",
+		"confirmPassword": "1234",
+		"items":           []gin.H{{"qty": 1}, {"qty": 0}},
+	}).AssertStatus(http.StatusBadRequest)
+	assert.Equal(t, gw.ErrCodeValidationFailed, resp.Envelope().Code)
+	var payload struct {
+		Errors []gw.FieldError
+	}
+	resp.DecodePayload(&payload)
+	var rules = make(map[string]string)
+	for _, e := range payload.Errors {
+		rules[e.Field] = e.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"name":            "unique_in_tenant",
+		"password":        "min",
+		"confirmPassword": "eqfield",
+		"items[1].qty":    "gt",
+	}, rules)
+
+	// unique in the user's tenant only.
+	other := server.Client().LoginAs(gw.User{ID: 2, TenantId: 20, Passport: "other"})
+	other.Call("validRegisterUser", nil, gin.H{"name": "gw", "password": "123456", "confirmPassword": "123456"}).AssertOK()
+
+	// the db errors of the validators are not validation errors.
+	resp = client.Call("validRegisterMissing", nil, gin.H{"name": "gw"}).
+		AssertError(http.StatusInternalServerError, gw.ErrInternalServerError.Error())
+	assert.Equal(t, gw.ErrCodeInternalError, resp.Envelope().Code)
+	assert.NotContains(t, string(resp.Body), "valid_missing_users")
+}
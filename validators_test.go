@@ -0,0 +1,74 @@
+package gw
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type validatorTester struct {
+	Start int `json:"start" binding:"required"`
+	End   int `json:"end"`
+	Tags  []struct {
+		Name string `form:"name" binding:"required"`
+	} `json:"tags" binding:"dive"`
+}
+
+func TestContext_Validate(t *testing.T) {
+	RegisterStructValidator(func(ctx *Context, sl validator.StructLevel) {
+		v := sl.Current().Interface().(validatorTester)
+		if v.End < v.Start {
+			sl.ReportError(v.End, "end", "End", "gtefield", "start")
+		}
+	}, validatorTester{})
+	var c *Context
+	err := c.Validate(&validatorTester{Start: 2, End: 1, Tags: []struct {
+		Name string `form:"name" binding:"required"`
+	}{{}}})
+	errs, ok := err.(ValidationErrors)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, ValidationErrors{
+		{Field: "tags[0].name", Rule: "required", Message: "tags[0].name is required"},
+		{Field: "end", Rule: "gtefield", Param: "start", Message: "end must be greater than or equal to start"},
+	}, errs)
+	assert.Nil(t, c.Validate(&validatorTester{Start: 1, End: 2}))
+}
+
+func TestContext_Validate_OutOfRequest(t *testing.T) {
+	var c *Context
+	err := c.Validate(&struct {
+		Name string `json:"name" binding:"unique_in_tenant=users.name"`
+		Role string `json:"role" binding:"exists_in_tenant=roles.name"`
+	}{Name: "gw", Role: "admin"})
+	errs, ok := err.(ValidationErrors)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, ValidationErrors{
+		{Field: "name", Rule: "unique_in_tenant", Param: "users.name", Message: "name already exists"},
+		{Field: "role", Rule: "exists_in_tenant", Param: "roles.name", Message: "role does not exist"},
+	}, errs)
+}
+
+func TestRegisterValidator_Concurrent(t *testing.T) {
+	var c *Context
+	verr := validatorEngine().Struct(&validatorTester{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterValidator("gw_concurrent", func(ctx *Context, fl validator.FieldLevel) bool {
+				return false
+			}, "is invalid")
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "start is required", parseValidationErr(c, verr).Error())
+		}()
+	}
+	wg.Wait()
+}